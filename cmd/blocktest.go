@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/morelucks/minievm/tests"
+)
+
+// blockTestCommand runs BlockchainTests fixtures and prints a summary per
+// network:
+//
+//	mevm blocktest [-run REGEXP] [-v] <file|dir>...
+func blockTestCommand(args []string) error {
+	flags := flag.NewFlagSet("blocktest", flag.ContinueOnError)
+	run := flags.String("run", "", "only run tests whose name matches this regular expression")
+	verbose := flags.Bool("v", false, "print passing and skipped tests too")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: mevm blocktest [flags] <file|dir>...")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("expected a test file or directory")
+	}
+	var filter *regexp.Regexp
+	if *run != "" {
+		var err error
+		if filter, err = regexp.Compile(*run); err != nil {
+			return fmt.Errorf("invalid -run pattern: %w", err)
+		}
+	}
+
+	files, err := fixtureFiles(flags.Args())
+	if err != nil {
+		return err
+	}
+	summary := make(map[string]*forkSummary)
+	var fileErrors int
+	for _, file := range files {
+		blockTests, err := tests.LoadBlockTests(file)
+		if err != nil {
+			fmt.Printf("ERROR %v\n", err)
+			fileErrors++
+			continue
+		}
+		names := make([]string, 0, len(blockTests))
+		for name := range blockTests {
+			if filter == nil || filter.MatchString(name) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			test := blockTests[name]
+			counts, ok := summary[test.Network]
+			if !ok {
+				counts = new(forkSummary)
+				summary[test.Network] = counts
+			}
+			err := test.Run()
+			switch {
+			case errors.Is(err, tests.ErrUnsupportedFork):
+				counts.skipped++
+				if *verbose {
+					fmt.Printf("SKIP  %s: %v\n", name, err)
+				}
+			case err != nil:
+				counts.failed++
+				fmt.Printf("FAIL  %s (%s): %v\n", name, file, err)
+			default:
+				counts.passed++
+				if *verbose {
+					fmt.Printf("PASS  %s\n", name)
+				}
+			}
+		}
+	}
+
+	total := printSummary("Network", summary)
+	if total.failed > 0 || fileErrors > 0 {
+		return fmt.Errorf("%d tests failed, %d files could not be read", total.failed, fileErrors)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// commands are the mevm subcommands; without one, mevm runs the feature demo
+var commands = map[string]func(args []string) error{
+	"blocktest": blockTestCommand,
+	"genesis":   genesisCommand,
+	"intrinsic": intrinsicCommand,
+	"statetest": stateTestCommand,
+	"t8n":       t8nCommand,
+}
+
+// runCommand dispatches to the named subcommand
+func runCommand(name string, args []string) error {
+	command, ok := commands[name]
+	if !ok {
+		var names []string
+		for n := range commands {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown command %q (available: %s)", name, strings.Join(names, ", "))
+	}
+	return command(args)
+}
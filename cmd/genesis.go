@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	types "github.com/morelucks/minievm/typess"
+)
+
+// genesisCommand loads a geth genesis.json and prints its genesis block:
+//
+//	mevm genesis <genesis.json>
+func genesisCommand(args []string) error {
+	flags := flag.NewFlagSet("genesis", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: mevm genesis <genesis.json>")
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("expected one genesis file")
+	}
+
+	genesis, err := types.LoadGenesis(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	header, _ := genesis.ToBlock()
+	hash := header.Hash()
+	fmt.Printf("Chain ID:   %v\n", genesis.Config.ChainID)
+	fmt.Printf("Fork:       %s\n", genesis.Fork())
+	fmt.Printf("Accounts:   %d\n", len(genesis.Alloc))
+	fmt.Printf("State root: 0x%x\n", header.Root[:])
+	fmt.Printf("Hash:       0x%x\n", hash[:])
+	return nil
+}
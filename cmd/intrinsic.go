@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	types "github.com/morelucks/minievm/typess"
+)
+
+// intrinsicCommand prints the intrinsic gas of a transaction:
+//
+//	mevm intrinsic [-fork Prague] [-create] [-accesslist JSON|@file] [-auths N] [calldata]
+func intrinsicCommand(args []string) error {
+	flags := flag.NewFlagSet("intrinsic", flag.ContinueOnError)
+	forkName := flags.String("fork", "Prague", "fork whose rules apply")
+	create := flags.Bool("create", false, "price a contract creation (calldata is init code)")
+	accessListArg := flags.String("accesslist", "", "EIP-2930 access list as JSON, or @file to read it from a file")
+	auths := flags.Int("auths", 0, "number of EIP-7702 authorizations")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: mevm intrinsic [flags] [calldata hex]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return fmt.Errorf("expected at most one calldata argument")
+	}
+
+	fork, ok := types.ParseFork(*forkName)
+	if !ok {
+		return fmt.Errorf("unknown fork %q", *forkName)
+	}
+	var data []byte
+	if flags.NArg() == 1 {
+		var err error
+		if data, err = types.ParseHexBytes(flags.Arg(0)); err != nil {
+			return fmt.Errorf("invalid calldata: %w", err)
+		}
+	}
+	var accessList types.AccessList
+	if *accessListArg != "" {
+		raw := []byte(*accessListArg)
+		if path, isFile := strings.CutPrefix(*accessListArg, "@"); isFile {
+			var err error
+			if raw, err = os.ReadFile(path); err != nil {
+				return err
+			}
+		}
+		if err := json.Unmarshal(raw, &accessList); err != nil {
+			return fmt.Errorf("invalid access list: %w", err)
+		}
+	}
+
+	zeros := 0
+	for _, b := range data {
+		if b == 0 {
+			zeros++
+		}
+	}
+	if *auths < 0 {
+		return fmt.Errorf("invalid authorization count %d", *auths)
+	}
+	intrinsic := types.IntrinsicGas(data, accessList, *auths, *create, fork)
+	fmt.Printf("Fork:              %s\n", fork)
+	fmt.Printf("Calldata:          %d bytes (%d zero, %d non-zero)\n", len(data), zeros, len(data)-zeros)
+	if fork.IsActive(types.Berlin) {
+		fmt.Printf("Access list:       %d addresses, %d storage keys\n", len(accessList), accessList.StorageKeys())
+	}
+	if fork.IsActive(types.Prague) {
+		fmt.Printf("Authorizations:    %d\n", *auths)
+	}
+	fmt.Printf("Intrinsic gas:     %d\n", intrinsic)
+
+	minimum := intrinsic
+	if fork.IsActive(types.Prague) {
+		floor := types.FloorDataGas(data)
+		fmt.Printf("Floor data gas:    %d\n", floor)
+		minimum = max(minimum, floor)
+	}
+	fmt.Printf("Minimum gas limit: %d\n", minimum)
+	return nil
+}
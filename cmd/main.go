@@ -1,12 +1,31 @@
 package main
 
 import (
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"math/big"
+	"os"
 
+	"github.com/morelucks/minievm/crypto/kzg"
+	"github.com/morelucks/minievm/trie"
 	types "github.com/morelucks/minievm/typess"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1], os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			// Commands such as t8n report failures through the exit code
+			var exit interface{ ExitCode() int }
+			if errors.As(err, &exit) {
+				os.Exit(exit.ExitCode())
+			}
+			os.Exit(1)
+		}
+		return
+	}
+
 	fmt.Println(" MiniEVM ")
 	fmt.Println()
 
@@ -116,19 +135,33 @@ func main() {
 	fmt.Println()
 
 	// Demo 5: Gas refund
-	fmt.Println("5. Gas Refund (capped at gasLimit/2):")
+	fmt.Println("5. Gas Refund (counter applied at transaction end):")
 	vm4 := types.NewVM(code, 10000)
 	vm4.ConsumeGas(3000) // Use 3000 gas
-	fmt.Printf("  Gas after consuming 3000: %d\n", vm4.GetGas())
-	vm4.RefundGas(1000) // Refund 1000
-	fmt.Printf("  Gas after refunding 1000: %d\n", vm4.GetGas())
+	vm4.RefundGas(1000)  // Refund 1000
+	fmt.Printf("  Gas after consuming 3000 and refunding 1000: %d (refund counter: %d)\n", vm4.GetGas(), vm4.Refund)
 
 	// Show refund cap
 	vm5 := types.NewVM(code, 10000)
 	vm5.ConsumeGas(5000) // Use half the gas
-	fmt.Printf("  Gas after consuming 5000: %d\n", vm5.GetGas())
-	vm5.RefundGas(3000) // Try to refund 3000 (but cap is 5000, already at max)
-	fmt.Printf("  Gas after attempting to refund 3000 (capped): %d\n", vm5.GetGas())
+	vm5.RefundGas(3000)
+	used := vm5.GasLimit - vm5.GetGas()
+	fmt.Printf("  Refund of 3000 after using 5000, Istanbul (cap 1/2): %d\n", types.CappedRefund(vm5.Refund, used, types.Istanbul))
+	fmt.Printf("  Refund of 3000 after using 5000, London (cap 1/5): %d\n", types.CappedRefund(vm5.Refund, used, types.London))
+
+	// SSTORE 1 then 0 to the same slot: the clear refund depends on the fork
+	setClear := []byte{0x60, 0x01, 0x60, 0x00, 0x55, 0x60, 0x00, 0x60, 0x00, 0x55, 0x00}
+	for _, fork := range []types.Fork{types.Istanbul, types.London} {
+		vm6 := types.NewVM(setClear, 100000)
+		vm6.Fork = fork
+		vm6.State = types.NewStateDB()
+		vm6.Storage = vm6.State.GetOrNewAccount(vm6.Address).Storage
+		if err := vm6.Execute(); err != nil {
+			fmt.Printf("  Error: %v\n", err)
+			continue
+		}
+		fmt.Printf("  SSTORE set then clear on %v: used %d, refund counter %d\n", fork, vm6.GasLimit-vm6.GetGas(), vm6.Refund)
+	}
 	fmt.Println()
 
 	// === Bytecode Interpreter Execution Demo ===
@@ -315,12 +348,14 @@ func main() {
 	// Subtraction: 125 - 5 = 120
 	fmt.Println("Subtracting (125 - 5):")
 	stack.Push(types.NewWord(5))
+	stack.Swap(1) // The top item is the minuend
 	stack.Sub()
 	stack.Print()
 
 	// Division: 120 / 4 = 30
 	fmt.Println("Dividing (120 / 4):")
 	stack.Push(types.NewWord(4))
+	stack.Swap(1) // The top item is the dividend
 	stack.Div()
 	stack.Print()
 
@@ -414,12 +449,12 @@ func main() {
 
 	// SWAP1: Swap top two elements
 	fmt.Println("SWAP1 (swap top two elements):")
-	stack.Swap(0)
+	stack.Swap(1)
 	stack.Print()
 
 	// SWAP2: Swap top and third elements
 	fmt.Println("SWAP2 (swap top and third elements):")
-	stack.Swap(1)
+	stack.Swap(2)
 	stack.Print()
 
 	fmt.Println("\n6. Advanced Operations:")
@@ -479,4 +514,372 @@ func main() {
 	testVM.Execute()
 	fmt.Printf("  After execution: %s\n", testVM.GetState())
 	fmt.Printf("  Is halted? %v\n", testVM.IsHalted())
+
+	// CLZ needs Osaka; the EIP-663 instructions are not in any fork and
+	// have to be enabled on the VM
+	fmt.Println("\n3. CLZ (Osaka) and EIP-663 Instructions (DUPN, SWAPN, EXCHANGE):")
+	osakaCode := []byte{
+		types.PUSH1, 0x01, // PUSH1 1
+		types.PUSH1, 0x02, // PUSH1 2
+		types.PUSH1, 0x03, // PUSH1 3
+		types.DUPN, 0x02, // DUPN 2 (duplicate 1)
+		types.EXCHANGE, 0x01, // EXCHANGE 0x01 (swap 2nd and 4th items)
+		types.SWAPN, 0x00, // SWAPN 0 (same as SWAP1)
+		types.CLZ,  // CLZ (leading zeros of top item)
+		types.STOP, // STOP
+	}
+	osakaVM := types.NewVM(osakaCode, 10000)
+	if err := osakaVM.Execute(); err != nil {
+		fmt.Printf("  Under %s: %v\n", osakaVM.Fork, err)
+	}
+	osakaVM = types.NewVM(osakaCode, 10000)
+	osakaVM.Fork = types.Osaka
+	if err := osakaVM.Execute(); err != nil {
+		fmt.Printf("  Under %s: %v\n", osakaVM.Fork, err)
+	}
+	osakaVM = types.NewVM(osakaCode, 10000)
+	osakaVM.Fork = types.Osaka
+	osakaVM.EnableEIP663 = true
+	if err := osakaVM.Execute(); err != nil {
+		fmt.Printf("  Under %s: %v\n", osakaVM.Fork, err)
+	} else {
+		fmt.Printf("  Under %s with EIP-663: execution successful\n", osakaVM.Fork)
+		osakaVM.Stack.Print()
+	}
+
+	// Precompiled contracts are called by address with raw input bytes
+	fmt.Println("\n4. ECRECOVER Precompile (0x01):")
+	sigInput, _ := hex.DecodeString("38d18acb67d25c8bb9942764b62f18e17054f66a817bd4295423adf9ed98873e" +
+		"000000000000000000000000000000000000000000000000000000000000001b" +
+		"38d18acb67d25c8bb9942764b62f18e17054f66a817bd4295423adf9ed98873e" +
+		"789d1dd423d25f0772d2748d60f7e4b81bb14d086eba8e8e8efb6dcff8a4ae02")
+	precompileVM := types.NewVM(nil, 10000)
+	recovered, gasLeft, err := precompileVM.RunPrecompile(types.EcrecoverAddress, sigInput, 10000)
+	if err != nil {
+		fmt.Printf("  Error: %v\n", err)
+	} else {
+		fmt.Printf("  Recovered signer: 0x%x\n", recovered[12:])
+		fmt.Printf("  Gas left: %d\n", gasLeft)
+	}
+
+	// Bytecode reaches precompiles through CALL/STATICCALL
+	fmt.Println("\n5. Hashing Precompiles via STATICCALL:")
+	hashTargets := []struct {
+		name string
+		addr byte
+	}{
+		{"SHA256", 0x02},
+		{"RIPEMD160", 0x03},
+		{"IDENTITY", 0x04},
+	}
+	for _, target := range hashTargets {
+		callCode := []byte{
+			types.PUSH3, 'a', 'b', 'c', // PUSH3 "abc"
+			types.PUSH1, 0x00, types.MSTORE, // MSTORE at 0 (bytes 29..31)
+			types.PUSH1, 0x20, // retSize 32
+			types.PUSH1, 0x20, // retOffset 32
+			types.PUSH1, 0x03, // argsSize 3
+			types.PUSH1, 0x1d, // argsOffset 29
+			types.PUSH1, target.addr, // precompile address
+			types.GAS,        // forward all available gas
+			types.STATICCALL, // STATICCALL
+			types.PUSH1, 0x20, types.MLOAD, // MLOAD result
+			types.STOP,
+		}
+		callVM := types.NewVM(callCode, 100000)
+		if err := callVM.Execute(); err != nil {
+			fmt.Printf("  %s: error %v\n", target.name, err)
+			continue
+		}
+		fmt.Printf("  %s(\"abc\"): %x (gas used %d)\n", target.name, callVM.Stack.Peek(), 100000-callVM.GetGas())
+	}
+
+	// MODEXP pricing follows the configured fork
+	fmt.Println("\n6. MODEXP Precompile (3^5 % 7):")
+	modexpInput := make([]byte, 96+3)
+	modexpInput[31], modexpInput[63], modexpInput[95] = 1, 1, 1 // 1-byte base, exponent and modulus
+	modexpInput[96], modexpInput[97], modexpInput[98] = 3, 5, 7
+	for _, fork := range []types.Fork{types.Istanbul, types.Berlin, types.Osaka} {
+		modexpVM := types.NewVM(nil, 10000)
+		modexpVM.Fork = fork
+		result, gasLeft, err := modexpVM.RunPrecompile(types.ModexpAddress, modexpInput, 10000)
+		if err != nil {
+			fmt.Printf("  %s: error %v\n", fork, err)
+			continue
+		}
+		fmt.Printf("  %s: result %d, gas used %d\n", fork, result[0], 10000-gasLeft)
+	}
+
+	// alt_bn128: G + G through ECADD must match 2 * G through ECMUL
+	fmt.Println("\n7. BN254 Precompiles (ECADD, ECMUL):")
+	generator := make([]byte, 64)
+	generator[31], generator[63] = 1, 2 // G1 generator (1, 2)
+	scalar := types.NewWord(2)
+	bnVM := types.NewVM(nil, 100000)
+	sum, _, errAdd := bnVM.RunPrecompile(types.Bn254AddAddress, append(generator, generator...), 100000)
+	doubled, _, errMul := bnVM.RunPrecompile(types.Bn254MulAddress, append(generator, scalar[:]...), 100000)
+	if errAdd != nil || errMul != nil {
+		fmt.Printf("  Error: %v %v\n", errAdd, errMul)
+	} else {
+		fmt.Printf("  G + G: %x\n", sum)
+		fmt.Printf("  2 * G: %x\n", doubled)
+		fmt.Printf("  Equal? %v\n", hex.EncodeToString(sum) == hex.EncodeToString(doubled))
+	}
+
+	// BLS12-381 precompiles only exist from Prague onwards
+	fmt.Println("\n8. BLS12-381 Precompiles (EIP-2537):")
+	blsVM := types.NewVM(nil, 100000)
+	blsVM.Fork = types.Cancun
+	fmt.Printf("  Cancun has MAP_FP_TO_G1? %v\n", blsVM.IsPrecompile(types.Bls12381MapG1Address))
+	blsVM.Fork = types.Prague
+	fmt.Printf("  Prague has MAP_FP_TO_G1? %v\n", blsVM.IsPrecompile(types.Bls12381MapG1Address))
+	fieldElement := make([]byte, 64)
+	fieldElement[63] = 1
+	point, gasLeft, err := blsVM.RunPrecompile(types.Bls12381MapG1Address, fieldElement, 100000)
+	if err != nil {
+		fmt.Printf("  Error: %v\n", err)
+	} else {
+		fmt.Printf("  map(1) = %x...\n", point[16:32])
+		fmt.Printf("  Gas used: %d\n", 100000-gasLeft)
+	}
+
+	// P256VERIFY returns empty output for an invalid signature instead of failing
+	fmt.Println("\n9. P256VERIFY Precompile (0x100):")
+	p256VM := types.NewVM(nil, 10000)
+	p256VM.Fork = types.Osaka
+	verified, gasLeft, err := p256VM.RunPrecompile(types.P256VerifyAddress, make([]byte, 160), 10000)
+	if err != nil {
+		fmt.Printf("  Error: %v\n", err)
+	} else {
+		fmt.Printf("  Zero signature valid? %v (output %d bytes)\n", len(verified) == 32, len(verified))
+		fmt.Printf("  Gas used: %d\n", 10000-gasLeft)
+	}
+	// Before Osaka, a chain can register it with the RIP-7212 price
+	p256VM.Fork = types.Prague
+	p256VM.RegisterPrecompile(types.P256VerifyAddress, types.P256Verify(p256VM.Fork))
+	if _, gasLeft, err = p256VM.RunPrecompile(types.P256VerifyAddress, make([]byte, 160), 10000); err == nil {
+		fmt.Printf("  Gas used under RIP-7212 (Prague): %d\n", 10000-gasLeft)
+	}
+
+	// The zero polynomial commits to the point at infinity and evaluates to 0 everywhere
+	fmt.Println("\n10. KZG Point Evaluation Precompile (0x0a):")
+	infinity := make([]byte, 48)
+	infinity[0] = 0xc0 // compressed point at infinity
+	versionedHash := kzg.VersionedHash(infinity)
+	kzgInput := append(versionedHash[:], make([]byte, 64)...) // z = 0, y = 0
+	kzgInput = append(kzgInput, infinity...)                  // commitment
+	kzgInput = append(kzgInput, infinity...)                  // proof
+	kzgVM := types.NewVM(nil, 100000)
+	kzgVM.Fork = types.Cancun
+	evaluation, gasLeft, err := kzgVM.RunPrecompile(types.PointEvaluationAddress, kzgInput, 100000)
+	if err != nil {
+		fmt.Printf("  Error: %v\n", err)
+	} else {
+		fmt.Printf("  Field elements per blob: %d\n", new(big.Int).SetBytes(evaluation[:32]))
+		fmt.Printf("  Gas used: %d\n", 100000-gasLeft)
+	}
+
+	// Custom precompiles: a mocked price oracle at 0x1000 and SHA256 switched off
+	fmt.Println("\n11. Precompile Registry:")
+	registryVM := types.NewVM(nil, 10000)
+	oracleAddress := types.Address{18: 0x10}
+	registryVM.RegisterPrecompile(oracleAddress, priceOracle{price: 1850})
+	registryVM.RemovePrecompile(types.Sha256Address)
+	fmt.Printf("  Active precompiles: %d\n", len(registryVM.ActivePrecompiles()))
+	fmt.Printf("  SHA256 available? %v\n", registryVM.IsPrecompile(types.Sha256Address))
+	price, gasLeft, err := registryVM.RunPrecompile(oracleAddress, nil, 10000)
+	if err != nil {
+		fmt.Printf("  Error: %v\n", err)
+	} else {
+		fmt.Printf("  Oracle price: %d (gas used %d)\n", new(big.Int).SetBytes(price), 10000-gasLeft)
+	}
+
+	// Deploy a contract that echoes its call data, then call it in a second transaction
+	fmt.Println("\n12. Applying Transactions to State:")
+	state := types.NewStateDB()
+	sender := types.Address{19: 0xaa}
+	state.AddBalance(sender, big.NewInt(1_000_000_000))
+	block := &types.BlockContext{Coinbase: types.Address{19: 0xcc}, GasLimit: 30_000_000}
+	runtime := []byte{
+		types.CALLDATASIZE, types.PUSH1, 0x00, types.PUSH1, 0x00, types.CALLDATACOPY,
+		types.CALLDATASIZE, types.PUSH1, 0x00, types.RETURN,
+	}
+	initCode := append([]byte{types.PUSH10}, runtime...)
+	initCode = append(initCode, types.PUSH1, 0x00, types.MSTORE, types.PUSH1, 0x0a, types.PUSH1, 0x16, types.RETURN)
+	deploy := &types.Message{From: sender, Nonce: 0, GasLimit: 100000, GasPrice: big.NewInt(10), Data: initCode}
+	deployed, err := types.ApplyMessage(state, block, deploy, types.Istanbul)
+	if err != nil {
+		fmt.Printf("  Error: %v\n", err)
+		return
+	}
+	fmt.Printf("  Deployed at 0x%x, gas used %d\n", *deployed.ContractAddress, deployed.UsedGas)
+	echo := &types.Message{From: sender, To: deployed.ContractAddress, Nonce: 1, GasLimit: 50000, GasPrice: big.NewInt(10), Data: []byte("hello")}
+	echoed, err := types.ApplyMessage(state, block, echo, types.Istanbul)
+	if err != nil {
+		fmt.Printf("  Error: %v\n", err)
+		return
+	}
+	fmt.Printf("  Call returned %q, gas used %d\n", echoed.ReturnData, echoed.UsedGas)
+	fmt.Printf("  Sender balance: %v, nonce %d\n", state.GetBalance(sender), state.GetNonce(sender))
+	fmt.Printf("  Coinbase balance: %v\n", state.GetBalance(block.Coinbase))
+	stateRoot := state.Root()
+	fmt.Printf("  State root: 0x%x\n", stateRoot[:])
+
+	// Demo 13: Raw signed transactions
+	fmt.Println("\n13. Decoding Signed Transactions (EIP-155 example):")
+	rawTx, _ := hex.DecodeString("f86c098504a817c800825208943535353535353535353535353535353535353535880de0b6b3a76400008025a028ef61340bd939bc2195fe537567866003e1a15d3c71ff63e1590620aa636276a067cbe9d8997f761aecb703304b3800ccf555c9f3dc64214b297fb1966a3b6d83")
+	tx, err := types.DecodeTransaction(rawTx)
+	if err != nil {
+		fmt.Printf("  Error: %v\n", err)
+		return
+	}
+	txSender, err := tx.Sender()
+	if err != nil {
+		fmt.Printf("  Error: %v\n", err)
+		return
+	}
+	txHash, sigHash := tx.Hash(), tx.SigHash()
+	fmt.Printf("  Type %d, chain id %v, nonce %d, value %v\n", tx.Type, tx.ChainID, tx.Nonce, tx.Value)
+	fmt.Printf("  Signing hash: 0x%x\n", sigHash[:])
+	fmt.Printf("  Tx hash: 0x%x\n", txHash[:])
+	fmt.Printf("  Sender: 0x%x\n", txSender)
+
+	// Demo 14: Merkle Patricia Trie
+	fmt.Println("\n14. Merkle Patricia Trie:")
+	t := trie.New()
+	fmt.Printf("  Empty root: 0x%x\n", t.Hash())
+	t.Update([]byte("doe"), []byte("reindeer"))
+	t.Update([]byte("dog"), []byte("puppy"))
+	t.Update([]byte("dogglesworth"), []byte("cat"))
+	fmt.Printf("  Root of doe, dog, dogglesworth: 0x%x\n", t.Hash())
+	fmt.Printf("  Get(\"dog\"): %q\n", t.Get([]byte("dog")))
+	t.Delete([]byte("dogglesworth"))
+	fmt.Printf("  Root after deleting dogglesworth: 0x%x\n", t.Hash())
+
+	// Demo 15: Account and storage proofs (eth_getProof)
+	fmt.Println("\n15. Account and Storage Proofs:")
+	proofState := types.NewStateDB()
+	bridge := types.Address{19: 0xb1}
+	proofState.AddBalance(bridge, big.NewInt(1_000_000))
+	proofState.SetState(bridge, types.NewWord(0), types.NewWord(42))
+	proofState.SetState(bridge, types.NewWord(1), types.NewWord(7))
+	proofState.AddBalance(types.Address{19: 0xb2}, big.NewInt(5))
+	proofRoot := proofState.Root()
+	proof := proofState.GetProof(bridge, []types.Word{types.NewWord(0), types.NewWord(5)})
+	fmt.Printf("  State root: 0x%x\n", proofRoot[:])
+	fmt.Printf("  Account proof: %d nodes, storage hash 0x%x\n", len(proof.AccountProof), proof.StorageHash[:])
+	for _, slot := range proof.StorageProof {
+		fmt.Printf("  Slot %v = %v (%d proof nodes)\n", slot.Key.ToBigInt(), slot.Value.ToInt(), len(slot.Proof))
+	}
+	fmt.Printf("  Verified: %v\n", types.VerifyAccountProof(proofRoot, proof) == nil)
+	proof.StorageProof[0].Value = types.NewHexBig(big.NewInt(43))
+	fmt.Printf("  Tampered slot value: %v\n", types.VerifyAccountProof(proofRoot, proof))
+
+	// Demo 16: Receipts, logs and the block roots
+	fmt.Println("\n16. Receipts and Logs:")
+	// LOG1 with topic 0x07 and the 32-byte word 42 as data
+	emitter := types.Address{19: 0xe1}
+	proofState.SetCode(emitter, []byte{
+		types.PUSH1, 0x2a, types.PUSH1, 0x00, types.MSTORE,
+		types.PUSH1, 0x07, types.PUSH1, 0x20, types.PUSH1, 0x00, types.LOG1, types.STOP,
+	})
+	logSender := types.Address{19: 0xa1}
+	proofState.AddBalance(logSender, big.NewInt(1_000_000_000))
+	logMsg := &types.Message{From: logSender, To: &emitter, GasLimit: 50000, GasPrice: big.NewInt(1)}
+	logged, err := types.ApplyMessage(proofState, &types.BlockContext{GasLimit: 30_000_000}, logMsg, types.Prague)
+	if err != nil {
+		fmt.Printf("  Error: %v\n", err)
+		return
+	}
+	receipt := &types.Receipt{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: logged.UsedGas, Logs: logged.Logs, Bloom: types.LogsBloom(logged.Logs)}
+	for _, log := range receipt.Logs {
+		fmt.Printf("  Log from 0x%x: topics %d, data 0x%x\n", log.Address, len(log.Topics), log.Data)
+	}
+	other := types.Address{19: 0xe2}
+	fmt.Printf("  Bloom matches emitter? %v, matches 0x%x? %v\n", receipt.Bloom.Test(emitter[:]), other, receipt.Bloom.Test(other[:]))
+	receiptsRoot, txsRoot := types.ReceiptsRoot([]*types.Receipt{receipt}), types.TransactionsRoot([]*types.Transaction{tx})
+	fmt.Printf("  Receipts root: 0x%x\n", receiptsRoot[:])
+	fmt.Printf("  Transactions root of the EIP-155 example: 0x%x\n", txsRoot[:])
+
+	// Demo 17: Processing a block: the EIP-155 transaction and a withdrawal
+	fmt.Println("\n17. Block Processing:")
+	chainState := types.NewStateDB()
+	chainState.AddBalance(txSender, big.NewInt(2e18))
+	chainState.SetNonce(txSender, tx.Nonce)
+	withdrawals := []*types.Withdrawal{{Index: 0, Validator: 7, Address: types.Address{19: 0xa7}, Amount: 32_000_000_000}}
+	header := &types.Header{Coinbase: types.Address{19: 0xc0}, Difficulty: new(big.Int), Number: 1, GasLimit: 30_000_000, Time: 12, BaseFee: big.NewInt(1_000_000_000)}
+	// A block producer executes the block first to fill in the header
+	built, err := types.ExecuteBlock(chainState.Copy(), header, []*types.Transaction{tx}, withdrawals, types.Shanghai, big.NewInt(1), nil)
+	if err != nil {
+		fmt.Printf("  Error: %v\n", err)
+		return
+	}
+	header.GasUsed, header.Bloom, header.ReceiptHash, header.Root = built.GasUsed, built.Bloom, built.ReceiptsRoot, built.StateRoot
+	processed, err := types.ProcessBlock(chainState.Copy(), header, []*types.Transaction{tx}, withdrawals, types.Shanghai, big.NewInt(1), nil)
+	if err != nil {
+		fmt.Printf("  Error: %v\n", err)
+		return
+	}
+	fmt.Printf("  Gas used %d, state root 0x%x\n", processed.GasUsed, processed.StateRoot[:])
+	header.GasUsed++
+	_, err = types.ProcessBlock(chainState.Copy(), header, []*types.Transaction{tx}, withdrawals, types.Shanghai, big.NewInt(1), nil)
+	fmt.Printf("  With a wrong gas used: %v\n", err)
+
+	// Demo 18: Base fee and blob base fee of the following blocks
+	fmt.Println("\n18. Fee Market (EIP-1559, EIP-4844):")
+	zero, blobsUsed := uint64(0), 9*types.GasPerBlob
+	parent := &types.Header{UncleHash: types.EmptyUncleHash, Difficulty: new(big.Int), Number: 100, GasLimit: 36_000_000, Time: 1200, BaseFee: big.NewInt(1_000_000_000),
+		WithdrawalsHash: &types.EmptyRootHash, BlobGasUsed: &zero, ExcessBlobGas: &zero, ParentBeaconRoot: &types.Word{}, RequestsHash: &types.Word{}}
+	for _, fill := range []struct {
+		name  string
+		gas   uint64
+		blobs uint64
+	}{{"full", 36_000_000, blobsUsed}, {"full", 36_000_000, blobsUsed}, {"half", 18_000_000, 6 * types.GasPerBlob}, {"empty", 0, 0}} {
+		excess := types.CalcExcessBlobGas(parent, types.Prague)
+		blobGas := fill.blobs
+		child := &types.Header{ParentHash: parent.Hash(), UncleHash: types.EmptyUncleHash, Difficulty: new(big.Int), Number: parent.Number + 1, GasLimit: parent.GasLimit, GasUsed: fill.gas, Time: parent.Time + 12,
+			BaseFee: types.CalcBaseFee(parent), WithdrawalsHash: &types.EmptyRootHash, BlobGasUsed: &blobGas, ExcessBlobGas: &excess, ParentBeaconRoot: &types.Word{}, RequestsHash: &types.Word{}}
+		fmt.Printf("  Block %d (%s, %d blobs): base fee %v wei, blob base fee %v wei (excess %d), valid %v\n",
+			child.Number, fill.name, fill.blobs/types.GasPerBlob, child.BaseFee, types.CalcBlobFee(excess, types.Prague), excess, types.VerifyHeader(parent, child, types.Prague) == nil)
+		parent = child
+	}
+	cheap := *parent
+	cheap.ParentHash, cheap.Number, cheap.Time, cheap.BaseFee = parent.Hash(), parent.Number+1, parent.Time+12, big.NewInt(1)
+	fmt.Printf("  Wrong base fee: %v\n", types.VerifyHeader(parent, &cheap, types.Prague))
+
+	// Demo 19: A dev chain from a geth genesis.json
+	fmt.Println("\n19. Genesis:")
+	genesis, err := types.ParseGenesis([]byte(`{
+		"config": {"chainId": 1337, "istanbulBlock": 0, "berlinBlock": 0, "londonBlock": 0, "shanghaiTime": 0, "cancunTime": 0, "pragueTime": 0},
+		"gasLimit": "0x1c9c380",
+		"difficulty": "0x0",
+		"alloc": {
+			"0x00000000000000000000000000000000000000a7": {"balance": "1000000000000000000000"},
+			"0x0000000000000000000000000000000000001000": {"balance": "0x0", "nonce": "0x1", "code": "0x60015f55", "storage": {"0x00": "0x2a"}}
+		}
+	}`))
+	if err != nil {
+		fmt.Printf("  Error: %v\n", err)
+		return
+	}
+	genesisHeader, genesisState := genesis.ToBlock()
+	genesisHash := genesisHeader.Hash()
+	fmt.Printf("  Chain %v at %s, %d accounts, slot 0 of 0x1000 = %v\n", genesis.Config.ChainID, genesis.Fork(), len(genesisState.Accounts), genesisState.GetState(types.Address{18: 0x10}, types.Word{}).ToBigInt())
+	fmt.Printf("  State root 0x%x\n", genesisHeader.Root[:])
+	fmt.Printf("  Genesis hash 0x%x\n", genesisHash[:])
+}
+
+// priceOracle is a mocked oracle precompile that always reports the same price
+type priceOracle struct {
+	price int64
+}
+
+func (o priceOracle) RequiredGas(input []byte) uint64 {
+	return 100
+}
+
+func (o priceOracle) Run(input []byte) ([]byte, error) {
+	output := make([]byte, 32)
+	big.NewInt(o.price).FillBytes(output)
+	return output, nil
 }
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/morelucks/minievm/tests"
+	types "github.com/morelucks/minievm/typess"
+)
+
+// forkSummary counts the subtests of a fork by outcome
+type forkSummary struct {
+	passed, failed, skipped int
+}
+
+// stateTestCommand runs GeneralStateTests fixtures and prints a summary
+// per fork:
+//
+//	mevm statetest [-fork F] [-run REGEXP] [-v] [-trace] <file|dir>...
+//
+// With -trace, the execution of every subtest is written to stderr in the
+// EIP-3155 format, like geth's evm statetest --json.
+func stateTestCommand(args []string) error {
+	flags := flag.NewFlagSet("statetest", flag.ContinueOnError)
+	forkName := flags.String("fork", "", "only run the post states of this fork")
+	run := flags.String("run", "", "only run tests whose name matches this regular expression")
+	verbose := flags.Bool("v", false, "print passing and skipped subtests too")
+	trace := flags.Bool("trace", false, "write an EIP-3155 JSON trace of each subtest to stderr")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: mevm statetest [flags] <file|dir>...")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("expected a test file or directory")
+	}
+	var filter *regexp.Regexp
+	if *run != "" {
+		var err error
+		if filter, err = regexp.Compile(*run); err != nil {
+			return fmt.Errorf("invalid -run pattern: %w", err)
+		}
+	}
+
+	files, err := fixtureFiles(flags.Args())
+	if err != nil {
+		return err
+	}
+	var tracer types.Tracer
+	if *trace {
+		tracer = types.NewJSONTracer(os.Stderr)
+	}
+	summary := make(map[string]*forkSummary)
+	var fileErrors int
+	for _, file := range files {
+		stateTests, err := tests.LoadStateTests(file)
+		if err != nil {
+			fmt.Printf("ERROR %v\n", err)
+			fileErrors++
+			continue
+		}
+		names := make([]string, 0, len(stateTests))
+		for name := range stateTests {
+			if filter == nil || filter.MatchString(name) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			test := stateTests[name]
+			for _, subtest := range test.Subtests() {
+				if *forkName != "" && !strings.EqualFold(subtest.Fork, *forkName) {
+					continue
+				}
+				counts, ok := summary[subtest.Fork]
+				if !ok {
+					counts = new(forkSummary)
+					summary[subtest.Fork] = counts
+				}
+				id := fmt.Sprintf("%s/%s/%d", name, subtest.Fork, subtest.Index)
+				_, err := test.Run(subtest, tracer)
+				switch {
+				case errors.Is(err, tests.ErrUnsupportedFork):
+					counts.skipped++
+					if *verbose {
+						fmt.Printf("SKIP  %s: %v\n", id, err)
+					}
+				case err != nil:
+					counts.failed++
+					fmt.Printf("FAIL  %s (%s): %v\n", id, file, err)
+				default:
+					counts.passed++
+					if *verbose {
+						fmt.Printf("PASS  %s\n", id)
+					}
+				}
+			}
+		}
+	}
+
+	total := printSummary("Fork", summary)
+	if total.failed > 0 || fileErrors > 0 {
+		return fmt.Errorf("%d subtests failed, %d files could not be read", total.failed, fileErrors)
+	}
+	return nil
+}
+
+// printSummary prints the outcome counts by fork or network, sorted by name,
+// and returns their total
+func printSummary(label string, summary map[string]*forkSummary) forkSummary {
+	width := 12
+	names := make([]string, 0, len(summary))
+	for name := range summary {
+		names = append(names, name)
+		width = max(width, len(name))
+	}
+	sort.Strings(names)
+	var total forkSummary
+	fmt.Printf("\n%-*s %8s %8s %8s\n", width, label, "Passed", "Failed", "Skipped")
+	for _, name := range names {
+		counts := summary[name]
+		fmt.Printf("%-*s %8d %8d %8d\n", width, name, counts.passed, counts.failed, counts.skipped)
+		total.passed += counts.passed
+		total.failed += counts.failed
+		total.skipped += counts.skipped
+	}
+	fmt.Printf("%-*s %8d %8d %8d\n", width, "Total", total.passed, total.failed, total.skipped)
+	return total
+}
+
+// fixtureFiles expands the arguments into the JSON files they name,
+// walking directories recursively
+func fixtureFiles(args []string) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		err = filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.HasSuffix(d.Name(), ".json") {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
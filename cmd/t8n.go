@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/morelucks/minievm/t8n"
+	"github.com/morelucks/minievm/tests"
+	types "github.com/morelucks/minievm/typess"
+)
+
+// stdinSelector as an input file reads that input from a JSON object on
+// stdin holding all of them
+const stdinSelector = "stdin"
+
+// t8nInput is the object read from stdin
+type t8nInput struct {
+	Alloc types.GenesisAlloc `json:"alloc"`
+	Env   *t8n.Env           `json:"env"`
+	Txs   []*t8n.Transaction `json:"txs"`
+	TxRlp string             `json:"txsRlp"`
+}
+
+// t8nCommand runs a state transition with the file and stdin/stdout
+// contract of geth's evm t8n:
+//
+//	mevm t8n [--input.alloc FILE] [--input.env FILE] [--input.txs FILE] [--state.fork FORK] ...
+//
+// Outputs named stdout or stderr are written there as one JSON object.
+func t8nCommand(args []string) error {
+	flags := flag.NewFlagSet("t8n", flag.ContinueOnError)
+	allocIn := flags.String("input.alloc", "alloc.json", "pre-state alloc `file`, or stdin")
+	envIn := flags.String("input.env", "env.json", "block env `file`, or stdin")
+	txsIn := flags.String("input.txs", "txs.json", "transactions `file` (JSON, or an RLP list in a .rlp file), or stdin")
+	baseDir := flags.String("output.basedir", "", "`directory` the output files are written to")
+	allocOut := flags.String("output.alloc", "alloc.json", "post-state alloc `file`, stdout or stderr")
+	resultOut := flags.String("output.result", "result.json", "result `file`, stdout or stderr")
+	bodyOut := flags.String("output.body", "", "RLP encoded body `file`, stdout or stderr; empty to leave out")
+	fork := flags.String("state.fork", "London", "`fork` or transition network to run under")
+	chainID := flags.Int64("state.chainid", 1, "chain `id`")
+	reward := flags.Int64("state.reward", 0, "block reward in wei; -1 pays none")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: mevm t8n [flags]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *baseDir != "" {
+		if err := os.MkdirAll(*baseDir, 0755); err != nil {
+			return t8n.NewError(t8n.ErrorIO, fmt.Errorf("failed creating output basedir: %w", err))
+		}
+	}
+	input := new(t8nInput)
+	if *allocIn == stdinSelector || *envIn == stdinSelector || *txsIn == stdinSelector {
+		if err := json.NewDecoder(os.Stdin).Decode(input); err != nil {
+			return t8n.NewError(t8n.ErrorJson, fmt.Errorf("failed unmarshalling stdin: %w", err))
+		}
+	}
+	prestate := &t8n.Prestate{Pre: input.Alloc}
+	if *allocIn != stdinSelector {
+		if err := readJSONFile(*allocIn, "alloc", &prestate.Pre); err != nil {
+			return err
+		}
+	}
+	if *envIn != stdinSelector {
+		input.Env = new(t8n.Env)
+		if err := readJSONFile(*envIn, "env", input.Env); err != nil {
+			return err
+		}
+	}
+	if input.Env == nil {
+		return t8n.NewError(t8n.ErrorJson, fmt.Errorf("missing env in stdin"))
+	}
+	prestate.Env = *input.Env
+
+	config, err := tests.NetworkConfig(*fork)
+	if err != nil {
+		return t8n.NewError(t8n.ErrorConfig, fmt.Errorf("failed constructing chain configuration: %w", err))
+	}
+	config.ChainID = big.NewInt(*chainID)
+	txs, err := loadTransactions(*txsIn, input, config.ChainID)
+	if err != nil {
+		return err
+	}
+
+	state, result, body, err := prestate.Apply(config, txs, *reward)
+	if err != nil {
+		return err
+	}
+	return dispatchOutput(*baseDir, map[string]string{"alloc": *allocOut, "result": *resultOut, "body": *bodyOut},
+		map[string]any{"alloc": state.Alloc(), "result": result, "body": types.HexBytes(body)})
+}
+
+// loadTransactions reads the transactions from a file or the stdin
+// input, signing those that come with a secret key instead of a signature
+func loadTransactions(path string, input *t8nInput, chainID *big.Int) ([]t8n.Tx, error) {
+	var raw []*t8n.Transaction
+	switch {
+	case path == stdinSelector && input.TxRlp != "":
+		body, err := types.ParseHexBytes(input.TxRlp)
+		if err != nil {
+			return nil, t8n.NewError(t8n.ErrorJson, fmt.Errorf("failed unmarshalling txsRlp: %w", err))
+		}
+		return t8n.DecodeTxs(body)
+	case path == stdinSelector:
+		raw = input.Txs
+	case strings.HasSuffix(path, ".rlp"):
+		// A JSON string holding the RLP list of signed transactions
+		var body types.HexBytes
+		if err := readJSONFile(path, "txs", &body); err != nil {
+			return nil, err
+		}
+		return t8n.DecodeTxs(body)
+	default:
+		if err := readJSONFile(path, "txs", &raw); err != nil {
+			return nil, err
+		}
+	}
+	txs := make([]t8n.Tx, len(raw))
+	for i, tx := range raw {
+		var err error
+		if txs[i].Tx, err = tx.ToTransaction(chainID); err != nil {
+			return nil, t8n.NewError(t8n.ErrorJson, fmt.Errorf("tx %d: %w", i, err))
+		}
+	}
+	return txs, nil
+}
+
+// readJSONFile decodes the JSON file at path, one of the inputs named name
+func readJSONFile(path, name string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return t8n.NewError(t8n.ErrorIO, fmt.Errorf("failed reading %s file: %w", name, err))
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return t8n.NewError(t8n.ErrorJson, fmt.Errorf("failed unmarshalling %s file: %w", name, err))
+	}
+	return nil
+}
+
+// dispatchOutput writes each output to the file targets names for it, or
+// collects it into the object written to stdout or stderr. An empty target
+// leaves the output out.
+func dispatchOutput(baseDir string, targets map[string]string, outputs map[string]any) error {
+	std := map[string]map[string]any{"stdout": {}, "stderr": {}}
+	for name, obj := range outputs {
+		switch target := targets[name]; target {
+		case "":
+		case "stdout", "stderr":
+			std[target][name] = obj
+		default:
+			data, err := json.MarshalIndent(obj, "", " ")
+			if err != nil {
+				return t8n.NewError(t8n.ErrorJson, fmt.Errorf("failed marshalling output: %w", err))
+			}
+			if err := os.WriteFile(filepath.Join(baseDir, target), data, 0644); err != nil {
+				return t8n.NewError(t8n.ErrorIO, fmt.Errorf("failed writing output: %w", err))
+			}
+		}
+	}
+	for target, out := range map[string]*os.File{"stdout": os.Stdout, "stderr": os.Stderr} {
+		if len(std[target]) == 0 {
+			continue
+		}
+		data, err := json.MarshalIndent(std[target], "", "  ")
+		if err != nil {
+			return t8n.NewError(t8n.ErrorJson, fmt.Errorf("failed marshalling output: %w", err))
+		}
+		fmt.Fprintln(out, string(data))
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package crypto
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+)
+
+// TestBlake2bF hashes "abc" with BLAKE2b-512 through a single compression,
+// the example of RFC 7693 appendix A
+func TestBlake2bF(t *testing.T) {
+	h := Blake2bIV
+	h[0] ^= 0x01010040 // Parameter block: 64-byte digest, no key, fanout and depth 1
+	var m [16]uint64
+	m[0] = 0x636261
+	Blake2bF(&h, m, [2]uint64{3, 0}, true, 12)
+
+	out := make([]byte, 64)
+	for i, v := range h {
+		binary.LittleEndian.PutUint64(out[i*8:], v)
+	}
+	want := "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923"
+	if got := hex.EncodeToString(out); got != want {
+		t.Errorf("hash %s, want %s", got, want)
+	}
+}
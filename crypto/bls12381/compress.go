@@ -0,0 +1,148 @@
+package bls12381
+
+import (
+	"errors"
+	"math/big"
+)
+
+// Compressed points use the ZCash serialization: the x coordinate with the
+// three top bits of the first byte holding flags for compression, infinity
+// and the sign of y (set when y is the lexicographically larger root).
+// Fp2 coordinates are written imaginary part first.
+const (
+	flagCompressed = 0x80
+	flagInfinity   = 0x40
+	flagSign       = 0x20
+)
+
+var ErrInvalidCompression = errors.New("bls12381: invalid compressed point encoding")
+
+// fpHalf is (p-1)/2; values above it are the larger of the two roots
+var fpHalf = new(big.Int).Rsh(new(big.Int).Sub(P, big.NewInt(1)), 1)
+
+// G1Generator returns the standard generator of G1
+func G1Generator() *G1 {
+	x, _ := new(big.Int).SetString("17f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb", 16)
+	y, _ := new(big.Int).SetString("08b3f481e3aaa0f1a09e30ed741d8ae4fcf5e095d5d00af600db18cb2c04b3edd03cc744a2888ae40caa232946c5e7e1", 16)
+	return &G1{x, y, false}
+}
+
+// G2Generator returns the standard generator of G2
+func G2Generator() *G2 {
+	x := fp2Constants(
+		"024aa2b2f08f0a91260805272dc51051c6e47ad4fa403b02b4510b647ae3d1770bac0326a805bbefd48056c8c121bdb8",
+		"13e02b6052719f607dacd3a088274f65596bd0d09920b61ab5da61bbdc7f5049334cf11213945d57e5ac7d055d042b7e",
+	)[0]
+	y := fp2Constants(
+		"0ce5d527727d6e118cc9cdc6da2e351aadfd9baa8cbdd3a76d429a695160d12c923ac9cc3baca289e193548608b82801",
+		"0606c4a02ea734cc32acd2b02bc28b99cb3e287e85a763af267492ab572e99ab3f370d275cec1da1aaa9075ff05f79be",
+	)[0]
+	return &G2{x, y, false}
+}
+
+// Neg returns -p
+func (p *G1) Neg() *G1 {
+	if p.infinity {
+		return p
+	}
+	return &G1{p.x, fpNeg(p.y), false}
+}
+
+// Neg returns -p
+func (p *G2) Neg() *G2 {
+	if p.infinity {
+		return p
+	}
+	return &G2{p.x, p.y.neg(), false}
+}
+
+// decompressFlags strips the flag bits from a compressed encoding and
+// reports whether it encodes infinity and the sign of y
+func decompressFlags(data []byte) (rest []byte, infinity, sign bool, err error) {
+	flags := data[0]
+	if flags&flagCompressed == 0 {
+		return nil, false, false, ErrInvalidCompression
+	}
+	rest = append([]byte{flags &^ (flagCompressed | flagInfinity | flagSign)}, data[1:]...)
+	infinity = flags&flagInfinity != 0
+	sign = flags&flagSign != 0
+	if infinity {
+		// Infinity carries no sign and no coordinate bits
+		if sign {
+			return nil, false, false, ErrInvalidCompression
+		}
+		for _, b := range rest {
+			if b != 0 {
+				return nil, false, false, ErrInvalidCompression
+			}
+		}
+	}
+	return rest, infinity, sign, nil
+}
+
+// DecompressG1 decodes a 48-byte compressed point and checks that it lies
+// in the prime order subgroup
+func DecompressG1(data []byte) (*G1, error) {
+	if len(data) != 48 {
+		return nil, ErrInvalidLength
+	}
+	rest, infinity, sign, err := decompressFlags(data)
+	if err != nil {
+		return nil, err
+	}
+	if infinity {
+		return g1Infinity(), nil
+	}
+	x := new(big.Int).SetBytes(rest)
+	if x.Cmp(P) >= 0 {
+		return nil, ErrInvalidFieldElement
+	}
+	y := fpSqrt(fpAdd(fpMul(fpMul(x, x), x), curveB))
+	if y == nil {
+		return nil, ErrNotOnCurve
+	}
+	if (y.Cmp(fpHalf) > 0) != sign {
+		y = fpNeg(y)
+	}
+	p := &G1{x, y, false}
+	if !p.InSubgroup() {
+		return nil, ErrNotInSubgroup
+	}
+	return p, nil
+}
+
+// DecompressG2 decodes a 96-byte compressed point and checks that it lies
+// in the prime order subgroup
+func DecompressG2(data []byte) (*G2, error) {
+	if len(data) != 96 {
+		return nil, ErrInvalidLength
+	}
+	rest, infinity, sign, err := decompressFlags(data)
+	if err != nil {
+		return nil, err
+	}
+	if infinity {
+		return g2Infinity(), nil
+	}
+	x := &fp2{new(big.Int).SetBytes(rest[48:]), new(big.Int).SetBytes(rest[:48])}
+	if x.a.Cmp(P) >= 0 || x.b.Cmp(P) >= 0 {
+		return nil, ErrInvalidFieldElement
+	}
+	y := x.square().mul(x).add(twistB).sqrt()
+	if y == nil {
+		return nil, ErrNotOnCurve
+	}
+	// Compare the imaginary part first, falling back to the real part
+	larger := y.b.Cmp(fpHalf) > 0
+	if y.b.Sign() == 0 {
+		larger = y.a.Cmp(fpHalf) > 0
+	}
+	if larger != sign {
+		y = y.neg()
+	}
+	q := &G2{x, y, false}
+	if !q.InSubgroup() {
+		return nil, ErrNotInSubgroup
+	}
+	return q, nil
+}
@@ -0,0 +1,89 @@
+package bls12381
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// Compressed generators in the ZCash serialization
+const (
+	g1GeneratorCompressed = "97f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb"
+	g2GeneratorCompressed = "93e02b6052719f607dacd3a088274f65596bd0d09920b61ab5da61bbdc7f5049334cf11213945d57e5ac7d055d042b7e024aa2b2f08f0a91260805272dc51051c6e47ad4fa403b02b4510b647ae3d1770bac0326a805bbefd48056c8c121bdb8"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestDecompressG1(t *testing.T) {
+	p, err := DecompressG1(mustHex(t, g1GeneratorCompressed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(p.Encode(), G1Generator().Encode()) {
+		t.Error("decompressed generator differs")
+	}
+	negated := mustHex(t, g1GeneratorCompressed)
+	negated[0] |= flagSign
+	if p, err = DecompressG1(negated); err != nil || !bytes.Equal(p.Encode(), G1Generator().Neg().Encode()) {
+		t.Errorf("sign flag did not negate the generator: %v", err)
+	}
+	infinity := make([]byte, 48)
+	infinity[0] = flagCompressed | flagInfinity
+	if p, err = DecompressG1(infinity); err != nil || !p.infinity {
+		t.Errorf("infinity decoded to %v, %v", p, err)
+	}
+
+	uncompressed := mustHex(t, g1GeneratorCompressed)
+	uncompressed[0] &^= flagCompressed
+	signedInfinity := bytes.Clone(infinity)
+	signedInfinity[0] |= flagSign
+	modulus := P.FillBytes(make([]byte, 48))
+	modulus[0] |= flagCompressed
+	outsideSubgroup := make([]byte, 48) // x = 0 gives (0, ±2), of order 3
+	outsideSubgroup[0] = flagCompressed
+
+	tests := []struct {
+		name  string
+		input []byte
+		err   error
+	}{
+		{"short", infinity[1:], ErrInvalidLength},
+		{"uncompressed", uncompressed, ErrInvalidCompression},
+		{"signedInfinity", signedInfinity, ErrInvalidCompression},
+		{"fieldModulus", modulus, ErrInvalidFieldElement},
+		{"outsideSubgroup", outsideSubgroup, ErrNotInSubgroup},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecompressG1(tt.input); !errors.Is(err, tt.err) {
+				t.Errorf("err %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestDecompressG2(t *testing.T) {
+	q, err := DecompressG2(mustHex(t, g2GeneratorCompressed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(q.Encode(), G2Generator().Encode()) {
+		t.Error("decompressed generator differs")
+	}
+	infinity := make([]byte, 96)
+	infinity[0] = flagCompressed | flagInfinity
+	if q, err = DecompressG2(infinity); err != nil || !q.infinity {
+		t.Errorf("infinity decoded to %v, %v", q, err)
+	}
+	if _, err := DecompressG2(infinity[:48]); !errors.Is(err, ErrInvalidLength) {
+		t.Errorf("err %v, want %v", err, ErrInvalidLength)
+	}
+}
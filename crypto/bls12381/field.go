@@ -0,0 +1,263 @@
+package bls12381
+
+import "math/big"
+
+// Field tower used by the curves and the pairing:
+//   Fp2  = Fp[u]  / (u^2 + 1)
+//   Fp6  = Fp2[v] / (v^3 - ξ), ξ = 1 + u
+//   Fp12 = Fp6[w] / (w^2 - v)
+
+// P is the base field modulus
+var P, _ = new(big.Int).SetString("1a0111ea397fe69a4b1ba7b6434bacd764774b84f38512bf6730d2a0f6b0f6241eabfffeb153ffffb9feffffffffaaab", 16)
+
+// Order is the prime order r of G1 and G2
+var Order, _ = new(big.Int).SetString("73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000001", 16)
+
+var (
+	// Exponent (p+1)/4 for square roots in Fp, valid because p = 3 mod 4
+	fpSqrtExp = new(big.Int).Rsh(new(big.Int).Add(P, big.NewInt(1)), 2)
+	// Exponent (p-1)/2 for Legendre symbols
+	fpLegendreExp = new(big.Int).Rsh(new(big.Int).Sub(P, big.NewInt(1)), 1)
+	// Exponent (p-3)/4 used by the Fp2 square root
+	fp2SqrtExp = new(big.Int).Rsh(new(big.Int).Sub(P, big.NewInt(3)), 2)
+)
+
+func fpMod(v *big.Int) *big.Int {
+	return v.Mod(v, P)
+}
+
+func fpAdd(a, b *big.Int) *big.Int {
+	return fpMod(new(big.Int).Add(a, b))
+}
+
+func fpSub(a, b *big.Int) *big.Int {
+	return fpMod(new(big.Int).Sub(a, b))
+}
+
+func fpMul(a, b *big.Int) *big.Int {
+	return fpMod(new(big.Int).Mul(a, b))
+}
+
+func fpNeg(a *big.Int) *big.Int {
+	return fpMod(new(big.Int).Neg(a))
+}
+
+func fpInv(a *big.Int) *big.Int {
+	return new(big.Int).ModInverse(a, P)
+}
+
+// fpIsSquare reports whether a is a quadratic residue (zero counts as square)
+func fpIsSquare(a *big.Int) bool {
+	if a.Sign() == 0 {
+		return true
+	}
+	return new(big.Int).Exp(a, fpLegendreExp, P).Cmp(big.NewInt(1)) == 0
+}
+
+// fpSqrt returns a square root of a, or nil if none exists
+func fpSqrt(a *big.Int) *big.Int {
+	root := new(big.Int).Exp(a, fpSqrtExp, P)
+	if fpMul(root, root).Cmp(fpMod(new(big.Int).Set(a))) != 0 {
+		return nil
+	}
+	return root
+}
+
+// fp2 is a + b*u
+type fp2 struct {
+	a, b *big.Int
+}
+
+func newFp2(a, b int64) *fp2 {
+	return &fp2{big.NewInt(a), big.NewInt(b)}
+}
+
+func fp2Zero() *fp2 { return newFp2(0, 0) }
+func fp2One() *fp2  { return newFp2(1, 0) }
+
+func (x *fp2) isZero() bool {
+	return x.a.Sign() == 0 && x.b.Sign() == 0
+}
+
+func (x *fp2) equal(y *fp2) bool {
+	return x.a.Cmp(y.a) == 0 && x.b.Cmp(y.b) == 0
+}
+
+func (x *fp2) add(y *fp2) *fp2 {
+	return &fp2{fpAdd(x.a, y.a), fpAdd(x.b, y.b)}
+}
+
+func (x *fp2) sub(y *fp2) *fp2 {
+	return &fp2{fpSub(x.a, y.a), fpSub(x.b, y.b)}
+}
+
+func (x *fp2) neg() *fp2 {
+	return &fp2{fpNeg(x.a), fpNeg(x.b)}
+}
+
+func (x *fp2) mul(y *fp2) *fp2 {
+	// (a + bu)(c + du) = (ac - bd) + (ad + bc)u
+	ac := new(big.Int).Mul(x.a, y.a)
+	bd := new(big.Int).Mul(x.b, y.b)
+	ad := new(big.Int).Mul(x.a, y.b)
+	bc := new(big.Int).Mul(x.b, y.a)
+	return &fp2{fpMod(ac.Sub(ac, bd)), fpMod(ad.Add(ad, bc))}
+}
+
+func (x *fp2) square() *fp2 {
+	return x.mul(x)
+}
+
+func (x *fp2) mulScalar(k *big.Int) *fp2 {
+	return &fp2{fpMul(x.a, k), fpMul(x.b, k)}
+}
+
+// norm returns a^2 + b^2, the product of x and its conjugate
+func (x *fp2) norm() *big.Int {
+	n := new(big.Int).Mul(x.a, x.a)
+	return fpMod(n.Add(n, new(big.Int).Mul(x.b, x.b)))
+}
+
+func (x *fp2) inverse() *fp2 {
+	// (a + bu)^-1 = (a - bu) / (a^2 + b^2)
+	inv := fpInv(x.norm())
+	return &fp2{fpMul(x.a, inv), fpNeg(fpMul(x.b, inv))}
+}
+
+func (x *fp2) exp(k *big.Int) *fp2 {
+	result := fp2One()
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		result = result.square()
+		if k.Bit(i) == 1 {
+			result = result.mul(x)
+		}
+	}
+	return result
+}
+
+// isSquare reports whether x is a square in Fp2, i.e. its norm is a square in Fp
+func (x *fp2) isSquare() bool {
+	return fpIsSquare(x.norm())
+}
+
+// sqrt returns a square root of x, or nil if none exists
+// (Adj and Rodríguez-Henríquez, algorithm 9, for p = 3 mod 4)
+func (x *fp2) sqrt() *fp2 {
+	a1 := x.exp(fp2SqrtExp)
+	alpha := a1.square().mul(x)
+	x0 := a1.mul(x)
+
+	var root *fp2
+	minusOne := &fp2{fpNeg(big.NewInt(1)), new(big.Int)}
+	if alpha.equal(minusOne) {
+		// root = u * x0
+		root = &fp2{fpNeg(x0.b), x0.a}
+	} else {
+		b := fp2One().add(alpha).exp(fpLegendreExp)
+		root = b.mul(x0)
+	}
+	if !root.square().equal(x) {
+		return nil
+	}
+	return root
+}
+
+// mulXi multiplies by ξ = 1 + u
+func (x *fp2) mulXi() *fp2 {
+	// (a + bu)(1 + u) = (a - b) + (a + b)u
+	return &fp2{fpSub(x.a, x.b), fpAdd(x.a, x.b)}
+}
+
+// fp6 is c0 + c1*v + c2*v^2
+type fp6 struct {
+	c0, c1, c2 *fp2
+}
+
+func fp6Zero() *fp6 { return &fp6{fp2Zero(), fp2Zero(), fp2Zero()} }
+func fp6One() *fp6  { return &fp6{fp2One(), fp2Zero(), fp2Zero()} }
+
+func (x *fp6) isZero() bool {
+	return x.c0.isZero() && x.c1.isZero() && x.c2.isZero()
+}
+
+func (x *fp6) equal(y *fp6) bool {
+	return x.c0.equal(y.c0) && x.c1.equal(y.c1) && x.c2.equal(y.c2)
+}
+
+func (x *fp6) add(y *fp6) *fp6 {
+	return &fp6{x.c0.add(y.c0), x.c1.add(y.c1), x.c2.add(y.c2)}
+}
+
+func (x *fp6) sub(y *fp6) *fp6 {
+	return &fp6{x.c0.sub(y.c0), x.c1.sub(y.c1), x.c2.sub(y.c2)}
+}
+
+func (x *fp6) neg() *fp6 {
+	return &fp6{x.c0.neg(), x.c1.neg(), x.c2.neg()}
+}
+
+func (x *fp6) mul(y *fp6) *fp6 {
+	// Schoolbook multiplication reduced with v^3 = ξ
+	c0 := x.c0.mul(y.c0).add(x.c1.mul(y.c2).add(x.c2.mul(y.c1)).mulXi())
+	c1 := x.c0.mul(y.c1).add(x.c1.mul(y.c0)).add(x.c2.mul(y.c2).mulXi())
+	c2 := x.c0.mul(y.c2).add(x.c1.mul(y.c1)).add(x.c2.mul(y.c0))
+	return &fp6{c0, c1, c2}
+}
+
+// mulV multiplies by v, shifting coefficients and reducing v^3 = ξ
+func (x *fp6) mulV() *fp6 {
+	return &fp6{x.c2.mulXi(), x.c0, x.c1}
+}
+
+func (x *fp6) inverse() *fp6 {
+	a := x.c0.square().sub(x.c1.mul(x.c2).mulXi())
+	b := x.c2.square().mulXi().sub(x.c0.mul(x.c1))
+	c := x.c1.square().sub(x.c0.mul(x.c2))
+	t := x.c0.mul(a).add(x.c2.mul(b).add(x.c1.mul(c)).mulXi())
+	tInv := t.inverse()
+	return &fp6{a.mul(tInv), b.mul(tInv), c.mul(tInv)}
+}
+
+// fp12 is c0 + c1*w
+type fp12 struct {
+	c0, c1 *fp6
+}
+
+func fp12One() *fp12 { return &fp12{fp6One(), fp6Zero()} }
+
+func (x *fp12) isOne() bool {
+	return x.c0.equal(fp6One()) && x.c1.isZero()
+}
+
+func (x *fp12) mul(y *fp12) *fp12 {
+	// (a0 + a1 w)(b0 + b1 w) = (a0 b0 + a1 b1 v) + (a0 b1 + a1 b0) w
+	c0 := x.c0.mul(y.c0).add(x.c1.mul(y.c1).mulV())
+	c1 := x.c0.mul(y.c1).add(x.c1.mul(y.c0))
+	return &fp12{c0, c1}
+}
+
+func (x *fp12) square() *fp12 {
+	return x.mul(x)
+}
+
+// conjugate is the p^6 Frobenius, which maps w to -w
+func (x *fp12) conjugate() *fp12 {
+	return &fp12{x.c0, x.c1.neg()}
+}
+
+func (x *fp12) inverse() *fp12 {
+	// (a0 + a1 w)^-1 = (a0 - a1 w) / (a0^2 - a1^2 v)
+	t := x.c0.mul(x.c0).sub(x.c1.mul(x.c1).mulV()).inverse()
+	return &fp12{x.c0.mul(t), x.c1.mul(t).neg()}
+}
+
+func (x *fp12) exp(k *big.Int) *fp12 {
+	result := fp12One()
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		result = result.square()
+		if k.Bit(i) == 1 {
+			result = result.mul(x)
+		}
+	}
+	return result
+}
@@ -0,0 +1,141 @@
+// Package bls12381 implements the BLS12-381 curve operations behind the
+// EIP-2537 precompiles: point addition, multi-scalar multiplication,
+// pairing checks and mapping field elements to the curve.
+package bls12381
+
+import (
+	"errors"
+	"math/big"
+)
+
+var (
+	ErrInvalidLength       = errors.New("bls12381: invalid input length")
+	ErrInvalidFieldElement = errors.New("bls12381: invalid field element encoding")
+	ErrNotOnCurve          = errors.New("bls12381: point not on curve")
+	ErrNotInSubgroup       = errors.New("bls12381: point not in prime order subgroup")
+)
+
+var curveB = big.NewInt(4)
+
+// decodeFieldElement reads a 64-byte field element: 16 zero bytes followed
+// by the 48-byte big-endian value, which must be less than p
+func decodeFieldElement(data []byte) (*big.Int, error) {
+	if len(data) != 64 {
+		return nil, ErrInvalidLength
+	}
+	for _, b := range data[:16] {
+		if b != 0 {
+			return nil, ErrInvalidFieldElement
+		}
+	}
+	value := new(big.Int).SetBytes(data[16:])
+	if value.Cmp(P) >= 0 {
+		return nil, ErrInvalidFieldElement
+	}
+	return value, nil
+}
+
+func encodeFieldElement(out []byte, value *big.Int) {
+	value.FillBytes(out[16:64])
+}
+
+// G1 is an affine point on y^2 = x^3 + 4 over Fp
+type G1 struct {
+	x, y     *big.Int
+	infinity bool
+}
+
+func g1Infinity() *G1 {
+	return &G1{new(big.Int), new(big.Int), true}
+}
+
+// DecodeG1 decodes a 128-byte point (x || y); all zeros encodes infinity.
+// The point must be on the curve but is not checked against the subgroup.
+func DecodeG1(data []byte) (*G1, error) {
+	if len(data) != 128 {
+		return nil, ErrInvalidLength
+	}
+	x, err := decodeFieldElement(data[:64])
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeFieldElement(data[64:])
+	if err != nil {
+		return nil, err
+	}
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return g1Infinity(), nil
+	}
+	p := &G1{x, y, false}
+	if !p.isOnCurve() {
+		return nil, ErrNotOnCurve
+	}
+	return p, nil
+}
+
+// Encode returns the 128-byte encoding of the point
+func (p *G1) Encode() []byte {
+	out := make([]byte, 128)
+	if !p.infinity {
+		encodeFieldElement(out[:64], p.x)
+		encodeFieldElement(out[64:], p.y)
+	}
+	return out
+}
+
+func (p *G1) isOnCurve() bool {
+	lhs := fpMul(p.y, p.y)
+	rhs := fpAdd(fpMul(fpMul(p.x, p.x), p.x), curveB)
+	return lhs.Cmp(rhs) == 0
+}
+
+// InSubgroup reports whether the point has order r
+func (p *G1) InSubgroup() bool {
+	return p.ScalarMult(Order).infinity
+}
+
+func (p *G1) double() *G1 {
+	if p.infinity || p.y.Sign() == 0 {
+		return g1Infinity()
+	}
+	// λ = 3x^2 / 2y
+	lambda := fpMul(fpMul(big.NewInt(3), fpMul(p.x, p.x)), fpInv(fpMul(big.NewInt(2), p.y)))
+	return p.withSlope(p, lambda)
+}
+
+// withSlope returns p + q given the slope λ of the line through them
+func (p *G1) withSlope(q *G1, lambda *big.Int) *G1 {
+	x3 := fpSub(fpSub(fpMul(lambda, lambda), p.x), q.x)
+	y3 := fpSub(fpMul(lambda, fpSub(p.x, x3)), p.y)
+	return &G1{x3, y3, false}
+}
+
+// Add returns p + q
+func (p *G1) Add(q *G1) *G1 {
+	if p.infinity {
+		return q
+	}
+	if q.infinity {
+		return p
+	}
+	if p.x.Cmp(q.x) == 0 {
+		if p.y.Cmp(q.y) == 0 {
+			return p.double()
+		}
+		return g1Infinity()
+	}
+	lambda := fpMul(fpSub(q.y, p.y), fpInv(fpSub(q.x, p.x)))
+	return p.withSlope(q, lambda)
+}
+
+// ScalarMult returns k*p
+func (p *G1) ScalarMult(k *big.Int) *G1 {
+	result := g1Infinity()
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		result = result.double()
+		if k.Bit(i) == 1 {
+			result = result.Add(p)
+		}
+	}
+	return result
+}
@@ -0,0 +1,76 @@
+package bls12381
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestG1GroupLaw(t *testing.T) {
+	g := G1Generator()
+	if !g.InSubgroup() {
+		t.Fatal("generator not in the subgroup")
+	}
+	decoded, err := DecodeG1(g.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded.Encode(), g.Encode()) {
+		t.Error("encoding does not round trip")
+	}
+	if !bytes.Equal(g.Add(g).Encode(), g.ScalarMult(big.NewInt(2)).Encode()) {
+		t.Error("g + g differs from 2g")
+	}
+	three := g.Add(g).Add(g)
+	if !bytes.Equal(three.Encode(), g.ScalarMult(big.NewInt(3)).Encode()) {
+		t.Error("g + g + g differs from 3g")
+	}
+	if !g.Add(g.Neg()).infinity {
+		t.Error("g - g is not infinity")
+	}
+	if !g.ScalarMult(Order).infinity {
+		t.Error("r*g is not infinity")
+	}
+	if inf, err := DecodeG1(make([]byte, 128)); err != nil || !inf.infinity {
+		t.Errorf("zero encoding decoded to %v, %v; want infinity", inf, err)
+	}
+}
+
+func TestDecodeG1Errors(t *testing.T) {
+	// (0, 2) lies on y^2 = x^3 + 4 but outside the prime order subgroup
+	onCurve := make([]byte, 128)
+	onCurve[127] = 2
+	p, err := DecodeG1(onCurve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.InSubgroup() {
+		t.Error("(0, 2) reported in the subgroup")
+	}
+
+	notOnCurve := make([]byte, 128)
+	notOnCurve[63], notOnCurve[127] = 1, 1
+	tooLarge := G1Generator().Encode()
+	P.FillBytes(tooLarge[16:64])
+	badPadding := G1Generator().Encode()
+	badPadding[0] = 1
+
+	tests := []struct {
+		name  string
+		input []byte
+		err   error
+	}{
+		{"short", onCurve[1:], ErrInvalidLength},
+		{"notOnCurve", notOnCurve, ErrNotOnCurve},
+		{"fieldModulus", tooLarge, ErrInvalidFieldElement},
+		{"padding", badPadding, ErrInvalidFieldElement},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeG1(tt.input); !errors.Is(err, tt.err) {
+				t.Errorf("err %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package bls12381
+
+import "math/big"
+
+// twistB is the coefficient of the M-type twist y^2 = x^3 + 4(1 + u) over Fp2
+var twistB = newFp2(4, 4)
+
+// G2 is an affine point on the twist curve over Fp2
+type G2 struct {
+	x, y     *fp2
+	infinity bool
+}
+
+func g2Infinity() *G2 {
+	return &G2{fp2Zero(), fp2Zero(), true}
+}
+
+func decodeFp2(data []byte) (*fp2, error) {
+	a, err := decodeFieldElement(data[:64])
+	if err != nil {
+		return nil, err
+	}
+	b, err := decodeFieldElement(data[64:128])
+	if err != nil {
+		return nil, err
+	}
+	return &fp2{a, b}, nil
+}
+
+// DecodeG2 decodes a 256-byte point. Each Fp2 coordinate a + b*u is encoded
+// as a || b, and all zeros encodes infinity. The point must be on the curve
+// but is not checked against the subgroup.
+func DecodeG2(data []byte) (*G2, error) {
+	if len(data) != 256 {
+		return nil, ErrInvalidLength
+	}
+	x, err := decodeFp2(data[:128])
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeFp2(data[128:])
+	if err != nil {
+		return nil, err
+	}
+	if x.isZero() && y.isZero() {
+		return g2Infinity(), nil
+	}
+	q := &G2{x, y, false}
+	if !q.isOnCurve() {
+		return nil, ErrNotOnCurve
+	}
+	return q, nil
+}
+
+// Encode returns the 256-byte encoding of the point
+func (p *G2) Encode() []byte {
+	out := make([]byte, 256)
+	if !p.infinity {
+		encodeFieldElement(out[0:64], p.x.a)
+		encodeFieldElement(out[64:128], p.x.b)
+		encodeFieldElement(out[128:192], p.y.a)
+		encodeFieldElement(out[192:256], p.y.b)
+	}
+	return out
+}
+
+func (p *G2) isOnCurve() bool {
+	return p.y.square().equal(p.x.square().mul(p.x).add(twistB))
+}
+
+// InSubgroup reports whether the point has order r
+func (p *G2) InSubgroup() bool {
+	return p.ScalarMult(Order).infinity
+}
+
+func (p *G2) double() *G2 {
+	if p.infinity || p.y.isZero() {
+		return g2Infinity()
+	}
+	// λ = 3x^2 / 2y
+	lambda := p.x.square().mulScalar(big.NewInt(3)).mul(p.y.add(p.y).inverse())
+	return p.withSlope(p, lambda)
+}
+
+func (p *G2) withSlope(q *G2, lambda *fp2) *G2 {
+	x3 := lambda.square().sub(p.x).sub(q.x)
+	y3 := lambda.mul(p.x.sub(x3)).sub(p.y)
+	return &G2{x3, y3, false}
+}
+
+// Add returns p + q
+func (p *G2) Add(q *G2) *G2 {
+	if p.infinity {
+		return q
+	}
+	if q.infinity {
+		return p
+	}
+	if p.x.equal(q.x) {
+		if p.y.equal(q.y) {
+			return p.double()
+		}
+		return g2Infinity()
+	}
+	lambda := q.y.sub(p.y).mul(q.x.sub(p.x).inverse())
+	return p.withSlope(q, lambda)
+}
+
+// ScalarMult returns k*p
+func (p *G2) ScalarMult(k *big.Int) *G2 {
+	result := g2Infinity()
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		result = result.double()
+		if k.Bit(i) == 1 {
+			result = result.Add(p)
+		}
+	}
+	return result
+}
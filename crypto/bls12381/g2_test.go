@@ -0,0 +1,39 @@
+package bls12381
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestG2GroupLaw(t *testing.T) {
+	g := G2Generator()
+	if !g.InSubgroup() {
+		t.Fatal("generator not in the subgroup")
+	}
+	decoded, err := DecodeG2(g.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded.Encode(), g.Encode()) {
+		t.Error("encoding does not round trip")
+	}
+	if !bytes.Equal(g.Add(g).Encode(), g.ScalarMult(big.NewInt(2)).Encode()) {
+		t.Error("g + g differs from 2g")
+	}
+	if !g.Add(g.Neg()).infinity {
+		t.Error("g - g is not infinity")
+	}
+}
+
+func TestDecodeG2Errors(t *testing.T) {
+	notOnCurve := make([]byte, 256)
+	notOnCurve[63] = 1
+	if _, err := DecodeG2(notOnCurve); !errors.Is(err, ErrNotOnCurve) {
+		t.Errorf("err %v, want %v", err, ErrNotOnCurve)
+	}
+	if _, err := DecodeG2(notOnCurve[:255]); !errors.Is(err, ErrInvalidLength) {
+		t.Errorf("err %v, want %v", err, ErrInvalidLength)
+	}
+}
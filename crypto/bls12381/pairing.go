@@ -0,0 +1,76 @@
+package bls12381
+
+import "math/big"
+
+// The pairing is the reduced Tate pairing e(P, Q) = f_{r,P}(Q)^((p^12-1)/r).
+// The M-type twist point Q = (x, y) maps into E(Fp12) as
+// (x / w^2, y / w^3) = ((x/ξ) v^2, (y/ξ) v w), using w^6 = ξ.
+// Q's x coordinate lies in Fp6, so vertical line denominators are removed
+// by the final exponentiation and can be skipped in the Miller loop.
+
+// finalExponent is (p^6 + 1) / r, the hard part after the p^6 - 1 step
+var finalExponent = func() *big.Int {
+	e := new(big.Int).Exp(P, big.NewInt(6), nil)
+	e.Add(e, big.NewInt(1))
+	return e.Div(e, Order)
+}()
+
+var xiInverse = newFp2(1, 1).inverse()
+
+// untwisted holds the coefficients x/ξ and y/ξ of Q's image in E(Fp12)
+type untwisted struct {
+	x, y *fp2
+}
+
+// lineAt evaluates at Q the line with slope λ through T:
+// yQ - yT - λ(xQ - xT) = (λxT - yT) - λ(x/ξ) v^2 + (y/ξ) v w
+func lineAt(lambda *big.Int, t *G1, q *untwisted) *fp12 {
+	constant := fpSub(fpMul(lambda, t.x), t.y)
+	return &fp12{
+		c0: &fp6{&fp2{constant, new(big.Int)}, fp2Zero(), q.x.mulScalar(fpNeg(lambda))},
+		c1: &fp6{fp2Zero(), q.y, fp2Zero()},
+	}
+}
+
+// millerLoop computes f_{r,P}(Q) by double-and-add over the bits of r
+func millerLoop(p *G1, q *G2) *fp12 {
+	image := &untwisted{q.x.mul(xiInverse), q.y.mul(xiInverse)}
+	f := fp12One()
+	t := p
+	for i := Order.BitLen() - 2; i >= 0; i-- {
+		lambda := fpMul(fpMul(big.NewInt(3), fpMul(t.x, t.x)), fpInv(fpMul(big.NewInt(2), t.y)))
+		f = f.square().mul(lineAt(lambda, t, image))
+		t = t.withSlope(t, lambda)
+
+		if Order.Bit(i) == 1 {
+			if t.x.Cmp(p.x) == 0 {
+				// T = -P on the final step: the line is vertical
+				t = g1Infinity()
+				continue
+			}
+			lambda = fpMul(fpSub(p.y, t.y), fpInv(fpSub(p.x, t.x)))
+			f = f.mul(lineAt(lambda, t, image))
+			t = t.withSlope(p, lambda)
+		}
+	}
+	return f
+}
+
+func finalExponentiation(f *fp12) *fp12 {
+	// f^(p^6 - 1) = conj(f) / f, then raise to (p^6 + 1) / r
+	f = f.conjugate().mul(f.inverse())
+	return f.exp(finalExponent)
+}
+
+// PairingCheck returns true if the product of e(g1[i], g2[i]) is one.
+// Pairs with a point at infinity contribute one and are skipped.
+func PairingCheck(g1 []*G1, g2 []*G2) bool {
+	acc := fp12One()
+	for i := range g1 {
+		if g1[i].infinity || g2[i].infinity {
+			continue
+		}
+		acc = acc.mul(millerLoop(g1[i], g2[i]))
+	}
+	return finalExponentiation(acc).isOne()
+}
@@ -0,0 +1,32 @@
+package bls12381
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestPairingCheck(t *testing.T) {
+	g1, g2 := G1Generator(), G2Generator()
+	a, b := big.NewInt(5), big.NewInt(7)
+	ab := big.NewInt(35)
+	tests := []struct {
+		name string
+		g1   []*G1
+		g2   []*G2
+		want bool
+	}{
+		{"empty", nil, nil, true},
+		{"single", []*G1{g1}, []*G2{g2}, false},
+		{"inverse", []*G1{g1, g1.Neg()}, []*G2{g2, g2}, true},
+		{"bilinear", []*G1{g1.ScalarMult(a), g1.Neg()}, []*G2{g2.ScalarMult(b), g2.ScalarMult(ab)}, true},
+		{"notBilinear", []*G1{g1.ScalarMult(a), g1.Neg()}, []*G2{g2.ScalarMult(b), g2.ScalarMult(a)}, false},
+		{"infinity", []*G1{g1Infinity()}, []*G2{g2}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PairingCheck(tt.g1, tt.g2); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package bn254
+
+import "math/big"
+
+// Field tower used by the pairing:
+//   Fp2  = Fp[i]  / (i^2 + 1)
+//   Fp6  = Fp2[v] / (v^3 - ξ), ξ = 9 + i
+//   Fp12 = Fp6[w] / (w^2 - v)
+
+// P is the base field modulus
+var P, _ = new(big.Int).SetString("21888242871839275222246405745257275088696311157297823662689037894645226208583", 10)
+
+// Order is the prime order r of G1 and G2
+var Order, _ = new(big.Int).SetString("21888242871839275222246405745257275088548364400416034343698204186575808495617", 10)
+
+func fpMod(v *big.Int) *big.Int {
+	return v.Mod(v, P)
+}
+
+func fpAdd(a, b *big.Int) *big.Int {
+	return fpMod(new(big.Int).Add(a, b))
+}
+
+func fpSub(a, b *big.Int) *big.Int {
+	return fpMod(new(big.Int).Sub(a, b))
+}
+
+func fpMul(a, b *big.Int) *big.Int {
+	return fpMod(new(big.Int).Mul(a, b))
+}
+
+func fpInv(a *big.Int) *big.Int {
+	return new(big.Int).ModInverse(a, P)
+}
+
+// fp2 is a + b*i
+type fp2 struct {
+	a, b *big.Int
+}
+
+func newFp2(a, b int64) *fp2 {
+	return &fp2{big.NewInt(a), big.NewInt(b)}
+}
+
+func fp2Zero() *fp2 { return newFp2(0, 0) }
+func fp2One() *fp2  { return newFp2(1, 0) }
+
+func (x *fp2) isZero() bool {
+	return x.a.Sign() == 0 && x.b.Sign() == 0
+}
+
+func (x *fp2) equal(y *fp2) bool {
+	return x.a.Cmp(y.a) == 0 && x.b.Cmp(y.b) == 0
+}
+
+func (x *fp2) add(y *fp2) *fp2 {
+	return &fp2{fpAdd(x.a, y.a), fpAdd(x.b, y.b)}
+}
+
+func (x *fp2) sub(y *fp2) *fp2 {
+	return &fp2{fpSub(x.a, y.a), fpSub(x.b, y.b)}
+}
+
+func (x *fp2) neg() *fp2 {
+	return fp2Zero().sub(x)
+}
+
+func (x *fp2) mul(y *fp2) *fp2 {
+	// (a + bi)(c + di) = (ac - bd) + (ad + bc)i
+	ac := new(big.Int).Mul(x.a, y.a)
+	bd := new(big.Int).Mul(x.b, y.b)
+	ad := new(big.Int).Mul(x.a, y.b)
+	bc := new(big.Int).Mul(x.b, y.a)
+	return &fp2{fpMod(ac.Sub(ac, bd)), fpMod(ad.Add(ad, bc))}
+}
+
+func (x *fp2) square() *fp2 {
+	return x.mul(x)
+}
+
+func (x *fp2) mulScalar(k *big.Int) *fp2 {
+	return &fp2{fpMul(x.a, k), fpMul(x.b, k)}
+}
+
+func (x *fp2) inverse() *fp2 {
+	// (a + bi)^-1 = (a - bi) / (a^2 + b^2)
+	norm := new(big.Int).Mul(x.a, x.a)
+	norm.Add(norm, new(big.Int).Mul(x.b, x.b))
+	inv := fpInv(fpMod(norm))
+	return &fp2{fpMul(x.a, inv), fpMod(new(big.Int).Neg(fpMul(x.b, inv)))}
+}
+
+// mulXi multiplies by ξ = 9 + i
+func (x *fp2) mulXi() *fp2 {
+	// (a + bi)(9 + i) = (9a - b) + (a + 9b)i
+	a := new(big.Int).Mul(x.a, big.NewInt(9))
+	a.Sub(a, x.b)
+	b := new(big.Int).Mul(x.b, big.NewInt(9))
+	b.Add(b, x.a)
+	return &fp2{fpMod(a), fpMod(b)}
+}
+
+// fp6 is c0 + c1*v + c2*v^2
+type fp6 struct {
+	c0, c1, c2 *fp2
+}
+
+func fp6Zero() *fp6 { return &fp6{fp2Zero(), fp2Zero(), fp2Zero()} }
+func fp6One() *fp6  { return &fp6{fp2One(), fp2Zero(), fp2Zero()} }
+
+func (x *fp6) isZero() bool {
+	return x.c0.isZero() && x.c1.isZero() && x.c2.isZero()
+}
+
+func (x *fp6) equal(y *fp6) bool {
+	return x.c0.equal(y.c0) && x.c1.equal(y.c1) && x.c2.equal(y.c2)
+}
+
+func (x *fp6) add(y *fp6) *fp6 {
+	return &fp6{x.c0.add(y.c0), x.c1.add(y.c1), x.c2.add(y.c2)}
+}
+
+func (x *fp6) sub(y *fp6) *fp6 {
+	return &fp6{x.c0.sub(y.c0), x.c1.sub(y.c1), x.c2.sub(y.c2)}
+}
+
+func (x *fp6) neg() *fp6 {
+	return &fp6{x.c0.neg(), x.c1.neg(), x.c2.neg()}
+}
+
+func (x *fp6) mul(y *fp6) *fp6 {
+	// Schoolbook multiplication reduced with v^3 = ξ
+	c0 := x.c0.mul(y.c0).add(x.c1.mul(y.c2).add(x.c2.mul(y.c1)).mulXi())
+	c1 := x.c0.mul(y.c1).add(x.c1.mul(y.c0)).add(x.c2.mul(y.c2).mulXi())
+	c2 := x.c0.mul(y.c2).add(x.c1.mul(y.c1)).add(x.c2.mul(y.c0))
+	return &fp6{c0, c1, c2}
+}
+
+// mulV multiplies by v, shifting coefficients and reducing v^3 = ξ
+func (x *fp6) mulV() *fp6 {
+	return &fp6{x.c2.mulXi(), x.c0, x.c1}
+}
+
+func (x *fp6) inverse() *fp6 {
+	a := x.c0.square().sub(x.c1.mul(x.c2).mulXi())
+	b := x.c2.square().mulXi().sub(x.c0.mul(x.c1))
+	c := x.c1.square().sub(x.c0.mul(x.c2))
+	t := x.c0.mul(a).add(x.c2.mul(b).add(x.c1.mul(c)).mulXi())
+	tInv := t.inverse()
+	return &fp6{a.mul(tInv), b.mul(tInv), c.mul(tInv)}
+}
+
+// fp12 is c0 + c1*w
+type fp12 struct {
+	c0, c1 *fp6
+}
+
+func fp12One() *fp12 { return &fp12{fp6One(), fp6Zero()} }
+
+func (x *fp12) isOne() bool {
+	return x.c0.equal(fp6One()) && x.c1.isZero()
+}
+
+func (x *fp12) mul(y *fp12) *fp12 {
+	// (a0 + a1 w)(b0 + b1 w) = (a0 b0 + a1 b1 v) + (a0 b1 + a1 b0) w
+	c0 := x.c0.mul(y.c0).add(x.c1.mul(y.c1).mulV())
+	c1 := x.c0.mul(y.c1).add(x.c1.mul(y.c0))
+	return &fp12{c0, c1}
+}
+
+func (x *fp12) square() *fp12 {
+	return x.mul(x)
+}
+
+// conjugate is the p^6 Frobenius, which maps w to -w
+func (x *fp12) conjugate() *fp12 {
+	return &fp12{x.c0, x.c1.neg()}
+}
+
+func (x *fp12) inverse() *fp12 {
+	// (a0 + a1 w)^-1 = (a0 - a1 w) / (a0^2 - a1^2 v)
+	t := x.c0.mul(x.c0).sub(x.c1.mul(x.c1).mulV()).inverse()
+	return &fp12{x.c0.mul(t), x.c1.mul(t).neg()}
+}
+
+func (x *fp12) exp(k *big.Int) *fp12 {
+	result := fp12One()
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		result = result.square()
+		if k.Bit(i) == 1 {
+			result = result.mul(x)
+		}
+	}
+	return result
+}
@@ -0,0 +1,107 @@
+// Package bn254 implements the alt_bn128 curve operations behind the
+// ECADD, ECMUL and ECPAIRING precompiles (EIP-196, EIP-197).
+package bn254
+
+import (
+	"errors"
+	"math/big"
+)
+
+var (
+	ErrInvalidLength      = errors.New("bn254: invalid input length")
+	ErrCoordinateTooLarge = errors.New("bn254: coordinate not less than field modulus")
+	ErrNotOnCurve         = errors.New("bn254: point not on curve")
+	ErrNotInSubgroup      = errors.New("bn254: point not in prime order subgroup")
+)
+
+var curveB = big.NewInt(3)
+
+// G1 is an affine point on y^2 = x^3 + 3 over Fp
+type G1 struct {
+	x, y     *big.Int
+	infinity bool
+}
+
+func g1Infinity() *G1 {
+	return &G1{new(big.Int), new(big.Int), true}
+}
+
+// UnmarshalG1 decodes a 64-byte big-endian (x, y) point; (0, 0) encodes infinity.
+// Every point on the curve is in G1, so no subgroup check is needed.
+func UnmarshalG1(data []byte) (*G1, error) {
+	if len(data) != 64 {
+		return nil, ErrInvalidLength
+	}
+	x := new(big.Int).SetBytes(data[:32])
+	y := new(big.Int).SetBytes(data[32:])
+	if x.Cmp(P) >= 0 || y.Cmp(P) >= 0 {
+		return nil, ErrCoordinateTooLarge
+	}
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return g1Infinity(), nil
+	}
+
+	// y^2 == x^3 + 3
+	lhs := fpMul(y, y)
+	rhs := fpAdd(fpMul(fpMul(x, x), x), curveB)
+	if lhs.Cmp(rhs) != 0 {
+		return nil, ErrNotOnCurve
+	}
+	return &G1{x, y, false}, nil
+}
+
+// Marshal encodes the point as 64 bytes, infinity as all zeros
+func (p *G1) Marshal() []byte {
+	out := make([]byte, 64)
+	if !p.infinity {
+		p.x.FillBytes(out[:32])
+		p.y.FillBytes(out[32:])
+	}
+	return out
+}
+
+func (p *G1) double() *G1 {
+	if p.infinity || p.y.Sign() == 0 {
+		return g1Infinity()
+	}
+	// λ = 3x^2 / 2y
+	lambda := fpMul(fpMul(big.NewInt(3), fpMul(p.x, p.x)), fpInv(fpMul(big.NewInt(2), p.y)))
+	return p.withSlope(p, lambda)
+}
+
+// withSlope returns p + q given the slope λ of the line through them
+func (p *G1) withSlope(q *G1, lambda *big.Int) *G1 {
+	x3 := fpSub(fpSub(fpMul(lambda, lambda), p.x), q.x)
+	y3 := fpSub(fpMul(lambda, fpSub(p.x, x3)), p.y)
+	return &G1{x3, y3, false}
+}
+
+// Add returns p + q
+func (p *G1) Add(q *G1) *G1 {
+	if p.infinity {
+		return q
+	}
+	if q.infinity {
+		return p
+	}
+	if p.x.Cmp(q.x) == 0 {
+		if p.y.Cmp(q.y) == 0 {
+			return p.double()
+		}
+		return g1Infinity()
+	}
+	lambda := fpMul(fpSub(q.y, p.y), fpInv(fpSub(q.x, p.x)))
+	return p.withSlope(q, lambda)
+}
+
+// ScalarMult returns k*p
+func (p *G1) ScalarMult(k *big.Int) *G1 {
+	result := g1Infinity()
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		result = result.double()
+		if k.Bit(i) == 1 {
+			result = result.Add(p)
+		}
+	}
+	return result
+}
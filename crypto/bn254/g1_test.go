@@ -0,0 +1,57 @@
+package bn254
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestG1GroupLaw(t *testing.T) {
+	g, err := UnmarshalG1(mustHex(t, "00000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	two := mustHex(t, "030644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd315ed738c0e0a7c92e7845f96b2ae9c0a68a6a449e3538fc7ff3ebf7a5a18a2c4")
+	if got := g.Add(g).Marshal(); !bytes.Equal(got, two) {
+		t.Errorf("g + g = %x, want %x", got, two)
+	}
+	if got := g.ScalarMult(big.NewInt(2)).Marshal(); !bytes.Equal(got, two) {
+		t.Errorf("2g = %x, want %x", got, two)
+	}
+	if !g.ScalarMult(Order).infinity {
+		t.Error("r*g is not infinity")
+	}
+	if got := g.ScalarMult(new(big.Int)).Marshal(); !bytes.Equal(got, make([]byte, 64)) {
+		t.Errorf("0*g = %x, want infinity", got)
+	}
+}
+
+func TestUnmarshalG1Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		err   error
+	}{
+		{"short", "0000000000000000000000000000000000000000000000000000000000000001", ErrInvalidLength},
+		{"notOnCurve", "00000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000003", ErrNotOnCurve},
+		{"xModulus", "30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd470000000000000000000000000000000000000000000000000000000000000002", ErrCoordinateTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := UnmarshalG1(mustHex(t, tt.input)); !errors.Is(err, tt.err) {
+				t.Errorf("err %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package bn254
+
+import "math/big"
+
+// twistB is the coefficient of the sextic twist y^2 = x^3 + 3/ξ over Fp2
+var twistB = newFp2(3, 0).mul(newFp2(9, 1).inverse())
+
+// G2 is an affine point on the twist curve over Fp2
+type G2 struct {
+	x, y     *fp2
+	infinity bool
+}
+
+func g2Infinity() *G2 {
+	return &G2{fp2Zero(), fp2Zero(), true}
+}
+
+// UnmarshalG2 decodes a 128-byte point. Each Fp2 coordinate a + b*i is
+// encoded as b || a (imaginary part first), and all zeros encodes infinity.
+// The point must lie on the twist and in the order-r subgroup.
+func UnmarshalG2(data []byte) (*G2, error) {
+	if len(data) != 128 {
+		return nil, ErrInvalidLength
+	}
+	var coords [4]*big.Int
+	for i := range coords {
+		coords[i] = new(big.Int).SetBytes(data[i*32 : (i+1)*32])
+		if coords[i].Cmp(P) >= 0 {
+			return nil, ErrCoordinateTooLarge
+		}
+	}
+	x := &fp2{coords[1], coords[0]}
+	y := &fp2{coords[3], coords[2]}
+	if x.isZero() && y.isZero() {
+		return g2Infinity(), nil
+	}
+
+	// y^2 == x^3 + 3/ξ
+	if !y.square().equal(x.square().mul(x).add(twistB)) {
+		return nil, ErrNotOnCurve
+	}
+	q := &G2{x, y, false}
+	if !q.scalarMult(Order).infinity {
+		return nil, ErrNotInSubgroup
+	}
+	return q, nil
+}
+
+func (p *G2) double() *G2 {
+	if p.infinity || p.y.isZero() {
+		return g2Infinity()
+	}
+	// λ = 3x^2 / 2y
+	lambda := p.x.square().mulScalar(big.NewInt(3)).mul(p.y.add(p.y).inverse())
+	return p.withSlope(p, lambda)
+}
+
+func (p *G2) withSlope(q *G2, lambda *fp2) *G2 {
+	x3 := lambda.square().sub(p.x).sub(q.x)
+	y3 := lambda.mul(p.x.sub(x3)).sub(p.y)
+	return &G2{x3, y3, false}
+}
+
+func (p *G2) add(q *G2) *G2 {
+	if p.infinity {
+		return q
+	}
+	if q.infinity {
+		return p
+	}
+	if p.x.equal(q.x) {
+		if p.y.equal(q.y) {
+			return p.double()
+		}
+		return g2Infinity()
+	}
+	lambda := q.y.sub(p.y).mul(q.x.sub(p.x).inverse())
+	return p.withSlope(q, lambda)
+}
+
+func (p *G2) scalarMult(k *big.Int) *G2 {
+	result := g2Infinity()
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		result = result.double()
+		if k.Bit(i) == 1 {
+			result = result.add(p)
+		}
+	}
+	return result
+}
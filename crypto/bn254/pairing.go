@@ -0,0 +1,68 @@
+package bn254
+
+import "math/big"
+
+// The pairing is the reduced Tate pairing e(P, Q) = f_{r,P}(Q)^((p^12-1)/r),
+// with Q mapped from the twist into E(Fp12) by (x, y) -> (x*v, y*v*w).
+// Q's x coordinate lies in Fp6, so vertical line denominators are removed
+// by the final exponentiation and can be skipped in the Miller loop.
+
+// finalExponent is (p^6 + 1) / r, the hard part after the p^6 - 1 step
+var finalExponent = func() *big.Int {
+	e := new(big.Int).Exp(P, big.NewInt(6), nil)
+	e.Add(e, big.NewInt(1))
+	return e.Div(e, Order)
+}()
+
+// lineAt evaluates at Q the line with slope λ through T:
+// yQ - yT - λ(xQ - xT) = (λxT - yT) - λ xq v + yq v w
+func lineAt(lambda *big.Int, t *G1, q *G2) *fp12 {
+	constant := fpSub(fpMul(lambda, t.x), t.y)
+	negLambda := fpMod(new(big.Int).Neg(lambda))
+	return &fp12{
+		c0: &fp6{&fp2{constant, new(big.Int)}, q.x.mulScalar(negLambda), fp2Zero()},
+		c1: &fp6{fp2Zero(), q.y, fp2Zero()},
+	}
+}
+
+// millerLoop computes f_{r,P}(Q) by double-and-add over the bits of r
+func millerLoop(p *G1, q *G2) *fp12 {
+	f := fp12One()
+	t := p
+	for i := Order.BitLen() - 2; i >= 0; i-- {
+		lambda := fpMul(fpMul(big.NewInt(3), fpMul(t.x, t.x)), fpInv(fpMul(big.NewInt(2), t.y)))
+		f = f.square().mul(lineAt(lambda, t, q))
+		t = t.withSlope(t, lambda)
+
+		if Order.Bit(i) == 1 {
+			if t.x.Cmp(p.x) == 0 {
+				// T = -P on the final step: the line is vertical
+				t = g1Infinity()
+				continue
+			}
+			lambda = fpMul(fpSub(p.y, t.y), fpInv(fpSub(p.x, t.x)))
+			f = f.mul(lineAt(lambda, t, q))
+			t = t.withSlope(p, lambda)
+		}
+	}
+	return f
+}
+
+func finalExponentiation(f *fp12) *fp12 {
+	// f^(p^6 - 1) = conj(f) / f, then raise to (p^6 + 1) / r
+	f = f.conjugate().mul(f.inverse())
+	return f.exp(finalExponent)
+}
+
+// PairingCheck returns true if the product of e(g1[i], g2[i]) is one.
+// Pairs with a point at infinity contribute one and are skipped.
+func PairingCheck(g1 []*G1, g2 []*G2) bool {
+	acc := fp12One()
+	for i := range g1 {
+		if g1[i].infinity || g2[i].infinity {
+			continue
+		}
+		acc = acc.mul(millerLoop(g1[i], g2[i]))
+	}
+	return finalExponentiation(acc).isOne()
+}
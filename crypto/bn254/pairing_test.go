@@ -0,0 +1,67 @@
+package bn254
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// g2Generator is the standard G2 generator in the precompile encoding
+const g2Generator = "198e9393920d483a7260bfb731fb5d25f1aa493335a9e71297e485b7aef312c21800deef121f1e76426a00665e5c4479674322d4f75edadd46debd5cd992f6ed090689d0585ff075ec9e99ad690c3395bc4b313370b38ef355acdadcd122975b12c85ea5db8c6deb4aab71808dcb408fe3d1e7690c43d37b4ce6cc0166fa7daa"
+
+func TestUnmarshalG2Errors(t *testing.T) {
+	if _, err := UnmarshalG2(mustHex(t, g2Generator)); err != nil {
+		t.Fatalf("generator: %v", err)
+	}
+	notOnCurve := mustHex(t, g2Generator)
+	notOnCurve[127] ^= 1
+	tooLarge := mustHex(t, g2Generator)
+	P.FillBytes(tooLarge[:32])
+
+	tests := []struct {
+		name  string
+		input []byte
+		err   error
+	}{
+		{"short", tooLarge[1:], ErrInvalidLength},
+		{"notOnCurve", notOnCurve, ErrNotOnCurve},
+		{"coordinateModulus", tooLarge, ErrCoordinateTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := UnmarshalG2(tt.input); !errors.Is(err, tt.err) {
+				t.Errorf("err %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestPairingCheck(t *testing.T) {
+	g1 := &G1{big.NewInt(1), big.NewInt(2), false}
+	negG1 := &G1{big.NewInt(1), new(big.Int).Sub(P, big.NewInt(2)), false}
+	g2, err := UnmarshalG2(mustHex(t, g2Generator))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b, ab := big.NewInt(5), big.NewInt(7), big.NewInt(35)
+	tests := []struct {
+		name string
+		g1   []*G1
+		g2   []*G2
+		want bool
+	}{
+		{"empty", nil, nil, true},
+		{"single", []*G1{g1}, []*G2{g2}, false},
+		{"inverse", []*G1{g1, negG1}, []*G2{g2, g2}, true},
+		{"bilinear", []*G1{g1.ScalarMult(a), negG1}, []*G2{g2.scalarMult(b), g2.scalarMult(ab)}, true},
+		{"notBilinear", []*G1{g1.ScalarMult(a), negG1}, []*G2{g2.scalarMult(b), g2.scalarMult(a)}, false},
+		{"infinity", []*G1{g1Infinity()}, []*G2{g2}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PairingCheck(tt.g1, tt.g2); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package crypto
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// Keccak-256 as used by Ethereum (original Keccak padding, not FIPS-202 SHA3)
+
+const keccakRate = 136 // (1600 - 2*256) / 8 bytes
+
+var keccakRoundConstants = [24]uint64{
+	0x0000000000000001, 0x0000000000008082, 0x800000000000808a, 0x8000000080008000,
+	0x000000000000808b, 0x0000000080000001, 0x8000000080008081, 0x8000000000008009,
+	0x000000000000008a, 0x0000000000000088, 0x0000000080008009, 0x000000008000000a,
+	0x000000008000808b, 0x800000000000008b, 0x8000000000008089, 0x8000000000008003,
+	0x8000000000008002, 0x8000000000000080, 0x000000000000800a, 0x800000008000000a,
+	0x8000000080008081, 0x8000000000008080, 0x0000000080000001, 0x8000000080008008,
+}
+
+// Rotation offsets indexed by lane position x + 5*y
+var keccakRotations = [25]int{
+	0, 1, 62, 28, 27,
+	36, 44, 6, 55, 20,
+	3, 10, 43, 25, 39,
+	41, 45, 15, 21, 8,
+	18, 2, 61, 56, 14,
+}
+
+// keccakF1600 applies the 24-round Keccak permutation to the state
+func keccakF1600(a *[25]uint64) {
+	var c [5]uint64
+	var b [25]uint64
+	for round := 0; round < 24; round++ {
+		// θ step
+		for x := 0; x < 5; x++ {
+			c[x] = a[x] ^ a[x+5] ^ a[x+10] ^ a[x+15] ^ a[x+20]
+		}
+		for x := 0; x < 5; x++ {
+			d := c[(x+4)%5] ^ bits.RotateLeft64(c[(x+1)%5], 1)
+			for y := 0; y < 25; y += 5 {
+				a[x+y] ^= d
+			}
+		}
+
+		// ρ and π steps
+		for x := 0; x < 5; x++ {
+			for y := 0; y < 5; y++ {
+				b[y+5*((2*x+3*y)%5)] = bits.RotateLeft64(a[x+5*y], keccakRotations[x+5*y])
+			}
+		}
+
+		// χ step
+		for y := 0; y < 25; y += 5 {
+			for x := 0; x < 5; x++ {
+				a[x+y] = b[x+y] ^ (^b[(x+1)%5+y] & b[(x+2)%5+y])
+			}
+		}
+
+		// ι step
+		a[0] ^= keccakRoundConstants[round]
+	}
+}
+
+// Keccak256 returns the Keccak-256 digest of the concatenated inputs
+func Keccak256(data ...[]byte) [32]byte {
+	var state [25]uint64
+	var block [keccakRate]byte
+	filled := 0
+
+	absorb := func() {
+		for i := 0; i < keccakRate/8; i++ {
+			state[i] ^= binary.LittleEndian.Uint64(block[i*8:])
+		}
+		keccakF1600(&state)
+		filled = 0
+	}
+
+	for _, chunk := range data {
+		for len(chunk) > 0 {
+			n := copy(block[filled:], chunk)
+			filled += n
+			chunk = chunk[n:]
+			if filled == keccakRate {
+				absorb()
+			}
+		}
+	}
+
+	// Pad with 0x01 ... 0x80 (multi-rate padding with Keccak domain byte)
+	for i := filled; i < keccakRate; i++ {
+		block[i] = 0
+	}
+	block[filled] ^= 0x01
+	block[keccakRate-1] ^= 0x80
+	absorb()
+
+	var digest [32]byte
+	for i := 0; i < 4; i++ {
+		binary.LittleEndian.PutUint64(digest[i*8:], state[i])
+	}
+	return digest
+}
@@ -0,0 +1,31 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestKeccak256(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		want  string
+	}{
+		{"empty", nil, "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+		{"abc", []byte("abc"), "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45"},
+		{"fullBlock", bytes.Repeat([]byte{'b'}, 136), "121b76d0b19f3c2c7632310b92c54cddd59d16a6b5aafe84696426f10e5733bf"},
+		{"twoBlocks", bytes.Repeat([]byte{'a'}, 200), "96ea54061def936c4be90b518992fdc6f12f535068a256229aca54267b4d084d"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash := Keccak256(tt.input)
+			if got := hex.EncodeToString(hash[:]); got != tt.want {
+				t.Errorf("hash %s, want %s", got, tt.want)
+			}
+		})
+	}
+	if Keccak256([]byte("a"), []byte("bc")) != Keccak256([]byte("abc")) {
+		t.Error("hash of split input differs from the joined input")
+	}
+}
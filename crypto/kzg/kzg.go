@@ -0,0 +1,137 @@
+// Package kzg verifies KZG point evaluation proofs for EIP-4844 blobs
+// against the mainnet trusted setup.
+package kzg
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"sync"
+
+	"github.com/morelucks/minievm/crypto/bls12381"
+)
+
+// VersionedHashVersion is the version byte of EIP-4844 versioned hashes
+const VersionedHashVersion = 0x01
+
+// FieldElementsPerBlob is the number of field elements in a blob
+const FieldElementsPerBlob = 4096
+
+var (
+	ErrInvalidFieldElement = errors.New("kzg: field element not below the BLS modulus")
+	ErrInvalidSetup        = errors.New("kzg: malformed trusted setup")
+)
+
+// trustedSetup is the mainnet KZG ceremony output in the c-kzg text format:
+// the G1 and G2 point counts, the G1 points in Lagrange form, then the G2
+// points in monomial form, all compressed and hex encoded.
+//
+//go:embed trusted_setup.txt
+var trustedSetup []byte
+
+var (
+	setupOnce sync.Once
+	setupTau  *bls12381.G2 // [τ]G2
+	setupErr  error
+)
+
+// loadSetup decodes [τ]G2, the only setup point needed for verification
+func loadSetup() (*bls12381.G2, error) {
+	setupOnce.Do(func() {
+		setupTau, setupErr = parseSetup(trustedSetup)
+	})
+	return setupTau, setupErr
+}
+
+func parseSetup(data []byte) (*bls12381.G2, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	var counts [2]int
+	for i := range counts {
+		if !scanner.Scan() {
+			return nil, ErrInvalidSetup
+		}
+		n, err := strconv.Atoi(scanner.Text())
+		if err != nil {
+			return nil, ErrInvalidSetup
+		}
+		counts[i] = n
+	}
+	if counts[0] != FieldElementsPerBlob || counts[1] < 2 {
+		return nil, ErrInvalidSetup
+	}
+
+	// Skip the G1 points and the first G2 point (the generator)
+	for i := 0; i < counts[0]+2; i++ {
+		if !scanner.Scan() {
+			return nil, ErrInvalidSetup
+		}
+	}
+	raw, err := hex.DecodeString(scanner.Text())
+	if err != nil {
+		return nil, ErrInvalidSetup
+	}
+	tau, err := bls12381.DecompressG2(raw)
+	if err != nil {
+		return nil, fmt.Errorf("kzg: trusted setup: %w", err)
+	}
+	return tau, nil
+}
+
+// VersionedHash returns the EIP-4844 versioned hash of a commitment:
+// its SHA-256 hash with the first byte replaced by the version
+func VersionedHash(commitment []byte) [32]byte {
+	hash := sha256.Sum256(commitment)
+	hash[0] = VersionedHashVersion
+	return hash
+}
+
+// VerifyProof checks that the polynomial committed to by commitment
+// evaluates to y at z, using the 48-byte compressed commitment and proof
+// and 32-byte big-endian field elements z and y:
+// e(C - [y]G1, -G2) * e(proof, [τ]G2 - [z]G2) == 1
+func VerifyProof(commitment, z, y, proof []byte) (bool, error) {
+	zValue, err := fieldElement(z)
+	if err != nil {
+		return false, err
+	}
+	yValue, err := fieldElement(y)
+	if err != nil {
+		return false, err
+	}
+	c, err := bls12381.DecompressG1(commitment)
+	if err != nil {
+		return false, err
+	}
+	pi, err := bls12381.DecompressG1(proof)
+	if err != nil {
+		return false, err
+	}
+	tau, err := loadSetup()
+	if err != nil {
+		return false, err
+	}
+
+	g1 := bls12381.G1Generator()
+	g2 := bls12381.G2Generator()
+	left := c.Add(g1.ScalarMult(yValue).Neg())
+	right := tau.Add(g2.ScalarMult(zValue).Neg())
+	return bls12381.PairingCheck(
+		[]*bls12381.G1{left, pi},
+		[]*bls12381.G2{g2.Neg(), right},
+	), nil
+}
+
+// fieldElement decodes a 32-byte big-endian scalar, which must be canonical
+func fieldElement(data []byte) (*big.Int, error) {
+	value := new(big.Int).SetBytes(data)
+	if len(data) != 32 || value.Cmp(bls12381.Order) >= 0 {
+		return nil, ErrInvalidFieldElement
+	}
+	return value, nil
+}
@@ -0,0 +1,70 @@
+package kzg
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/morelucks/minievm/crypto/bls12381"
+)
+
+// Point evaluation vector from geth's precompile tests
+const (
+	testHash       = "01e798154708fe7789429634053cbf9f99b619f9f084048927333fce637f549b"
+	testZ          = "564c0a11a0f704f4fc3e8acfe0f8245f0ad1347b378fbf96e206da11a5d36306"
+	testY          = "24d25032e67a7e6a4910df5834b8fe70e6bcfeeac0352434196bdf4b2485d5a1"
+	testCommitment = "8f59a8d2a1a625a17f3fea0fe5eb8c896db3764f3185481bc22f91b4aaffcca25f26936857bc3a7c2539ea8ec3a952b7"
+	testProof      = "873033e038326e87ed3e1276fd140253fa08e9fc25fb2d9a98527fc22a2c9612fbeafdad446cbc7bcdbdcd780af2c16a"
+
+	infinity = "c0" + "0000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000"
+	zero     = "0000000000000000000000000000000000000000000000000000000000000000"
+	one      = "0000000000000000000000000000000000000000000000000000000000000001"
+	modulus  = "73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000001"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestVersionedHash(t *testing.T) {
+	hash := VersionedHash(mustHex(t, testCommitment))
+	if got := hex.EncodeToString(hash[:]); got != testHash {
+		t.Errorf("hash %s, want %s", got, testHash)
+	}
+}
+
+func TestVerifyProof(t *testing.T) {
+	tests := []struct {
+		name                    string
+		commitment, z, y, proof string
+		want                    bool
+		err                     error
+	}{
+		{"valid", testCommitment, testZ, testY, testProof, true, nil},
+		{"wrongY", testCommitment, testZ, one, testProof, false, nil},
+		{"wrongProof", testCommitment, testZ, testY, testCommitment, false, nil},
+		// The zero polynomial: its commitment and every proof are infinity
+		{"zeroPolynomial", infinity, testZ, zero, infinity, true, nil},
+		{"zeroPolynomialWrongY", infinity, testZ, one, infinity, false, nil},
+		{"zModulus", testCommitment, modulus, testY, testProof, false, ErrInvalidFieldElement},
+		{"yModulus", testCommitment, testZ, modulus, testProof, false, ErrInvalidFieldElement},
+		{"uncompressed", "0f" + testCommitment[2:], testZ, testY, testProof, false, bls12381.ErrInvalidCompression},
+		{"shortProof", testCommitment, testZ, testY, testProof[2:], false, bls12381.ErrInvalidLength},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := VerifyProof(mustHex(t, tt.commitment), mustHex(t, tt.z), mustHex(t, tt.y), mustHex(t, tt.proof))
+			switch {
+			case !errors.Is(err, tt.err):
+				t.Fatalf("err %v, want %v", err, tt.err)
+			case ok != tt.want:
+				t.Errorf("got %v, want %v", ok, tt.want)
+			}
+		})
+	}
+}
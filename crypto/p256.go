@@ -0,0 +1,19 @@
+package crypto
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"math/big"
+)
+
+// VerifyP256 checks a secp256r1 (NIST P-256) ECDSA signature (r, s) over hash
+// against the public key (x, y). The key must be a point on the curve; unlike
+// secp256k1 signatures, high s values are accepted.
+func VerifyP256(hash []byte, r, s, x, y *big.Int) bool {
+	curve := elliptic.P256()
+	if !curve.IsOnCurve(x, y) {
+		return false
+	}
+	pub := &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+	return ecdsa.Verify(pub, hash, r, s)
+}
@@ -0,0 +1,317 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+// secp256k1 curve y^2 = x^3 + 7 over F_p (SEC 2, section 2.4.1)
+var (
+	secpP, _  = new(big.Int).SetString("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f", 16)
+	secpN, _  = new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+	secpGx, _ = new(big.Int).SetString("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798", 16)
+	secpGy, _ = new(big.Int).SetString("483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8", 16)
+	secpB     = big.NewInt(7)
+
+	// Exponent (p+1)/4 for square roots, valid because p = 3 mod 4
+	secpSqrtExp = new(big.Int).Rsh(new(big.Int).Add(secpP, big.NewInt(1)), 2)
+	secpHalfN   = new(big.Int).Rsh(secpN, 1)
+)
+
+var (
+	ErrInvalidSignature  = errors.New("invalid signature values")
+	ErrInvalidRecoveryID = errors.New("invalid recovery id")
+	ErrNoCurvePoint      = errors.New("signature r is not an x coordinate on the curve")
+	ErrPointAtInfinity   = errors.New("recovered point at infinity")
+	ErrInvalidPrivateKey = errors.New("invalid private key")
+	ErrInvalidHashLength = errors.New("hash must be 32 bytes")
+)
+
+// secpPoint is a secp256k1 point in Jacobian coordinates (X/Z^2, Y/Z^3).
+// Z == 0 encodes the point at infinity.
+type secpPoint struct {
+	x, y, z *big.Int
+}
+
+func newSecpAffine(x, y *big.Int) *secpPoint {
+	return &secpPoint{new(big.Int).Set(x), new(big.Int).Set(y), big.NewInt(1)}
+}
+
+func (p *secpPoint) isInfinity() bool {
+	return p.z.Sign() == 0
+}
+
+func secpMod(v *big.Int) *big.Int {
+	return v.Mod(v, secpP)
+}
+
+func (p *secpPoint) double() *secpPoint {
+	if p.isInfinity() || p.y.Sign() == 0 {
+		return &secpPoint{new(big.Int), new(big.Int), new(big.Int)}
+	}
+	a := secpMod(new(big.Int).Mul(p.x, p.x))
+	b := secpMod(new(big.Int).Mul(p.y, p.y))
+	c := secpMod(new(big.Int).Mul(b, b))
+
+	// d = 2 * ((x + b)^2 - a - c)
+	d := new(big.Int).Add(p.x, b)
+	d.Mul(d, d)
+	d.Sub(d, a)
+	d.Sub(d, c)
+	d.Lsh(d, 1)
+	secpMod(d)
+
+	e := new(big.Int).Mul(a, big.NewInt(3))
+	f := secpMod(new(big.Int).Mul(e, e))
+
+	x3 := new(big.Int).Sub(f, new(big.Int).Lsh(d, 1))
+	secpMod(x3)
+
+	y3 := new(big.Int).Sub(d, x3)
+	y3.Mul(y3, e)
+	y3.Sub(y3, new(big.Int).Lsh(c, 3))
+	secpMod(y3)
+
+	z3 := new(big.Int).Mul(p.y, p.z)
+	z3.Lsh(z3, 1)
+	secpMod(z3)
+
+	return &secpPoint{x3, y3, z3}
+}
+
+func (p *secpPoint) add(q *secpPoint) *secpPoint {
+	if p.isInfinity() {
+		return q
+	}
+	if q.isInfinity() {
+		return p
+	}
+	z1z1 := secpMod(new(big.Int).Mul(p.z, p.z))
+	z2z2 := secpMod(new(big.Int).Mul(q.z, q.z))
+	u1 := secpMod(new(big.Int).Mul(p.x, z2z2))
+	u2 := secpMod(new(big.Int).Mul(q.x, z1z1))
+	s1 := secpMod(new(big.Int).Mul(p.y, new(big.Int).Mul(q.z, z2z2)))
+	s2 := secpMod(new(big.Int).Mul(q.y, new(big.Int).Mul(p.z, z1z1)))
+
+	if u1.Cmp(u2) == 0 {
+		if s1.Cmp(s2) != 0 {
+			return &secpPoint{new(big.Int), new(big.Int), new(big.Int)}
+		}
+		return p.double()
+	}
+
+	h := secpMod(new(big.Int).Sub(u2, u1))
+	r := secpMod(new(big.Int).Sub(s2, s1))
+	hh := secpMod(new(big.Int).Mul(h, h))
+	hhh := secpMod(new(big.Int).Mul(hh, h))
+	v := secpMod(new(big.Int).Mul(u1, hh))
+
+	x3 := new(big.Int).Mul(r, r)
+	x3.Sub(x3, hhh)
+	x3.Sub(x3, new(big.Int).Lsh(v, 1))
+	secpMod(x3)
+
+	y3 := new(big.Int).Sub(v, x3)
+	y3.Mul(y3, r)
+	y3.Sub(y3, new(big.Int).Mul(s1, hhh))
+	secpMod(y3)
+
+	z3 := new(big.Int).Mul(p.z, q.z)
+	z3.Mul(z3, h)
+	secpMod(z3)
+
+	return &secpPoint{x3, y3, z3}
+}
+
+// mul computes k*p with double-and-add
+func (p *secpPoint) mul(k *big.Int) *secpPoint {
+	result := &secpPoint{new(big.Int), new(big.Int), new(big.Int)}
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		result = result.double()
+		if k.Bit(i) == 1 {
+			result = result.add(p)
+		}
+	}
+	return result
+}
+
+func (p *secpPoint) affine() (*big.Int, *big.Int) {
+	zInv := new(big.Int).ModInverse(p.z, secpP)
+	zInv2 := secpMod(new(big.Int).Mul(zInv, zInv))
+	x := secpMod(new(big.Int).Mul(p.x, zInv2))
+	y := secpMod(new(big.Int).Mul(p.y, new(big.Int).Mul(zInv2, zInv)))
+	return x, y
+}
+
+// ValidateSignatureValues checks that r and s are in [1, n-1].
+// When lowS is set, s must also be in the lower half of the order (EIP-2).
+func ValidateSignatureValues(r, s *big.Int, lowS bool) bool {
+	if r.Sign() <= 0 || s.Sign() <= 0 {
+		return false
+	}
+	if r.Cmp(secpN) >= 0 || s.Cmp(secpN) >= 0 {
+		return false
+	}
+	if lowS && s.Cmp(secpHalfN) > 0 {
+		return false
+	}
+	return true
+}
+
+// RecoverPubkey recovers the public key that produced signature (r, s) over
+// hash. recoveryID selects the parity of the ephemeral point's y coordinate.
+// Returns the 64-byte uncompressed key X || Y.
+func RecoverPubkey(hash []byte, r, s *big.Int, recoveryID byte) ([]byte, error) {
+	if recoveryID > 1 {
+		return nil, ErrInvalidRecoveryID
+	}
+	if !ValidateSignatureValues(r, s, false) {
+		return nil, ErrInvalidSignature
+	}
+
+	// Lift r to the curve point R = (r, y) with the requested parity
+	x := new(big.Int).Set(r)
+	if x.Cmp(secpP) >= 0 {
+		return nil, ErrNoCurvePoint
+	}
+	rhs := new(big.Int).Exp(x, big.NewInt(3), secpP)
+	rhs.Add(rhs, secpB)
+	secpMod(rhs)
+	y := new(big.Int).Exp(rhs, secpSqrtExp, secpP)
+	if secpMod(new(big.Int).Mul(y, y)).Cmp(rhs) != 0 {
+		return nil, ErrNoCurvePoint
+	}
+	if y.Bit(0) != uint(recoveryID) {
+		y.Sub(secpP, y)
+	}
+
+	// Q = r^-1 * (s*R - e*G)
+	e := new(big.Int).SetBytes(hash)
+	e.Mod(e, secpN)
+	rInv := new(big.Int).ModInverse(r, secpN)
+	u1 := new(big.Int).Mul(e, rInv)
+	u1.Neg(u1)
+	u1.Mod(u1, secpN)
+	u2 := new(big.Int).Mul(s, rInv)
+	u2.Mod(u2, secpN)
+
+	g := newSecpAffine(secpGx, secpGy)
+	point := newSecpAffine(x, y)
+	q := g.mul(u1).add(point.mul(u2))
+	if q.isInfinity() {
+		return nil, ErrPointAtInfinity
+	}
+
+	qx, qy := q.affine()
+	pub := make([]byte, 64)
+	qx.FillBytes(pub[:32])
+	qy.FillBytes(pub[32:])
+	return pub, nil
+}
+
+// PubkeyFromPrivate returns the 64-byte public key X || Y of a 32-byte
+// private key, the point key*G
+func PubkeyFromPrivate(key []byte) ([]byte, error) {
+	k := new(big.Int).SetBytes(key)
+	if len(key) != 32 || k.Sign() == 0 || k.Cmp(secpN) >= 0 {
+		return nil, ErrInvalidPrivateKey
+	}
+	x, y := newSecpAffine(secpGx, secpGy).mul(k).affine()
+	pub := make([]byte, 64)
+	x.FillBytes(pub[:32])
+	y.FillBytes(pub[32:])
+	return pub, nil
+}
+
+// PubkeyToAddress derives the Ethereum address from a 64-byte public key
+func PubkeyToAddress(pub []byte) [20]byte {
+	var addr [20]byte
+	hash := Keccak256(pub)
+	copy(addr[:], hash[12:])
+	return addr
+}
+
+// Sign signs a 32-byte hash with a private key, drawing the nonce
+// deterministically (RFC 6979) like libsecp256k1. The signature is returned
+// as R || S || V with a low s (EIP-2) and the recovery id V in {0, 1}.
+func Sign(hash, key []byte) ([]byte, error) {
+	d := new(big.Int).SetBytes(key)
+	if len(key) != 32 || d.Sign() == 0 || d.Cmp(secpN) >= 0 {
+		return nil, ErrInvalidPrivateKey
+	}
+	if len(hash) != 32 {
+		return nil, ErrInvalidHashLength
+	}
+	e := new(big.Int).SetBytes(hash)
+	e.Mod(e, secpN)
+
+	nonces := newRFC6979(key, e.FillBytes(make([]byte, 32)))
+	for {
+		k := nonces.next()
+		x, y := newSecpAffine(secpGx, secpGy).mul(k).affine()
+		r := new(big.Int).Mod(x, secpN)
+		if r.Sign() == 0 {
+			continue
+		}
+		// s = k^-1 * (e + r*d)
+		s := new(big.Int).Mul(r, d)
+		s.Add(s, e)
+		s.Mul(s, new(big.Int).ModInverse(k, secpN))
+		s.Mod(s, secpN)
+		if s.Sign() == 0 {
+			continue
+		}
+		recoveryID := byte(y.Bit(0))
+		if s.Cmp(secpHalfN) > 0 {
+			s.Sub(secpN, s)
+			recoveryID ^= 1
+		}
+		sig := make([]byte, 65)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:64])
+		sig[64] = recoveryID
+		return sig, nil
+	}
+}
+
+// rfc6979 is the HMAC-SHA256 DRBG that derives signing nonces from the key
+// and the message (RFC 6979, section 3.2)
+type rfc6979 struct {
+	k, v    []byte
+	started bool
+}
+
+func newRFC6979(key, msg []byte) *rfc6979 {
+	g := &rfc6979{k: make([]byte, 32), v: bytes.Repeat([]byte{0x01}, 32)}
+	for _, sep := range []byte{0x00, 0x01} {
+		g.k = hmacSHA256(g.k, g.v, []byte{sep}, key, msg)
+		g.v = hmacSHA256(g.k, g.v)
+	}
+	return g
+}
+
+// next returns the next candidate nonce in [1, n-1]
+func (g *rfc6979) next() *big.Int {
+	for {
+		if g.started {
+			g.k = hmacSHA256(g.k, g.v, []byte{0x00})
+			g.v = hmacSHA256(g.k, g.v)
+		}
+		g.started = true
+		g.v = hmacSHA256(g.k, g.v)
+		if k := new(big.Int).SetBytes(g.v); k.Sign() > 0 && k.Cmp(secpN) < 0 {
+			return k
+		}
+	}
+}
+
+func hmacSHA256(key []byte, data ...[]byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	for _, d := range data {
+		mac.Write(d)
+	}
+	return mac.Sum(nil)
+}
@@ -0,0 +1,104 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// Key and signature checked against geth
+var (
+	testKey, _  = hex.DecodeString("289c2857d4598e37fb9647507e47a309d6133539bf21a8b9cb6df88fd5232032")
+	testPub, _  = hex.DecodeString("7db227d7094ce215c3a0f57e1bcc732551fe351f94249471934567e0f5dc1bf795962b8cccb87a2eb56b29fbe37d614e2f4c3c45b789ae4f1f51f4cb21972ffd")
+	testAddr, _ = hex.DecodeString("970e8128ab834e8eac17ab8e3812f010678cf791")
+	testSig, _  = hex.DecodeString("d155e94305af7e07dd8c32873e5c03cb95c9e05960ef85be9c07f671da58c73718c19adc397a211aa9e87e519e2038c5a3b658618db335f74f800b8e0cfeef4401")
+)
+
+func TestSign(t *testing.T) {
+	hash := Keccak256([]byte("foo"))
+	sig, err := Sign(hash[:], testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(sig, testSig) {
+		t.Errorf("signature %x, want %x", sig, testSig)
+	}
+	pub, err := RecoverPubkey(hash[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:64]), sig[64])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pub, testPub) {
+		t.Errorf("recovered key %x, want %x", pub, testPub)
+	}
+	if addr := PubkeyToAddress(pub); !bytes.Equal(addr[:], testAddr) {
+		t.Errorf("address %x, want %x", addr, testAddr)
+	}
+}
+
+func TestPubkeyFromPrivate(t *testing.T) {
+	pub, err := PubkeyFromPrivate(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pub, testPub) {
+		t.Errorf("key %x, want %x", pub, testPub)
+	}
+	for _, key := range [][]byte{make([]byte, 32), secpN.FillBytes(make([]byte, 32)), testKey[1:]} {
+		if _, err := PubkeyFromPrivate(key); !errors.Is(err, ErrInvalidPrivateKey) {
+			t.Errorf("key %x: err %v, want %v", key, err, ErrInvalidPrivateKey)
+		}
+	}
+}
+
+func TestRecoverPubkeyErrors(t *testing.T) {
+	hash := Keccak256([]byte("foo"))
+	r, s := new(big.Int).SetBytes(testSig[:32]), new(big.Int).SetBytes(testSig[32:64])
+	tests := []struct {
+		name string
+		r, s *big.Int
+		id   byte
+		err  error
+	}{
+		{"recoveryID", r, s, 2, ErrInvalidRecoveryID},
+		{"rZero", new(big.Int), s, 0, ErrInvalidSignature},
+		{"sZero", r, new(big.Int), 0, ErrInvalidSignature},
+		{"rOrder", secpN, s, 0, ErrInvalidSignature},
+		{"sOrder", r, secpN, 0, ErrInvalidSignature},
+		{"rNotOnCurve", big.NewInt(5), s, 0, ErrNoCurvePoint}, // 5^3 + 7 is not a square mod p
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := RecoverPubkey(hash[:], tt.r, tt.s, tt.id); !errors.Is(err, tt.err) {
+				t.Errorf("err %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestValidateSignatureValues(t *testing.T) {
+	one := big.NewInt(1)
+	nMinus1 := new(big.Int).Sub(secpN, one)
+	tests := []struct {
+		name string
+		r, s *big.Int
+		lowS bool
+		want bool
+	}{
+		{"minimal", one, one, true, true},
+		{"maximal", nMinus1, nMinus1, false, true},
+		{"highS", one, nMinus1, true, false},
+		{"halfOrder", one, secpHalfN, true, true},
+		{"aboveHalfOrder", one, new(big.Int).Add(secpHalfN, one), true, false},
+		{"rZero", new(big.Int), one, false, false},
+		{"sOrder", one, secpN, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidateSignatureValues(tt.r, tt.s, tt.lowS); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,607 @@
+package rlp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+	"strings"
+)
+
+// EOL is returned when the end of the current list has been reached
+var EOL = errors.New("rlp: end of list")
+
+var (
+	errNotInList     = errors.New("rlp: call of ListEnd outside of any list")
+	errNotAtEOL      = errors.New("rlp: call of ListEnd not positioned at EOL")
+	errTooFewFields  = errors.New("rlp: too few elements")
+	errTooManyFields = errors.New("rlp: input list has too many elements")
+)
+
+// Decoder is implemented by types that decode themselves from a stream
+type Decoder interface {
+	DecodeRLP(s *Stream) error
+}
+
+// ByteReader is the input a Stream reads from; other readers are buffered
+type ByteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// Decode reads one value from r into the value pointed to by val.
+// See DecodeBytes for the rules.
+func Decode(r io.Reader, val any) error {
+	return NewStream(r, 0).Decode(val)
+}
+
+// DecodeBytes decodes b into the value pointed to by val, the reverse of
+// EncodeToBytes. Only canonical encodings are accepted and b must hold
+// exactly one value. Pointers are allocated as needed; a pointer field
+// tagged rlp:"nil" is set to nil for an empty value. Into an interface{},
+// strings decode as []byte and lists as []interface{}.
+func DecodeBytes(b []byte, val any) error {
+	r := bytes.NewReader(b)
+	if err := NewStream(r, uint64(len(b))).Decode(val); err != nil {
+		return err
+	}
+	if r.Len() > 0 {
+		return ErrMoreThanOneValue
+	}
+	return nil
+}
+
+// Stream reads RLP items one at a time from an input. Lists are entered
+// with List and left with ListEnd; reading past the last item of a list
+// returns EOL. Items that exceed their enclosing list or the input limit are
+// rejected before they are read.
+type Stream struct {
+	r         ByteReader
+	remaining uint64   // Bytes left before the input limit
+	limited   bool     // Whether the input limit applies
+	stack     []uint64 // Bytes left in each open list
+
+	kind      Kind // Cached header of the next item
+	size      uint64
+	byteval   byte // Value of a Byte item, which is its own header
+	kinderr   error
+	kindValid bool
+}
+
+// NewStream creates a stream reading from r. inputLimit caps the number of
+// bytes read; if it is 0 the length of a bytes.Reader or strings.Reader is
+// used, otherwise the input is unlimited.
+func NewStream(r io.Reader, inputLimit uint64) *Stream {
+	s := new(Stream)
+	s.Reset(r, inputLimit)
+	return s
+}
+
+// Reset discards the stream state and starts reading from r
+func (s *Stream) Reset(r io.Reader, inputLimit uint64) {
+	s.limited, s.remaining = false, 0
+	switch br := r.(type) {
+	case *bytes.Reader:
+		s.limited, s.remaining = true, uint64(br.Len())
+	case *strings.Reader:
+		s.limited, s.remaining = true, uint64(br.Len())
+	}
+	if inputLimit > 0 {
+		s.limited, s.remaining = true, inputLimit
+	}
+	if br, ok := r.(ByteReader); ok {
+		s.r = br
+	} else {
+		s.r = bufio.NewReader(r)
+	}
+	s.stack = s.stack[:0]
+	s.kindValid, s.kinderr, s.byteval = false, nil, 0
+}
+
+// Kind returns the kind and content size of the next item without
+// consuming it
+func (s *Stream) Kind() (Kind, uint64, error) {
+	if s.kindValid {
+		return s.kind, s.size, s.kinderr
+	}
+	inList, limit := s.listLimit()
+	if inList && limit == 0 {
+		return 0, 0, EOL
+	}
+	s.kind, s.size, s.kinderr = s.readKind()
+	if s.kinderr == nil {
+		// The header has been read, so the limits now hold only the content
+		if inList, limit := s.listLimit(); inList && s.size > limit {
+			s.kinderr = ErrElemTooLarge
+		} else if s.limited && s.size > s.remaining {
+			s.kinderr = ErrValueTooLarge
+		}
+	}
+	s.kindValid = true
+	return s.kind, s.size, s.kinderr
+}
+
+func (s *Stream) readKind() (Kind, uint64, error) {
+	b, err := s.readByte()
+	if err != nil {
+		if len(s.stack) > 0 && err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, 0, err
+	}
+	s.byteval = 0
+	switch {
+	case b < 0x80:
+		s.byteval = b
+		return Byte, 0, nil
+	case b < 0xb8:
+		return String, uint64(b - 0x80), nil
+	case b < 0xc0:
+		size, err := s.readSize(b - 0xb7)
+		return String, size, err
+	case b < 0xf8:
+		return List, uint64(b - 0xc0), nil
+	default:
+		size, err := s.readSize(b - 0xf7)
+		return List, size, err
+	}
+}
+
+// readSize reads the size of a long-form header, which must be canonical
+func (s *Stream) readSize(slen byte) (uint64, error) {
+	var buf [8]byte
+	if err := s.readFull(buf[8-slen:]); err != nil {
+		return 0, err
+	}
+	if buf[8-slen] == 0 {
+		return 0, ErrCanonSize
+	}
+	size := binary.BigEndian.Uint64(buf[:])
+	if size < 56 {
+		return 0, ErrCanonSize
+	}
+	return size, nil
+}
+
+// Bytes reads a string item. A single byte below 0x80 must be encoded as
+// itself, not as a one-byte string.
+func (s *Stream) Bytes() ([]byte, error) {
+	kind, size, err := s.Kind()
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case Byte:
+		s.kindValid = false
+		return []byte{s.byteval}, nil
+	case String:
+		b := make([]byte, size)
+		if err := s.readFull(b); err != nil {
+			return nil, err
+		}
+		if size == 1 && b[0] < 0x80 {
+			return nil, ErrCanonSize
+		}
+		return b, nil
+	default:
+		return nil, ErrExpectedString
+	}
+}
+
+// ReadBytes reads a string item of exactly len(b) bytes into b
+func (s *Stream) ReadBytes(b []byte) error {
+	kind, size, err := s.Kind()
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case Byte:
+		if len(b) != 1 {
+			return fmt.Errorf("rlp: input value has wrong size 1, want %d", len(b))
+		}
+		b[0] = s.byteval
+		s.kindValid = false
+		return nil
+	case String:
+		if uint64(len(b)) != size {
+			return fmt.Errorf("rlp: input value has wrong size %d, want %d", size, len(b))
+		}
+		if err := s.readFull(b); err != nil {
+			return err
+		}
+		if size == 1 && b[0] < 0x80 {
+			return ErrCanonSize
+		}
+		return nil
+	default:
+		return ErrExpectedString
+	}
+}
+
+// Uint64 reads an integer of at most 8 bytes
+func (s *Stream) Uint64() (uint64, error) {
+	return s.uint(64)
+}
+
+// Bool reads an integer that must be 0 or 1
+func (s *Stream) Bool() (bool, error) {
+	x, err := s.uint(8)
+	if err != nil {
+		return false, err
+	}
+	switch x {
+	case 0:
+		return false, nil
+	case 1:
+		return true, nil
+	}
+	return false, fmt.Errorf("rlp: invalid boolean value: %d", x)
+}
+
+func (s *Stream) uint(maxbits int) (uint64, error) {
+	b, err := s.intBytes()
+	if err != nil {
+		return 0, err
+	}
+	if len(b) > maxbits/8 {
+		return 0, ErrUint64Range
+	}
+	var x uint64
+	for _, c := range b {
+		x = x<<8 | uint64(c)
+	}
+	return x, nil
+}
+
+// BigInt reads a non-negative integer of any size
+func (s *Stream) BigInt() (*big.Int, error) {
+	b, err := s.intBytes()
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// intBytes reads the big-endian bytes of an integer, which must not have
+// leading zeros
+func (s *Stream) intBytes() ([]byte, error) {
+	b, err := s.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > 0 && b[0] == 0 {
+		return nil, ErrCanonInt
+	}
+	return b, nil
+}
+
+// Raw reads the next item including its header
+func (s *Stream) Raw() ([]byte, error) {
+	kind, size, err := s.Kind()
+	if err != nil {
+		return nil, err
+	}
+	if kind == Byte {
+		s.kindValid = false
+		return []byte{s.byteval}, nil
+	}
+	offset := byte(0x80)
+	if kind == List {
+		offset = 0xc0
+	}
+	raw := appendHeader(nil, offset, size)
+	start := len(raw)
+	raw = append(raw, make([]byte, size)...)
+	if err := s.readFull(raw[start:]); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// List enters the next item, which must be a list, and returns its content size
+func (s *Stream) List() (uint64, error) {
+	kind, size, err := s.Kind()
+	if err != nil {
+		return 0, err
+	}
+	if kind != List {
+		return 0, ErrExpectedList
+	}
+	// Count the whole list against the enclosing one now, so it is correct
+	// again after the matching ListEnd
+	if inList, limit := s.listLimit(); inList {
+		s.stack[len(s.stack)-1] = limit - size
+	}
+	s.stack = append(s.stack, size)
+	s.kindValid = false
+	return size, nil
+}
+
+// ListEnd leaves the current list, which must have been read completely
+func (s *Stream) ListEnd() error {
+	inList, limit := s.listLimit()
+	if !inList {
+		return errNotInList
+	}
+	if limit > 0 {
+		return errNotAtEOL
+	}
+	s.stack = s.stack[:len(s.stack)-1]
+	s.kindValid = false
+	return nil
+}
+
+// MoreDataInList reports whether the current list has items left
+func (s *Stream) MoreDataInList() bool {
+	inList, limit := s.listLimit()
+	return inList && limit > 0
+}
+
+func (s *Stream) listLimit() (bool, uint64) {
+	if len(s.stack) == 0 {
+		return false, 0
+	}
+	return true, s.stack[len(s.stack)-1]
+}
+
+// Decode reads the next item into the value pointed to by val
+func (s *Stream) Decode(val any) error {
+	rv := reflect.ValueOf(val)
+	if !rv.IsValid() || rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("rlp: Decode requires a non-nil pointer, got %T", val)
+	}
+	if err := s.decodeValue(rv.Elem()); err != nil {
+		if err == EOL {
+			return err
+		}
+		return fmt.Errorf("%w (decoding %v)", err, rv.Type().Elem())
+	}
+	return nil
+}
+
+func (s *Stream) decodeValue(v reflect.Value) error {
+	typ := v.Type()
+	switch {
+	case typ == rawValueType:
+		raw, err := s.Raw()
+		if err == nil {
+			v.SetBytes(raw)
+		}
+		return err
+	case reflect.PointerTo(typ).Implements(decoderType):
+		return v.Addr().Interface().(Decoder).DecodeRLP(s)
+	case typ == bigIntType:
+		x, err := s.BigInt()
+		if err == nil {
+			v.Set(reflect.ValueOf(x).Elem())
+		}
+		return err
+	case typ == bigIntPtrType:
+		x, err := s.BigInt()
+		if err == nil {
+			v.Set(reflect.ValueOf(x))
+		}
+		return err
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		b, err := s.Bool()
+		if err == nil {
+			v.SetBool(b)
+		}
+		return err
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		x, err := s.uint(typ.Bits())
+		if err == nil {
+			v.SetUint(x)
+		}
+		return err
+	case reflect.String:
+		b, err := s.Bytes()
+		if err == nil {
+			v.SetString(string(b))
+		}
+		return err
+	case reflect.Slice:
+		if isByteArray(typ) {
+			b, err := s.Bytes()
+			if err == nil {
+				v.SetBytes(b)
+			}
+			return err
+		}
+		return s.decodeList(v)
+	case reflect.Array:
+		if isByteArray(typ) {
+			b := make([]byte, v.Len())
+			if err := s.ReadBytes(b); err != nil {
+				return err
+			}
+			reflect.Copy(v, reflect.ValueOf(b))
+			return nil
+		}
+		return s.decodeList(v)
+	case reflect.Struct:
+		return s.decodeStruct(v)
+	case reflect.Pointer:
+		return s.decodePointer(v, false)
+	case reflect.Interface:
+		if typ.NumMethod() != 0 {
+			break
+		}
+		x, err := s.decodeAny()
+		if err == nil {
+			v.Set(reflect.ValueOf(x))
+		}
+		return err
+	}
+	return fmt.Errorf("rlp: type %v is not RLP-serializable", typ)
+}
+
+// decodeList fills a slice, or an array which must be matched exactly
+func (s *Stream) decodeList(v reflect.Value) error {
+	if _, err := s.List(); err != nil {
+		return err
+	}
+	isSlice := v.Kind() == reflect.Slice
+	if isSlice {
+		v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+	}
+	i := 0
+	for ; s.MoreDataInList(); i++ {
+		if isSlice {
+			v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem())))
+		} else if i >= v.Len() {
+			return fmt.Errorf("rlp: input list has too many elements for %v", v.Type())
+		}
+		if err := s.decodeValue(v.Index(i)); err != nil {
+			return err
+		}
+	}
+	if !isSlice && i < v.Len() {
+		return fmt.Errorf("rlp: input list has too few elements for %v", v.Type())
+	}
+	return s.ListEnd()
+}
+
+func (s *Stream) decodeStruct(v reflect.Value) error {
+	fields, err := structFields(v.Type())
+	if err != nil {
+		return err
+	}
+	if _, err := s.List(); err != nil {
+		return err
+	}
+	for i, f := range fields {
+		if !s.MoreDataInList() {
+			if !f.optional {
+				return fmt.Errorf("%w for %v, missing %s", errTooFewFields, v.Type(), f.name)
+			}
+			// Absent optional fields are zero
+			for _, rest := range fields[i:] {
+				fv := v.Field(rest.index)
+				fv.Set(reflect.Zero(fv.Type()))
+			}
+			break
+		}
+		fv := v.Field(f.index)
+		if f.nilOK {
+			err = s.decodePointer(fv, true)
+		} else {
+			err = s.decodeValue(fv)
+		}
+		if err != nil {
+			return fmt.Errorf("%w (field %s)", err, f.name)
+		}
+	}
+	if s.MoreDataInList() {
+		return fmt.Errorf("%w for %v", errTooManyFields, v.Type())
+	}
+	return s.ListEnd()
+}
+
+// decodePointer allocates the pointer's target and decodes into it. With
+// nilOK, an empty string or list leaves the pointer nil instead.
+func (s *Stream) decodePointer(v reflect.Value, nilOK bool) error {
+	if nilOK {
+		kind, size, err := s.Kind()
+		if err != nil {
+			return err
+		}
+		if kind != Byte && size == 0 {
+			if kind == List {
+				s.List()
+				s.ListEnd()
+			} else {
+				s.kindValid = false
+			}
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+	}
+	target := v
+	if v.IsNil() {
+		target = reflect.New(v.Type().Elem())
+	}
+	if err := s.decodeValue(target.Elem()); err != nil {
+		return err
+	}
+	v.Set(target)
+	return nil
+}
+
+// decodeAny decodes strings as []byte and lists as []any
+func (s *Stream) decodeAny() (any, error) {
+	kind, _, err := s.Kind()
+	if err != nil {
+		return nil, err
+	}
+	if kind != List {
+		return s.Bytes()
+	}
+	if _, err := s.List(); err != nil {
+		return nil, err
+	}
+	items := []any{}
+	for s.MoreDataInList() {
+		item, err := s.decodeAny()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, s.ListEnd()
+}
+
+// readByte and readFull consume input, counting it against the input limit
+// and the innermost open list
+func (s *Stream) readByte() (byte, error) {
+	if err := s.willRead(1); err != nil {
+		return 0, err
+	}
+	b, err := s.r.ReadByte()
+	if err == io.EOF && len(s.stack) > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return b, err
+}
+
+func (s *Stream) readFull(buf []byte) error {
+	if err := s.willRead(uint64(len(buf))); err != nil {
+		return err
+	}
+	n, err := io.ReadFull(s.r, buf)
+	if err == io.EOF {
+		if n < len(buf) {
+			err = io.ErrUnexpectedEOF
+		} else {
+			err = nil
+		}
+	}
+	return err
+}
+
+func (s *Stream) willRead(n uint64) error {
+	s.kindValid = false
+	if inList, limit := s.listLimit(); inList {
+		if n > limit {
+			return ErrElemTooLarge
+		}
+		s.stack[len(s.stack)-1] = limit - n
+	}
+	if s.limited {
+		if n > s.remaining {
+			if s.remaining == 0 && len(s.stack) == 0 {
+				return io.EOF
+			}
+			return ErrValueTooLarge
+		}
+		s.remaining -= n
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package rlp
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+// errAny marks inputs that must be rejected without a sentinel to match
+var errAny = errors.New("any error")
+
+// Vectors from geth's RLP package; every non-canonical form is rejected
+var decodeTests = []struct {
+	input string
+	ptr   any
+	value any
+	err   error
+}{
+	{"01", new(bool), true, nil},
+	{"80", new(bool), false, nil},
+	{"02", new(bool), nil, errAny},
+
+	{"05", new(uint32), uint32(5), nil},
+	{"80", new(uint32), uint32(0), nil},
+	{"820505", new(uint32), uint32(0x0505), nil},
+	{"8405050505", new(uint32), uint32(0x05050505), nil},
+	{"850505050505", new(uint32), nil, errAny},
+	{"C0", new(uint32), nil, ErrExpectedString},
+	{"00", new(uint32), nil, ErrCanonInt},
+	{"8105", new(uint32), nil, ErrCanonSize},
+	{"820004", new(uint32), nil, ErrCanonInt},
+	{"B8020004", new(uint32), nil, ErrCanonSize},
+	{"89FFFFFFFFFFFFFFFFFF", new(uint64), nil, ErrUint64Range},
+
+	{"C0", new([]uint), []uint{}, nil},
+	{"C80102030405060708", new([]uint), []uint{1, 2, 3, 4, 5, 6, 7, 8}, nil},
+	{"F8020004", new([]uint), nil, ErrCanonSize},
+	{"C50102030405", new([5]uint), [5]uint{1, 2, 3, 4, 5}, nil},
+	{"C102", new([5]uint), nil, errAny},
+	{"C6010203040506", new([5]uint), nil, errAny},
+
+	{"01", new([]byte), []byte{1}, nil},
+	{"80", new([]byte), []byte{}, nil},
+	{"8D6162636465666768696A6B6C6D", new([]byte), []byte("abcdefghijklm"), nil},
+	{"C0", new([]byte), nil, ErrExpectedString},
+	{"8105", new([]byte), nil, ErrCanonSize},
+
+	{"02", new([1]byte), [1]byte{2}, nil},
+	{"8180", new([1]byte), [1]byte{128}, nil},
+	{"850102030405", new([5]byte), [5]byte{1, 2, 3, 4, 5}, nil},
+	{"02", new([5]byte), nil, errAny},
+	{"820000", new([5]byte), nil, errAny},
+	{"86010203040506", new([5]byte), nil, errAny},
+	{"C3010203", new([5]byte), nil, ErrExpectedString},
+	{"817F", new([1]byte), nil, ErrCanonSize},
+
+	{"00", new(string), "\000", nil},
+	{"8D6162636465666768696A6B6C6D", new(string), "abcdefghijklm", nil},
+	{"C0", new(string), nil, ErrExpectedString},
+
+	{"80", new(*big.Int), big.NewInt(0), nil},
+	{"01", new(*big.Int), big.NewInt(1), nil},
+	{"89FFFFFFFFFFFFFFFFFF", new(*big.Int), new(big.Int).SetBytes(unhex("FFFFFFFFFFFFFFFFFF")), nil},
+	{"10", new(big.Int), *big.NewInt(16), nil},
+	{"C0", new(*big.Int), nil, ErrExpectedString},
+	{"00", new(*big.Int), nil, ErrCanonInt},
+	{"820001", new(*big.Int), nil, ErrCanonInt},
+	{"8105", new(*big.Int), nil, ErrCanonSize},
+
+	{"C50583343434", new(simpleStruct), simpleStruct{5, "444"}, nil},
+	{"C601C402C203C0", new(recStruct), recStruct{1, &recStruct{2, &recStruct{3, nil}}}, nil},
+	{"C0", new(simpleStruct), nil, errAny},
+	{"83222222", new(simpleStruct), nil, ErrExpectedList},
+	{"C3010101", new(simpleStruct), nil, errAny},
+	{"C101", new(optionalFields), optionalFields{A: 1}, nil},
+	{"C3010203", new(optionalFields), optionalFields{1, 2, 3}, nil},
+
+	// Sizes that overrun the input or the enclosing list
+	{"8501020304", new([]byte), nil, ErrValueTooLarge},
+	{"C60102C401020304", new([]any), nil, ErrElemTooLarge},
+	{"0101", new(uint), nil, ErrMoreThanOneValue},
+
+	{"C3C10180", new([]any), []any{[]any{[]byte{1}}, []byte{}}, nil},
+}
+
+func TestDecodeBytes(t *testing.T) {
+	for i, tt := range decodeTests {
+		err := DecodeBytes(unhex(tt.input), tt.ptr)
+		switch {
+		case tt.err == errAny:
+			if err == nil {
+				t.Errorf("test %d: decode %s into %T succeeded", i, tt.input, tt.ptr)
+			}
+		case tt.err != nil:
+			if !errors.Is(err, tt.err) {
+				t.Errorf("test %d: decode %s into %T: err %v, want %v", i, tt.input, tt.ptr, err, tt.err)
+			}
+		case err != nil:
+			t.Errorf("test %d: decode %s into %T: %v", i, tt.input, tt.ptr, err)
+		default:
+			if got := reflect.ValueOf(tt.ptr).Elem().Interface(); !reflect.DeepEqual(got, tt.value) {
+				t.Errorf("test %d: decode %s = %#v, want %#v", i, tt.input, got, tt.value)
+			}
+		}
+	}
+}
+
+// TestRoundTrip decodes each encoding test vector back into its type
+func TestRoundTrip(t *testing.T) {
+	for i, tt := range encTests {
+		typ := reflect.TypeOf(tt.val)
+		if typ.Kind() == reflect.Pointer || typ.Kind() == reflect.Interface || typ == reflect.TypeOf([]any{}) {
+			continue
+		}
+		ptr := reflect.New(typ)
+		if err := DecodeBytes(unhex(tt.output), ptr.Interface()); err != nil {
+			t.Errorf("test %d: decode %s into %v: %v", i, tt.output, typ, err)
+			continue
+		}
+		enc, err := EncodeToBytes(ptr.Elem().Interface())
+		if err != nil || !bytes.Equal(enc, unhex(tt.output)) {
+			t.Errorf("test %d: re-encoding gave %X, %v; want %s", i, enc, err, tt.output)
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	// [1, [2, 3], "abc"]
+	s := NewStream(bytes.NewReader(unhex("C801C2020383616263")), 0)
+	if _, err := s.List(); err != nil {
+		t.Fatal(err)
+	}
+	if x, err := s.Uint64(); err != nil || x != 1 {
+		t.Fatalf("first item %d, %v", x, err)
+	}
+	if kind, size, err := s.Kind(); err != nil || kind != List || size != 2 {
+		t.Fatalf("second item kind %v size %d, %v", kind, size, err)
+	}
+	if raw, err := s.Raw(); err != nil || !bytes.Equal(raw, unhex("C20203")) {
+		t.Fatalf("raw second item %X, %v", raw, err)
+	}
+	if b, err := s.Bytes(); err != nil || string(b) != "abc" {
+		t.Fatalf("third item %q, %v", b, err)
+	}
+	if _, err := s.Uint64(); err != EOL {
+		t.Fatalf("read past the list: %v, want EOL", err)
+	}
+	if err := s.ListEnd(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	content, rest, err := SplitList(unhex("C3010203FF"))
+	if err != nil || !bytes.Equal(content, unhex("010203")) || !bytes.Equal(rest, unhex("FF")) {
+		t.Errorf("SplitList = %X, %X, %v", content, rest, err)
+	}
+	if n, err := CountValues(content); err != nil || n != 3 {
+		t.Errorf("CountValues = %d, %v", n, err)
+	}
+	if _, _, err := SplitString(unhex("C0")); !errors.Is(err, ErrExpectedString) {
+		t.Errorf("SplitString of a list: %v", err)
+	}
+	if _, _, _, err := Split(unhex("8100")); !errors.Is(err, ErrCanonSize) {
+		t.Errorf("Split of a non-canonical byte: %v", err)
+	}
+	if x, _, err := SplitUint64(unhex("820100")); err != nil || x != 256 {
+		t.Errorf("SplitUint64 = %d, %v", x, err)
+	}
+	if _, _, err := SplitUint64(unhex("820001")); !errors.Is(err, ErrCanonInt) {
+		t.Errorf("SplitUint64 with a leading zero: %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package rlp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+)
+
+// Encoder is implemented by types that encode themselves
+type Encoder interface {
+	EncodeRLP(w io.Writer) error
+}
+
+// RawValue is an already encoded value, written and read verbatim
+type RawValue []byte
+
+// Encode writes the encoding of val to w. See EncodeToBytes for the
+// supported types.
+func Encode(w io.Writer, val any) error {
+	b, err := EncodeToBytes(val)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// EncodeToBytes returns the encoding of val:
+//   - unsigned integers and bool as integers without leading zeros
+//   - big.Int and *big.Int as non-negative integers (nil is zero)
+//   - strings, byte slices and byte arrays, such as hashes and addresses, as strings
+//   - other slices and arrays as lists of their elements
+//   - structs as lists of their exported fields, following their rlp tags
+//   - nil pointers as the empty string or empty list of the element type
+//   - Encoder and RawValue values as they encode themselves
+func EncodeToBytes(val any) ([]byte, error) {
+	return appendValue(nil, reflect.ValueOf(val))
+}
+
+func appendValue(dst []byte, v reflect.Value) ([]byte, error) {
+	if !v.IsValid() {
+		return append(dst, EmptyList...), nil // nil interface
+	}
+	typ := v.Type()
+	switch {
+	case typ == rawValueType:
+		return append(dst, v.Bytes()...), nil
+	case typ.Implements(encoderType):
+		if typ.Kind() == reflect.Pointer && v.IsNil() {
+			return appendNil(dst, typ.Elem()), nil
+		}
+		return appendEncoder(dst, v.Interface().(Encoder))
+	case v.CanAddr() && reflect.PointerTo(typ).Implements(encoderType):
+		return appendEncoder(dst, v.Addr().Interface().(Encoder))
+	case typ == bigIntType:
+		x := v.Interface().(big.Int)
+		return appendBig(dst, &x)
+	case typ == bigIntPtrType:
+		return appendBig(dst, v.Interface().(*big.Int))
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return append(dst, 0x01), nil
+		}
+		return append(dst, 0x80), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return AppendUint64(dst, v.Uint()), nil
+	case reflect.String:
+		return AppendBytes(dst, []byte(v.String())), nil
+	case reflect.Slice, reflect.Array:
+		if isByteArray(typ) {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			return AppendBytes(dst, b), nil
+		}
+		var payload []byte
+		for i := 0; i < v.Len(); i++ {
+			var err error
+			if payload, err = appendValue(payload, v.Index(i)); err != nil {
+				return nil, err
+			}
+		}
+		return AppendList(dst, payload), nil
+	case reflect.Struct:
+		return appendStruct(dst, v)
+	case reflect.Pointer:
+		if v.IsNil() {
+			return appendNil(dst, typ.Elem()), nil
+		}
+		return appendValue(dst, v.Elem())
+	case reflect.Interface:
+		return appendValue(dst, v.Elem())
+	}
+	return nil, fmt.Errorf("rlp: type %v is not RLP-serializable", typ)
+}
+
+func appendStruct(dst []byte, v reflect.Value) ([]byte, error) {
+	fields, err := structFields(v.Type())
+	if err != nil {
+		return nil, err
+	}
+	// Trailing optional fields are dropped while they are zero
+	end := len(fields)
+	for end > 0 && fields[end-1].optional && v.Field(fields[end-1].index).IsZero() {
+		end--
+	}
+	var payload []byte
+	for _, f := range fields[:end] {
+		if payload, err = appendValue(payload, v.Field(f.index)); err != nil {
+			return nil, err
+		}
+	}
+	return AppendList(dst, payload), nil
+}
+
+// appendNil writes the empty value of typ, the encoding of a nil pointer
+func appendNil(dst []byte, typ reflect.Type) []byte {
+	if isListType(typ) {
+		return append(dst, EmptyList...)
+	}
+	return append(dst, EmptyString...)
+}
+
+func appendBig(dst []byte, x *big.Int) ([]byte, error) {
+	if x != nil && x.Sign() < 0 {
+		return nil, fmt.Errorf("rlp: cannot encode negative big.Int")
+	}
+	return AppendBigInt(dst, x), nil
+}
+
+func appendEncoder(dst []byte, enc Encoder) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := enc.EncodeRLP(&buf); err != nil {
+		return nil, err
+	}
+	return append(dst, buf.Bytes()...), nil
+}
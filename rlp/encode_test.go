@@ -0,0 +1,121 @@
+package rlp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+type simpleStruct struct {
+	A uint
+	B string
+}
+
+type recStruct struct {
+	I     uint
+	Child *recStruct `rlp:"nil"`
+}
+
+type optionalFields struct {
+	A uint
+	B uint `rlp:"optional"`
+	C uint `rlp:"optional"`
+}
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(strings.ToLower(s))
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Vectors from geth's RLP package
+var encTests = []struct {
+	val    any
+	output string
+}{
+	{true, "01"},
+	{false, "80"},
+
+	{uint32(0), "80"},
+	{uint32(127), "7F"},
+	{uint32(128), "8180"},
+	{uint32(256), "820100"},
+	{uint32(1024), "820400"},
+	{uint32(0xFFFFFF), "83FFFFFF"},
+	{uint64(0xFFFFFFFFFFFFFFFF), "88FFFFFFFFFFFFFFFF"},
+
+	{big.NewInt(0), "80"},
+	{big.NewInt(1), "01"},
+	{big.NewInt(127), "7F"},
+	{big.NewInt(128), "8180"},
+	{big.NewInt(0xFFFFFF), "83FFFFFF"},
+	{new(big.Int).SetBytes(unhex("102030405060708090A0B0C0D0E0F2")), "8F102030405060708090A0B0C0D0E0F2"},
+	{new(big.Int).SetBytes(unhex("010000000000000000000000000000000000000000000000000000000000000000")), "A1010000000000000000000000000000000000000000000000000000000000000000"},
+
+	{[0]byte{}, "80"},
+	{[1]byte{0}, "00"},
+	{[1]byte{0x7F}, "7F"},
+	{[1]byte{0x80}, "8180"},
+	{[3]byte{1, 2, 3}, "83010203"},
+	{[57]byte{1, 2, 3}, "B839010203000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000"},
+
+	{[]byte{}, "80"},
+	{[]byte{0}, "00"},
+	{[]byte{0x7E}, "7E"},
+	{[]byte{0x80}, "8180"},
+	{[]byte{1, 2, 3}, "83010203"},
+
+	{"", "80"},
+	{"\x7F", "7F"},
+	{"\x80", "8180"},
+	{"dog", "83646F67"},
+	{"Lorem ipsum dolor sit amet, consectetur adipisicing eli", "B74C6F72656D20697073756D20646F6C6F722073697420616D65742C20636F6E7365637465747572206164697069736963696E6720656C69"},
+	{"Lorem ipsum dolor sit amet, consectetur adipisicing elit", "B8384C6F72656D20697073756D20646F6C6F722073697420616D65742C20636F6E7365637465747572206164697069736963696E6720656C6974"},
+
+	{[]uint{}, "C0"},
+	{[]uint{1, 2, 3}, "C3010203"},
+	// [ [], [[]], [ [], [[]] ] ]
+	{[]any{[]any{}, [][]any{{}}, []any{[]any{}, [][]any{{}}}}, "C7C0C1C0C3C0C1C0"},
+	{[]string{"aaa", "bbb", "ccc", "ddd", "eee", "fff", "ggg", "hhh", "iii", "jjj", "kkk", "lll", "mmm", "nnn", "ooo"}, "F83C836161618362626283636363836464648365656583666666836767678368686883696969836A6A6A836B6B6B836C6C6C836D6D6D836E6E6E836F6F6F"},
+	{[]any{uint(1), uint(0xFFFFFF), []any{[]uint{4, 5, 5}}, "abc"}, "CE0183FFFFFFC4C304050583616263"},
+	{[4]uint64{1, 2, 3, 4}, "C401020304"},
+
+	{RawValue(unhex("82FFFF")), "82FFFF"},
+	{[]RawValue{unhex("01"), unhex("02")}, "C20102"},
+
+	{simpleStruct{}, "C28080"},
+	{simpleStruct{A: 3, B: "foo"}, "C50383666F6F"},
+	{&recStruct{5, nil}, "C205C0"},
+	{&recStruct{5, &recStruct{4, &recStruct{3, nil}}}, "C605C404C203C0"},
+	{&optionalFields{}, "C180"},
+	{&optionalFields{A: 1, B: 2}, "C20102"},
+	{&optionalFields{A: 1, B: 0, C: 3}, "C3018003"},
+
+	{(*uint)(nil), "80"},
+	{(*[]uint)(nil), "C0"},
+}
+
+func TestEncode(t *testing.T) {
+	for i, tt := range encTests {
+		got, err := EncodeToBytes(tt.val)
+		if err != nil {
+			t.Errorf("test %d: encode %#v: %v", i, tt.val, err)
+			continue
+		}
+		if want := unhex(tt.output); !bytes.Equal(got, want) {
+			t.Errorf("test %d: encode %#v = %X, want %s", i, tt.val, got, tt.output)
+		}
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	for _, val := range []any{big.NewInt(-1), 3, &struct{ X int }{}} {
+		if _, err := EncodeToBytes(val); err == nil {
+			t.Errorf("encode %#v succeeded", val)
+		}
+	}
+}
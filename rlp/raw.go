@@ -0,0 +1,255 @@
+// Package rlp implements the Recursive Length Prefix serialization used for
+// transactions, receipts, trie nodes and contract addresses (Yellow Paper
+// appendix B).
+package rlp
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+	"math/bits"
+)
+
+// Kind is the type of an RLP item
+type Kind int
+
+const (
+	Byte   Kind = iota // A single byte below 0x80, encoded as itself
+	String             // A byte string with a length prefix
+	List               // A list of items with a length prefix
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Byte:
+		return "Byte"
+	case String:
+		return "String"
+	case List:
+		return "List"
+	default:
+		return "Unknown"
+	}
+}
+
+var (
+	ErrExpectedString   = errors.New("rlp: expected String or Byte")
+	ErrExpectedList     = errors.New("rlp: expected List")
+	ErrCanonInt         = errors.New("rlp: non-canonical integer format")
+	ErrCanonSize        = errors.New("rlp: non-canonical size information")
+	ErrElemTooLarge     = errors.New("rlp: element is larger than containing list")
+	ErrValueTooLarge    = errors.New("rlp: value size exceeds available input length")
+	ErrUint64Range      = errors.New("rlp: value overflows uint64")
+	ErrMoreThanOneValue = errors.New("rlp: input contains more than one value")
+)
+
+// EmptyString and EmptyList are the encodings of "" and []
+var (
+	EmptyString = []byte{0x80}
+	EmptyList   = []byte{0xc0}
+)
+
+// AppendBytes appends the encoding of the byte string b to dst
+func AppendBytes(dst, b []byte) []byte {
+	if len(b) == 1 && b[0] < 0x80 {
+		return append(dst, b[0])
+	}
+	dst = appendHeader(dst, 0x80, uint64(len(b)))
+	return append(dst, b...)
+}
+
+// AppendUint64 appends the encoding of i as a big-endian integer without leading zeros
+func AppendUint64(dst []byte, i uint64) []byte {
+	switch {
+	case i == 0:
+		return append(dst, 0x80)
+	case i < 0x80:
+		return append(dst, byte(i))
+	}
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], i)
+	return AppendBytes(dst, buf[bits.LeadingZeros64(i)/8:])
+}
+
+// AppendBigInt appends the encoding of a non-negative integer; nil encodes as zero
+func AppendBigInt(dst []byte, i *big.Int) []byte {
+	if i == nil || i.Sign() == 0 {
+		return append(dst, 0x80)
+	}
+	if i.Sign() < 0 {
+		panic("rlp: cannot encode negative big.Int")
+	}
+	return AppendBytes(dst, i.Bytes())
+}
+
+// AppendList wraps the already encoded items in payload into a list
+func AppendList(dst, payload []byte) []byte {
+	dst = appendHeader(dst, 0xc0, uint64(len(payload)))
+	return append(dst, payload...)
+}
+
+// EncodeBytes, EncodeUint64 and EncodeList return the encoding of a single item
+func EncodeBytes(b []byte) []byte       { return AppendBytes(nil, b) }
+func EncodeUint64(i uint64) []byte      { return AppendUint64(nil, i) }
+func EncodeList(items ...[]byte) []byte { return AppendList(nil, concat(items)) }
+
+func concat(items [][]byte) []byte {
+	var payload []byte
+	for _, item := range items {
+		payload = append(payload, item...)
+	}
+	return payload
+}
+
+// appendHeader writes the short form (offset + size) for payloads under 56
+// bytes, otherwise offset + 55 + len(size) followed by the size itself
+func appendHeader(dst []byte, offset byte, size uint64) []byte {
+	if size < 56 {
+		return append(dst, offset+byte(size))
+	}
+	n := 8 - bits.LeadingZeros64(size)/8
+	dst = append(dst, offset+55+byte(n))
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], size)
+	return append(dst, buf[8-n:]...)
+}
+
+// Split returns the kind and content of the first item in b and the bytes
+// after it. Non-canonical size prefixes are rejected.
+func Split(b []byte) (k Kind, content, rest []byte, err error) {
+	k, ts, cs, err := readKind(b)
+	if err != nil {
+		return 0, nil, b, err
+	}
+	return k, b[ts : ts+cs], b[ts+cs:], nil
+}
+
+// SplitString splits b into the content of a string item and the remaining bytes
+func SplitString(b []byte) (content, rest []byte, err error) {
+	k, content, rest, err := Split(b)
+	if err != nil {
+		return nil, b, err
+	}
+	if k == List {
+		return nil, b, ErrExpectedString
+	}
+	return content, rest, nil
+}
+
+// SplitList splits b into the payload of a list item and the remaining bytes
+func SplitList(b []byte) (content, rest []byte, err error) {
+	k, content, rest, err := Split(b)
+	if err != nil {
+		return nil, b, err
+	}
+	if k != List {
+		return nil, b, ErrExpectedList
+	}
+	return content, rest, nil
+}
+
+// SplitUint64 decodes an integer of at most 8 bytes from the start of b
+func SplitUint64(b []byte) (x uint64, rest []byte, err error) {
+	content, rest, err := SplitString(b)
+	if err != nil {
+		return 0, b, err
+	}
+	switch {
+	case len(content) == 0:
+		return 0, rest, nil
+	case len(content) == 1:
+		if content[0] == 0 {
+			return 0, b, ErrCanonInt
+		}
+		return uint64(content[0]), rest, nil
+	case len(content) > 8:
+		return 0, b, ErrUint64Range
+	case content[0] == 0:
+		return 0, b, ErrCanonInt
+	default:
+		var buf [8]byte
+		copy(buf[8-len(content):], content)
+		return binary.BigEndian.Uint64(buf[:]), rest, nil
+	}
+}
+
+// SplitBigInt decodes a non-negative integer of at most maxBytes bytes
+func SplitBigInt(b []byte, maxBytes int) (x *big.Int, rest []byte, err error) {
+	content, rest, err := SplitString(b)
+	if err != nil {
+		return nil, b, err
+	}
+	if len(content) > 0 && content[0] == 0 {
+		return nil, b, ErrCanonInt
+	}
+	if len(content) > maxBytes {
+		return nil, b, ErrValueTooLarge
+	}
+	return new(big.Int).SetBytes(content), rest, nil
+}
+
+// CountValues counts the encoded items in b, e.g. the payload of a list
+func CountValues(b []byte) (int, error) {
+	i := 0
+	for ; len(b) > 0; i++ {
+		_, tagsize, size, err := readKind(b)
+		if err != nil {
+			return 0, err
+		}
+		b = b[tagsize+size:]
+	}
+	return i, nil
+}
+
+// readKind decodes the prefix of the item at the start of buf, returning the
+// header size and content size
+func readKind(buf []byte) (k Kind, tagsize, contentsize uint64, err error) {
+	if len(buf) == 0 {
+		return 0, 0, 0, io.ErrUnexpectedEOF
+	}
+	b := buf[0]
+	switch {
+	case b < 0x80:
+		k, tagsize, contentsize = Byte, 0, 1
+	case b < 0xb8:
+		k, tagsize, contentsize = String, 1, uint64(b-0x80)
+		// A single byte below 0x80 must be encoded as itself
+		if contentsize == 1 && len(buf) > 1 && buf[1] < 0x80 {
+			return 0, 0, 0, ErrCanonSize
+		}
+	case b < 0xc0:
+		k, tagsize = String, uint64(b-0xb7)+1
+		contentsize, err = readSize(buf[1:], b-0xb7)
+	case b < 0xf8:
+		k, tagsize, contentsize = List, 1, uint64(b-0xc0)
+	default:
+		k, tagsize = List, uint64(b-0xf7)+1
+		contentsize, err = readSize(buf[1:], b-0xf7)
+	}
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if contentsize > uint64(len(buf))-tagsize {
+		return 0, 0, 0, ErrValueTooLarge
+	}
+	return k, tagsize, contentsize, nil
+}
+
+// readSize reads a big-endian size of slen bytes, which must not have leading
+// zeros and, for long-form headers, must be at least 56
+func readSize(b []byte, slen byte) (uint64, error) {
+	if int(slen) > len(b) {
+		return 0, ErrValueTooLarge
+	}
+	if b[0] == 0 {
+		return 0, ErrCanonSize
+	}
+	var buf [8]byte
+	copy(buf[8-slen:], b[:slen])
+	s := binary.BigEndian.Uint64(buf[:])
+	if s < 56 {
+		return 0, ErrCanonSize
+	}
+	return s, nil
+}
@@ -0,0 +1,91 @@
+package rlp
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var (
+	bigIntType    = reflect.TypeOf(big.Int{})
+	bigIntPtrType = reflect.TypeOf((*big.Int)(nil))
+	rawValueType  = reflect.TypeOf(RawValue{})
+	encoderType   = reflect.TypeOf((*Encoder)(nil)).Elem()
+	decoderType   = reflect.TypeOf((*Decoder)(nil)).Elem()
+)
+
+// field is an exported struct field and the options from its rlp tag:
+//
+//	rlp:"-"        the field is ignored
+//	rlp:"nil"      a nil pointer encodes as an empty value and decodes back to nil
+//	rlp:"optional" the field may be missing from the end of the list; when it
+//	               and all fields after it are zero they are not encoded.
+//	               Every field after an optional one must be optional too.
+type field struct {
+	index    int
+	name     string
+	nilOK    bool
+	optional bool
+}
+
+var fieldCache sync.Map // reflect.Type -> []field
+
+// structFields returns the encoded fields of a struct type in order
+func structFields(typ reflect.Type) ([]field, error) {
+	if cached, ok := fieldCache.Load(typ); ok {
+		return cached.([]field), nil
+	}
+	var fields []field
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		f := field{index: i, name: sf.Name}
+		ignored := false
+		for _, opt := range strings.Split(sf.Tag.Get("rlp"), ",") {
+			switch strings.TrimSpace(opt) {
+			case "":
+			case "-":
+				ignored = true
+			case "nil":
+				if sf.Type.Kind() != reflect.Pointer {
+					return nil, fmt.Errorf("rlp: invalid struct tag \"nil\" for %v.%s (field is not a pointer)", typ, sf.Name)
+				}
+				f.nilOK = true
+			case "optional":
+				f.optional = true
+			default:
+				return nil, fmt.Errorf("rlp: unknown struct tag %q on %v.%s", opt, typ, sf.Name)
+			}
+		}
+		if ignored {
+			continue
+		}
+		if n := len(fields); n > 0 && fields[n-1].optional && !f.optional {
+			return nil, fmt.Errorf("rlp: invalid struct tag for %v.%s (must be optional because preceding field %q is optional)", typ, sf.Name, fields[n-1].name)
+		}
+		fields = append(fields, f)
+	}
+	fieldCache.Store(typ, fields)
+	return fields, nil
+}
+
+// isByteArray reports whether typ is encoded as a string rather than a list
+func isByteArray(typ reflect.Type) bool {
+	return (typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array) && typ.Elem().Kind() == reflect.Uint8
+}
+
+// isListType reports whether values of typ encode as lists, which decides
+// the empty value used for nil pointers
+func isListType(typ reflect.Type) bool {
+	switch {
+	case typ == bigIntType, isByteArray(typ):
+		return false
+	case typ.Kind() == reflect.Struct, typ.Kind() == reflect.Slice, typ.Kind() == reflect.Array, typ.Kind() == reflect.Interface:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+package t8n
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	types "github.com/morelucks/minievm/typess"
+)
+
+// Env is the block a transition executes in, env.json. Fields the tool can
+// derive, such as the base fee or the difficulty, may be left out when the
+// parent values they follow from are given.
+type Env struct {
+	Coinbase              types.Address                  `json:"currentCoinbase"`
+	Difficulty            *types.HexBig                  `json:"currentDifficulty"`
+	Random                *types.Word                    `json:"currentRandom"`
+	ParentDifficulty      *types.HexBig                  `json:"parentDifficulty"`
+	ParentBaseFee         *types.HexBig                  `json:"parentBaseFee"`
+	ParentGasUsed         types.HexUint64                `json:"parentGasUsed"`
+	ParentGasLimit        types.HexUint64                `json:"parentGasLimit"`
+	GasLimit              types.HexUint64                `json:"currentGasLimit"`
+	Number                types.HexUint64                `json:"currentNumber"`
+	Timestamp             types.HexUint64                `json:"currentTimestamp"`
+	ParentTimestamp       types.HexUint64                `json:"parentTimestamp"`
+	BlockHashes           map[types.HexUint64]types.Word `json:"blockHashes"`
+	Ommers                []Ommer                        `json:"ommers"`
+	Withdrawals           []*Withdrawal                  `json:"withdrawals"`
+	BaseFee               *types.HexBig                  `json:"currentBaseFee"`
+	ParentUncleHash       types.Word                     `json:"parentUncleHash"`
+	ExcessBlobGas         *types.HexUint64               `json:"currentExcessBlobGas"`
+	ParentExcessBlobGas   *types.HexUint64               `json:"parentExcessBlobGas"`
+	ParentBlobGasUsed     *types.HexUint64               `json:"parentBlobGasUsed"`
+	ParentBeaconBlockRoot *types.Word                    `json:"parentBeaconBlockRoot"`
+}
+
+// Ommer is an ommer of a proof-of-work block, Delta blocks older than it
+type Ommer struct {
+	Delta   uint64        `json:"delta"`
+	Address types.Address `json:"address"`
+}
+
+// Withdrawal is a withdrawal of env.json (EIP-4895)
+type Withdrawal struct {
+	Index     types.HexUint64 `json:"index"`
+	Validator types.HexUint64 `json:"validatorIndex"`
+	Address   types.Address   `json:"address"`
+	Amount    types.HexUint64 `json:"amount"` // Gwei
+}
+
+// Difficulty bomb delays (EIP-1234, EIP-2384, EIP-3554)
+var bombDelays = map[types.Fork]uint64{
+	types.Istanbul: 5_000_000,
+	types.Berlin:   9_000_000,
+	types.London:   9_700_000,
+}
+
+const minimumDifficulty = 131072
+
+// prepare checks that the env has the fields fork needs and derives those
+// left out: the base fee from the parent's, and before the merge the
+// difficulty from the parent's
+func (env *Env) prepare(config *types.ChainConfig, fork types.Fork) error {
+	if fork.IsActive(types.London) && env.BaseFee == nil {
+		if env.ParentBaseFee == nil || env.Number == 0 {
+			return errors.New("EIP-1559 config but missing 'parentBaseFee' in env section")
+		}
+		parent := &types.Header{
+			GasLimit: uint64(env.ParentGasLimit),
+			GasUsed:  uint64(env.ParentGasUsed),
+		}
+		// The first London block starts from the initial base fee
+		if config.Fork(uint64(env.Number)-1, uint64(env.ParentTimestamp)).IsActive(types.London) {
+			parent.BaseFee = env.ParentBaseFee.ToInt()
+		}
+		env.BaseFee = types.NewHexBig(types.CalcBaseFee(parent))
+	}
+	if fork.IsActive(types.Shanghai) && env.Withdrawals == nil {
+		return errors.New("Shanghai config but missing 'withdrawals' in env section")
+	}
+
+	if config.IsMerged() {
+		switch {
+		case env.Random == nil:
+			return errors.New("post-merge requires currentRandom to be defined in env")
+		case env.Difficulty != nil && env.Difficulty.ToInt().Sign() != 0:
+			return errors.New("post-merge difficulty must be zero (or omitted) in env")
+		}
+		env.Difficulty = nil
+	} else if env.Difficulty == nil {
+		switch {
+		case env.ParentDifficulty == nil:
+			return errors.New("currentDifficulty was not provided, and cannot be calculated due to missing parentDifficulty")
+		case env.Number == 0:
+			return errors.New("currentDifficulty needs to be provided for block number 0")
+		case env.Timestamp <= env.ParentTimestamp:
+			return fmt.Errorf("currentDifficulty cannot be calculated -- currentTime (%d) needs to be after parent time (%d)", env.Timestamp, env.ParentTimestamp)
+		}
+		env.Difficulty = types.NewHexBig(env.calcDifficulty(fork))
+	}
+
+	if !fork.IsActive(types.Cancun) {
+		env.ParentBeaconBlockRoot = nil
+	} else if env.ParentBeaconBlockRoot == nil {
+		return errors.New("post-cancun env requires parentBeaconBlockRoot to be set")
+	}
+	return nil
+}
+
+// calcDifficulty returns the proof-of-work difficulty of the block (EIP-100):
+// the parent's adjusted by the time since it, plus the difficulty bomb
+func (env *Env) calcDifficulty(fork types.Fork) *big.Int {
+	parent := env.ParentDifficulty.ToInt()
+	// max((2 if the parent has ommers else 1) - (time - parent time) // 9, -99)
+	adjustment := int64((env.Timestamp - env.ParentTimestamp) / 9)
+	if env.ParentUncleHash == types.EmptyUncleHash || env.ParentUncleHash == (types.Word{}) {
+		adjustment = 1 - adjustment
+	} else {
+		adjustment = 2 - adjustment
+	}
+	adjustment = max(adjustment, -99)
+	diff := new(big.Int).Div(parent, big.NewInt(2048))
+	diff.Mul(diff, big.NewInt(adjustment))
+	diff.Add(diff, parent)
+	if diff.Cmp(big.NewInt(minimumDifficulty)) < 0 {
+		diff.SetInt64(minimumDifficulty)
+	}
+
+	// The bomb doubles every 100000 blocks, counted from a delayed block number
+	var fakeNumber uint64
+	if delay := bombDelays[fork]; uint64(env.Number) > delay {
+		fakeNumber = uint64(env.Number) - delay
+	}
+	if periods := fakeNumber / 100000; periods > 1 {
+		diff.Add(diff, new(big.Int).Lsh(big.NewInt(1), uint(periods-2)))
+	}
+	return diff
+}
+
+// getHash returns the BLOCKHASH lookup of the env. Missing hashes are
+// recorded in missing, as they make the transition fail.
+func (env *Env) getHash(missing *error) func(uint64) types.Word {
+	return func(number uint64) types.Word {
+		if env.BlockHashes == nil {
+			*missing = fmt.Errorf("getHash(%d) invoked, no blockhashes provided", number)
+			return types.Word{}
+		}
+		hash, ok := env.BlockHashes[types.HexUint64(number)]
+		if !ok {
+			*missing = fmt.Errorf("getHash(%d) invoked, blockhash for that block not provided", number)
+		}
+		return hash
+	}
+}
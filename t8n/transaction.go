@@ -0,0 +1,178 @@
+package t8n
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/morelucks/minievm/crypto"
+	"github.com/morelucks/minievm/rlp"
+	types "github.com/morelucks/minievm/typess"
+)
+
+// Transaction is a transaction of txs.json in the JSON form of
+// eth_getTransactionByHash. A transaction with a zero signature and a
+// secretKey is signed by the tool, with EIP-155 replay protection for
+// legacy transactions unless protected is false.
+type Transaction struct {
+	Type                 types.HexUint64  `json:"type"`
+	ChainID              *types.HexBig    `json:"chainId"`
+	Nonce                types.HexUint64  `json:"nonce"`
+	To                   *types.Address   `json:"to"` // nil creates a contract
+	Gas                  types.HexUint64  `json:"gas"`
+	GasPrice             *types.HexBig    `json:"gasPrice"`
+	MaxPriorityFeePerGas *types.HexBig    `json:"maxPriorityFeePerGas"`
+	MaxFeePerGas         *types.HexBig    `json:"maxFeePerGas"`
+	MaxFeePerBlobGas     *types.HexBig    `json:"maxFeePerBlobGas"`
+	Value                *types.HexBig    `json:"value"`
+	Input                types.HexBytes   `json:"input"`
+	AccessList           types.AccessList `json:"accessList"`
+	BlobVersionedHashes  []types.Word     `json:"blobVersionedHashes"`
+	AuthorizationList    []Authorization  `json:"authorizationList"`
+	V                    *types.HexBig    `json:"v"`
+	R                    *types.HexBig    `json:"r"`
+	S                    *types.HexBig    `json:"s"`
+	YParity              *types.HexUint64 `json:"yParity"`
+	SecretKey            *types.Word      `json:"secretKey"`
+	Protected            *bool            `json:"protected"`
+}
+
+// Authorization is an EIP-7702 authorization of a set code transaction
+type Authorization struct {
+	ChainID *types.HexBig   `json:"chainId"`
+	Address types.Address   `json:"address"`
+	Nonce   types.HexUint64 `json:"nonce"`
+	YParity types.HexUint64 `json:"yParity"`
+	R       *types.HexBig   `json:"r"`
+	S       *types.HexBig   `json:"s"`
+}
+
+// Tx is a transaction to apply, or the error reading it, which makes the
+// transition reject it
+type Tx struct {
+	Tx  *types.Transaction
+	Err error
+}
+
+// DecodeTxs decodes an RLP list of signed transactions in the form blocks
+// hold them. Only a malformed list is an error; transactions that fail to
+// decode are rejected.
+func DecodeTxs(body []byte) ([]Tx, error) {
+	var encs []rlp.RawValue
+	if err := rlp.DecodeBytes(body, &encs); err != nil {
+		return nil, NewError(ErrorRlp, fmt.Errorf("failed decoding transactions: %w", err))
+	}
+	txs := make([]Tx, len(encs))
+	for i, enc := range encs {
+		txs[i].Tx, txs[i].Err = types.DecodeBlockTransaction(enc)
+	}
+	return txs, nil
+}
+
+// ToTransaction converts tx, signing it for chainID if it is unsigned
+func (tx *Transaction) ToTransaction(chainID *big.Int) (*types.Transaction, error) {
+	if tx.Type > types.HexUint64(types.SetCodeTxType) {
+		return nil, fmt.Errorf("%w: type %d", types.ErrTxTypeNotSupported, tx.Type)
+	}
+	out := &types.Transaction{
+		Type:       byte(tx.Type),
+		Nonce:      uint64(tx.Nonce),
+		GasPrice:   bigOrNil(tx.GasPrice),
+		GasTipCap:  bigOrNil(tx.MaxPriorityFeePerGas),
+		GasFeeCap:  bigOrNil(tx.MaxFeePerGas),
+		Gas:        uint64(tx.Gas),
+		To:         tx.To,
+		Value:      bigOrZero(tx.Value),
+		Data:       tx.Input,
+		AccessList: tx.AccessList,
+		BlobFeeCap: bigOrNil(tx.MaxFeePerBlobGas),
+		BlobHashes: tx.BlobVersionedHashes,
+		V:          bigOrZero(tx.V),
+		R:          bigOrZero(tx.R),
+		S:          bigOrZero(tx.S),
+	}
+	if tx.YParity != nil && tx.V == nil {
+		out.V = new(big.Int).SetUint64(uint64(*tx.YParity))
+	}
+	for _, auth := range tx.AuthorizationList {
+		out.AuthList = append(out.AuthList, types.SetCodeAuthorization{
+			ChainID: bigOrZero(auth.ChainID),
+			Address: auth.Address,
+			Nonce:   uint64(auth.Nonce),
+			V:       uint8(auth.YParity),
+			R:       bigOrZero(auth.R),
+			S:       bigOrZero(auth.S),
+		})
+	}
+
+	unsigned := out.V.Sign() == 0 && out.R.Sign() == 0 && out.S.Sign() == 0
+	switch {
+	case out.Type != types.LegacyTxType:
+		out.ChainID = bigOrZero(tx.ChainID)
+	case unsigned && (tx.Protected == nil || *tx.Protected):
+		out.ChainID = chainID
+	case !unsigned:
+		out.ChainID = legacyChainID(out.V)
+	}
+	if tx.SecretKey == nil || !unsigned {
+		return out, nil
+	}
+	// Only legacy transactions can go without replay protection
+	if out.Type != types.LegacyTxType && tx.Protected != nil && !*tx.Protected {
+		return nil, fmt.Errorf("failed to sign tx: %w", types.ErrTxTypeNotSupported)
+	}
+	if err := sign(out, tx.SecretKey[:], chainID); err != nil {
+		return nil, fmt.Errorf("failed to sign tx: %w", err)
+	}
+	return out, nil
+}
+
+// sign sets the signature of tx by key. Typed transactions must be for
+// chainID, the chain the tool signs for.
+func sign(tx *types.Transaction, key []byte, chainID *big.Int) error {
+	if tx.Type != types.LegacyTxType && tx.ChainID.Cmp(chainID) != 0 {
+		return fmt.Errorf("%w: have %d want %d", types.ErrInvalidChainID, tx.ChainID, chainID)
+	}
+	hash := tx.SigHash()
+	sig, err := crypto.Sign(hash[:], key)
+	if err != nil {
+		return err
+	}
+	tx.R = new(big.Int).SetBytes(sig[:32])
+	tx.S = new(big.Int).SetBytes(sig[32:64])
+	tx.V = big.NewInt(int64(sig[64]))
+	switch {
+	case tx.Type != types.LegacyTxType:
+	case tx.Protected():
+		// v = chainId * 2 + 35 + recovery id (EIP-155)
+		tx.V.Add(tx.V, new(big.Int).Lsh(tx.ChainID, 1))
+		tx.V.Add(tx.V, big.NewInt(35))
+	default:
+		tx.V.Add(tx.V, big.NewInt(27))
+	}
+	return nil
+}
+
+// legacyChainID returns the chain id a legacy signature commits to, nil
+// for signatures from before EIP-155
+func legacyChainID(v *big.Int) *big.Int {
+	if v.Cmp(big.NewInt(35)) < 0 {
+		return nil
+	}
+	// v = chainId * 2 + 35 + recovery id
+	chainID := new(big.Int).Sub(v, big.NewInt(35))
+	return chainID.Rsh(chainID, 1)
+}
+
+func bigOrNil(x *types.HexBig) *big.Int {
+	if x == nil {
+		return nil
+	}
+	return new(big.Int).Set(x.ToInt())
+}
+
+func bigOrZero(x *types.HexBig) *big.Int {
+	if x == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(x.ToInt())
+}
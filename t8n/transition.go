@@ -0,0 +1,353 @@
+// Package t8n implements the state transition tool test fillers drive
+// clients through: a pre-state alloc, a block env and transactions in; the
+// post-state alloc, the block results and the body of the included
+// transactions out. Its inputs, outputs and exit codes follow geth's evm t8n.
+package t8n
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/morelucks/minievm/tests"
+	types "github.com/morelucks/minievm/typess"
+)
+
+// Exit codes of the tool
+const (
+	ErrorEVM              = 2
+	ErrorConfig           = 3
+	ErrorMissingBlockhash = 4
+	ErrorJson             = 10
+	ErrorIO               = 11
+	ErrorRlp              = 12
+)
+
+// Error is a failure of the tool with the exit code it ends with
+type Error struct {
+	Code int
+	Err  error
+}
+
+func NewError(code int, err error) *Error {
+	return &Error{Code: code, Err: err}
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("ERROR(%d): %v", e.Code, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the status the process exits with
+func (e *Error) ExitCode() int {
+	return e.Code
+}
+
+// blockHash stands in for the hash of the block being built, which is
+// unknown until the results are in
+var blockHash = types.Word{0x13, 0x37}
+
+// Prestate is the input of a transition: the block env and the accounts
+// before it
+type Prestate struct {
+	Env Env                `json:"env"`
+	Pre types.GenesisAlloc `json:"pre"`
+}
+
+// ExecutionResult is result.json, the outcome of the transition from which
+// the filler builds the block header
+type ExecutionResult struct {
+	StateRoot            types.Word       `json:"stateRoot"`
+	TxRoot               types.Word       `json:"txRoot"`
+	ReceiptsRoot         types.Word       `json:"receiptsRoot"`
+	LogsHash             types.Word       `json:"logsHash"`
+	Bloom                types.Bloom      `json:"logsBloom"`
+	Receipts             []*Receipt       `json:"receipts"`
+	Rejected             []*RejectedTx    `json:"rejected,omitempty"`
+	Difficulty           *types.HexBig    `json:"currentDifficulty"` // null after the merge
+	GasUsed              types.HexUint64  `json:"gasUsed"`
+	BaseFee              *types.HexBig    `json:"currentBaseFee,omitempty"`
+	WithdrawalsRoot      *types.Word      `json:"withdrawalsRoot,omitempty"`
+	CurrentExcessBlobGas *types.HexUint64 `json:"currentExcessBlobGas,omitempty"`
+	BlobGasUsed          *types.HexUint64 `json:"blobGasUsed,omitempty"`
+	RequestsHash         *types.Word      `json:"requestsHash,omitempty"`
+	Requests             []types.HexBytes `json:"requests"` // Non-empty request lists from Prague (EIP-7685)
+}
+
+// RejectedTx is a transaction left out of the block, by its index in the
+// input, and the reason why
+type RejectedTx struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+// Receipt is a receipt in the JSON form of eth_getTransactionReceipt
+type Receipt struct {
+	Type              types.HexUint64 `json:"type,omitempty"`
+	Root              types.HexBytes  `json:"root"` // Empty; receipts have a status since Byzantium
+	Status            types.HexUint64 `json:"status"`
+	CumulativeGasUsed types.HexUint64 `json:"cumulativeGasUsed"`
+	Bloom             types.Bloom     `json:"logsBloom"`
+	Logs              []*Log          `json:"logs"`
+	TxHash            types.Word      `json:"transactionHash"`
+	ContractAddress   types.Address   `json:"contractAddress"`
+	GasUsed           types.HexUint64 `json:"gasUsed"`
+	EffectiveGasPrice *types.HexBig   `json:"effectiveGasPrice"`
+	BlobGasUsed       types.HexUint64 `json:"blobGasUsed,omitempty"`
+	BlobGasPrice      *types.HexBig   `json:"blobGasPrice,omitempty"`
+	BlockHash         types.Word      `json:"blockHash"`
+	BlockNumber       *types.HexBig   `json:"blockNumber"`
+	TransactionIndex  types.HexUint64 `json:"transactionIndex"`
+}
+
+// Log is a log in the JSON form of eth_getLogs
+type Log struct {
+	Address     types.Address   `json:"address"`
+	Topics      []types.Word    `json:"topics"`
+	Data        types.HexBytes  `json:"data"`
+	BlockNumber types.HexUint64 `json:"blockNumber"`
+	TxHash      types.Word      `json:"transactionHash"`
+	TxIndex     types.HexUint64 `json:"transactionIndex"`
+	BlockHash   types.Word      `json:"blockHash"`
+	Index       types.HexUint64 `json:"logIndex"`
+	Removed     bool            `json:"removed"`
+}
+
+// Apply executes txs on the pre-state in the env under config and returns
+// the post-state, the results and the RLP encoded list of the included
+// transactions. Invalid transactions are rejected rather than failing the
+// transition; a miner reward below zero is not paid.
+func (pre *Prestate) Apply(config *types.ChainConfig, txs []Tx, reward int64) (*types.StateDB, *ExecutionResult, []byte, error) {
+	env := &pre.Env
+	fork := config.Fork(uint64(env.Number), uint64(env.Timestamp))
+	if err := env.prepare(config, fork); err != nil {
+		return nil, nil, nil, NewError(ErrorConfig, err)
+	}
+
+	var hashErr error
+	state := pre.Pre.State()
+	block := &types.BlockContext{
+		Coinbase:   env.Coinbase,
+		Number:     uint64(env.Number),
+		Time:       uint64(env.Timestamp),
+		GasLimit:   uint64(env.GasLimit),
+		BaseFee:    bigOrNil(env.BaseFee),
+		ChainID:    config.ChainID,
+		Difficulty: bigOrNil(env.Difficulty),
+		Random:     env.Random,
+		GetHash:    env.getHash(&hashErr),
+	}
+	// The blob base fee follows from the excess blob gas, given or derived
+	// from the parent's
+	var excessBlobGas uint64
+	switch {
+	case env.ExcessBlobGas != nil:
+		excessBlobGas = uint64(*env.ExcessBlobGas)
+		block.BlobBaseFee = types.CalcBlobFee(excessBlobGas, fork)
+	case env.ParentExcessBlobGas != nil && env.ParentBlobGasUsed != nil:
+		parentExcess, parentUsed := uint64(*env.ParentExcessBlobGas), uint64(*env.ParentBlobGasUsed)
+		parent := &types.Header{ExcessBlobGas: &parentExcess, BlobGasUsed: &parentUsed, BaseFee: bigOrNil(env.ParentBaseFee)}
+		excessBlobGas = types.CalcExcessBlobGas(parent, fork)
+		block.BlobBaseFee = types.CalcBlobFee(excessBlobGas, fork)
+	}
+
+	if env.ParentBeaconBlockRoot != nil {
+		types.ProcessBeaconBlockRoot(state, block, *env.ParentBeaconBlockRoot, fork)
+	}
+	if env.BlockHashes != nil && fork.IsActive(types.Prague) {
+		parentHash := env.BlockHashes[env.Number-1]
+		types.ProcessParentBlockHash(state, block, parentHash, fork)
+	}
+
+	var (
+		included    []*types.Transaction
+		receipts    []*types.Receipt
+		rejected    []*RejectedTx
+		gasUsed     uint64
+		blobGasUsed uint64
+		logIndex    uint
+	)
+	reject := func(i int, err error) {
+		rejected = append(rejected, &RejectedTx{Index: i, Error: err.Error()})
+	}
+	for i, item := range txs {
+		tx := item.Tx
+		if item.Err != nil {
+			reject(i, item.Err)
+			continue
+		}
+		if tx.Type == types.BlobTxType && block.BlobBaseFee == nil {
+			reject(i, fmt.Errorf("blob tx used but field env.ExcessBlobGas missing"))
+			continue
+		}
+		txBlobGas := uint64(len(tx.BlobHashes)) * types.GasPerBlob
+		if cfg := types.BlobParams(fork); cfg != nil && blobGasUsed+txBlobGas > cfg.MaxBlobGas() {
+			reject(i, fmt.Errorf("blob gas (%d) would exceed maximum allowance %d", blobGasUsed+txBlobGas, cfg.MaxBlobGas()))
+			continue
+		}
+		if available := uint64(env.GasLimit) - gasUsed; tx.Gas > available {
+			reject(i, fmt.Errorf("%w: have %d, want %d", types.ErrGasLimitReached, available, tx.Gas))
+			continue
+		}
+		// Transactions fail before they change the state
+		receipt, err := types.ApplyTransaction(state, block, tx, fork, &gasUsed)
+		if err != nil {
+			reject(i, err)
+			continue
+		}
+		if hashErr != nil {
+			return nil, nil, nil, NewError(ErrorMissingBlockhash, hashErr)
+		}
+		receipt.TransactionIndex = uint(len(receipts))
+		for _, log := range receipt.Logs {
+			log.TxIndex, log.Index = receipt.TransactionIndex, logIndex
+			logIndex++
+		}
+		blobGasUsed += receipt.BlobGasUsed
+		included = append(included, tx)
+		receipts = append(receipts, receipt)
+	}
+
+	if reward >= 0 {
+		// Ommers get (8 - delta) / 8 of the reward and the miner 1/32 of it
+		// for each ommer on top. A zero reward still touches the coinbase.
+		blockReward := big.NewInt(reward)
+		minerReward := new(big.Int).Set(blockReward)
+		for _, ommer := range env.Ommers {
+			minerReward.Add(minerReward, new(big.Int).Rsh(blockReward, 5))
+			ommerReward := big.NewInt(8 - int64(ommer.Delta))
+			ommerReward.Mul(ommerReward, blockReward)
+			state.AddBalance(ommer.Address, ommerReward.Rsh(ommerReward, 3))
+		}
+		state.AddBalance(env.Coinbase, minerReward)
+	}
+	if fork.IsActive(types.Shanghai) {
+		for _, w := range env.Withdrawals {
+			amount := new(big.Int).Mul(new(big.Int).SetUint64(uint64(w.Amount)), big.NewInt(types.GweiPerEther))
+			state.AddBalance(w.Address, amount)
+		}
+	}
+	var requests [][]byte
+	if fork.IsActive(types.Prague) {
+		var err error
+		if requests, err = collectRequests(state, block, receipts, fork); err != nil {
+			return nil, nil, nil, NewError(ErrorEVM, fmt.Errorf("failed to process post-execution: %w", err))
+		}
+	}
+	state.Finalise()
+
+	var logs []*types.Log
+	for _, receipt := range receipts {
+		logs = append(logs, receipt.Logs...)
+	}
+	result := &ExecutionResult{
+		StateRoot:    state.Root(),
+		TxRoot:       types.TransactionsRoot(included),
+		ReceiptsRoot: types.ReceiptsRoot(receipts),
+		LogsHash:     tests.LogsHash(logs),
+		Bloom:        types.CreateBloom(receipts),
+		Receipts:     make([]*Receipt, len(receipts)),
+		Rejected:     rejected,
+		Difficulty:   env.Difficulty,
+		GasUsed:      types.HexUint64(gasUsed),
+		BaseFee:      env.BaseFee,
+	}
+	for i, receipt := range receipts {
+		result.Receipts[i] = newReceipt(receipt, block.Number)
+	}
+	if env.Withdrawals != nil {
+		root := types.WithdrawalsRoot(env.toWithdrawals())
+		result.WithdrawalsRoot = &root
+	}
+	if block.BlobBaseFee != nil {
+		result.CurrentExcessBlobGas = (*types.HexUint64)(&excessBlobGas)
+		result.BlobGasUsed = (*types.HexUint64)(&blobGasUsed)
+	}
+	if requests != nil {
+		hash := types.RequestsHash(requests)
+		result.RequestsHash = &hash
+		result.Requests = []types.HexBytes{}
+		for _, list := range requests {
+			if len(list) > 1 {
+				result.Requests = append(result.Requests, list)
+			}
+		}
+	}
+	return state, result, types.EncodeTransactions(included), nil
+}
+
+// collectRequests returns the deposit, withdrawal and consolidation request
+// lists of the block (EIP-7685)
+func collectRequests(state *types.StateDB, block *types.BlockContext, receipts []*types.Receipt, fork types.Fork) ([][]byte, error) {
+	var logs []*types.Log
+	for _, receipt := range receipts {
+		logs = append(logs, receipt.Logs...)
+	}
+	deposits, err := types.ParseDepositLogs(logs)
+	if err != nil {
+		return nil, err
+	}
+	withdrawals, err := types.ProcessWithdrawalQueue(state, block, fork)
+	if err != nil {
+		return nil, err
+	}
+	consolidations, err := types.ProcessConsolidationQueue(state, block, fork)
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{deposits, withdrawals, consolidations}, nil
+}
+
+// toWithdrawals converts the withdrawals of the env
+func (env *Env) toWithdrawals() []*types.Withdrawal {
+	withdrawals := make([]*types.Withdrawal, len(env.Withdrawals))
+	for i, w := range env.Withdrawals {
+		withdrawals[i] = &types.Withdrawal{
+			Index:     uint64(w.Index),
+			Validator: uint64(w.Validator),
+			Address:   w.Address,
+			Amount:    uint64(w.Amount),
+		}
+	}
+	return withdrawals
+}
+
+// newReceipt converts a receipt of block number
+func newReceipt(r *types.Receipt, number uint64) *Receipt {
+	receipt := &Receipt{
+		Type:              types.HexUint64(r.Type),
+		Root:              types.HexBytes{},
+		Status:            types.HexUint64(r.Status),
+		CumulativeGasUsed: types.HexUint64(r.CumulativeGasUsed),
+		Bloom:             r.Bloom,
+		Logs:              make([]*Log, len(r.Logs)),
+		TxHash:            r.TxHash,
+		GasUsed:           types.HexUint64(r.GasUsed),
+		EffectiveGasPrice: types.NewHexBig(r.EffectiveGasPrice),
+		BlobGasUsed:       types.HexUint64(r.BlobGasUsed),
+		BlockHash:         blockHash,
+		BlockNumber:       types.NewHexBig(new(big.Int).SetUint64(number)),
+		TransactionIndex:  types.HexUint64(r.TransactionIndex),
+	}
+	if r.ContractAddress != nil {
+		receipt.ContractAddress = *r.ContractAddress
+	}
+	if r.BlobGasPrice != nil {
+		receipt.BlobGasPrice = types.NewHexBig(r.BlobGasPrice)
+	}
+	for i, log := range r.Logs {
+		receipt.Logs[i] = &Log{
+			Address:     log.Address,
+			Topics:      log.Topics,
+			Data:        log.Data,
+			BlockNumber: types.HexUint64(log.BlockNumber),
+			TxHash:      log.TxHash,
+			TxIndex:     types.HexUint64(log.TxIndex),
+			BlockHash:   blockHash,
+			Index:       types.HexUint64(log.Index),
+		}
+	}
+	return receipt
+}
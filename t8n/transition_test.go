@@ -0,0 +1,80 @@
+package t8n
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/morelucks/minievm/tests"
+)
+
+func readJSON(t *testing.T, path string, v any) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+}
+
+// testdata/cancun holds the inputs of a Cancun transition: a contract
+// creation using CREATE2, transient storage and MCOPY, a call that logs
+// and self-destructs, and a transaction with a future nonce. result.json
+// is what geth's evm t8n writes for them.
+func TestApply(t *testing.T) {
+	dir := filepath.Join("testdata", "cancun")
+	pre := new(Prestate)
+	readJSON(t, filepath.Join(dir, "alloc.json"), &pre.Pre)
+	readJSON(t, filepath.Join(dir, "env.json"), &pre.Env)
+	var raw []*Transaction
+	readJSON(t, filepath.Join(dir, "txs.json"), &raw)
+	var want ExecutionResult
+	readJSON(t, filepath.Join(dir, "result.json"), &want)
+
+	config, err := tests.NetworkConfig("Cancun")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.ChainID = big.NewInt(1)
+	txs := make([]Tx, len(raw))
+	for i, tx := range raw {
+		if txs[i].Tx, err = tx.ToTransaction(config.ChainID); err != nil {
+			t.Fatalf("tx %d: %v", i, err)
+		}
+	}
+	_, got, _, err := pre.Apply(config, txs, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.StateRoot != want.StateRoot {
+		t.Errorf("state root %x, want %x", got.StateRoot, want.StateRoot)
+	}
+	if got.TxRoot != want.TxRoot {
+		t.Errorf("tx root %x, want %x", got.TxRoot, want.TxRoot)
+	}
+	if got.ReceiptsRoot != want.ReceiptsRoot {
+		t.Errorf("receipts root %x, want %x", got.ReceiptsRoot, want.ReceiptsRoot)
+	}
+	if got.LogsHash != want.LogsHash {
+		t.Errorf("logs hash %x, want %x", got.LogsHash, want.LogsHash)
+	}
+	if got.GasUsed != want.GasUsed {
+		t.Errorf("gas used %d, want %d", got.GasUsed, want.GasUsed)
+	}
+	if len(got.Receipts) != len(want.Receipts) {
+		t.Fatalf("%d receipts, want %d", len(got.Receipts), len(want.Receipts))
+	}
+	for i, r := range got.Receipts {
+		if r.ContractAddress != want.Receipts[i].ContractAddress {
+			t.Errorf("receipt %d contract address %x, want %x", i, r.ContractAddress, want.Receipts[i].ContractAddress)
+		}
+	}
+	if len(got.Rejected) != len(want.Rejected) || len(got.Rejected) > 0 && got.Rejected[0].Index != want.Rejected[0].Index {
+		t.Errorf("rejected %v, want %v", got.Rejected, want.Rejected)
+	}
+}
@@ -0,0 +1,327 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	types "github.com/morelucks/minievm/typess"
+)
+
+// BlockTest is a BlockchainTests fixture: a genesis block and pre state,
+// RLP encoded blocks to import, some of which must be rejected, and the
+// expected post state and head of the chain
+type BlockTest struct {
+	Network       string             `json:"network"`
+	Genesis       BlockTestHeader    `json:"genesisBlockHeader"`
+	Blocks        []BlockTestBlock   `json:"blocks"`
+	Pre           types.GenesisAlloc `json:"pre"`
+	Post          types.GenesisAlloc `json:"postState"`
+	PostStateHash *types.Word        `json:"postStateHash"` // Given instead of the post state by large tests
+	LastBlockHash types.Word         `json:"lastblockhash"`
+	Config        *StateConfig       `json:"config"` // execution-spec-tests only
+}
+
+// BlockTestBlock is a block to import. Blocks without a header are invalid
+// and must be rejected for the reason in ExpectException.
+type BlockTestBlock struct {
+	RLP             types.HexBytes   `json:"rlp"`
+	BlockHeader     *BlockTestHeader `json:"blockHeader"`
+	ExpectException string           `json:"expectException"`
+}
+
+// BlockTestHeader is a header in the JSON form of the fixtures
+type BlockTestHeader struct {
+	ParentHash            types.Word       `json:"parentHash"`
+	UncleHash             types.Word       `json:"uncleHash"`
+	Coinbase              types.Address    `json:"coinbase"`
+	StateRoot             types.Word       `json:"stateRoot"`
+	TransactionsTrie      types.Word       `json:"transactionsTrie"`
+	ReceiptTrie           types.Word       `json:"receiptTrie"`
+	Bloom                 types.Bloom      `json:"bloom"`
+	Difficulty            *types.HexBig    `json:"difficulty"`
+	Number                types.HexUint64  `json:"number"`
+	GasLimit              types.HexUint64  `json:"gasLimit"`
+	GasUsed               types.HexUint64  `json:"gasUsed"`
+	Timestamp             types.HexUint64  `json:"timestamp"`
+	ExtraData             types.HexBytes   `json:"extraData"`
+	MixHash               types.Word       `json:"mixHash"`
+	Nonce                 types.HexBytes   `json:"nonce"`
+	BaseFeePerGas         *types.HexBig    `json:"baseFeePerGas"`
+	WithdrawalsRoot       *types.Word      `json:"withdrawalsRoot"`
+	BlobGasUsed           *types.HexUint64 `json:"blobGasUsed"`
+	ExcessBlobGas         *types.HexUint64 `json:"excessBlobGas"`
+	ParentBeaconBlockRoot *types.Word      `json:"parentBeaconBlockRoot"`
+	RequestsHash          *types.Word      `json:"requestsHash"`
+	Hash                  types.Word       `json:"hash"`
+}
+
+// LoadBlockTests reads a fixture file, a JSON object of tests by name
+func LoadBlockTests(path string) (map[string]*BlockTest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var tests map[string]*BlockTest
+	if err := json.Unmarshal(data, &tests); err != nil {
+		return nil, fmt.Errorf("invalid blockchain test file %s: %w", path, err)
+	}
+	return tests, nil
+}
+
+// ToHeader converts the header to the form it is hashed in
+func (h *BlockTestHeader) ToHeader() *types.Header {
+	header := &types.Header{
+		ParentHash:       h.ParentHash,
+		UncleHash:        h.UncleHash,
+		Coinbase:         h.Coinbase,
+		Root:             h.StateRoot,
+		TxHash:           h.TransactionsTrie,
+		ReceiptHash:      h.ReceiptTrie,
+		Bloom:            h.Bloom,
+		Difficulty:       new(big.Int),
+		Number:           uint64(h.Number),
+		GasLimit:         uint64(h.GasLimit),
+		GasUsed:          uint64(h.GasUsed),
+		Time:             uint64(h.Timestamp),
+		Extra:            h.ExtraData,
+		MixDigest:        h.MixHash,
+		WithdrawalsHash:  h.WithdrawalsRoot,
+		ParentBeaconRoot: h.ParentBeaconBlockRoot,
+		RequestsHash:     h.RequestsHash,
+	}
+	copy(header.Nonce[len(header.Nonce)-min(len(h.Nonce), len(header.Nonce)):], h.Nonce)
+	if h.Difficulty != nil {
+		header.Difficulty = h.Difficulty.ToInt()
+	}
+	if h.BaseFeePerGas != nil {
+		header.BaseFee = h.BaseFeePerGas.ToInt()
+	}
+	if h.BlobGasUsed != nil {
+		used := uint64(*h.BlobGasUsed)
+		header.BlobGasUsed = &used
+	}
+	if h.ExcessBlobGas != nil {
+		excess := uint64(*h.ExcessBlobGas)
+		header.ExcessBlobGas = &excess
+	}
+	return header
+}
+
+// chainBlock is an imported block with the state after it
+type chainBlock struct {
+	header *types.Header
+	state  *types.StateDB
+	td     *big.Int // Total difficulty, which picks the head of proof-of-work chains
+}
+
+// blockChain is the tree of blocks a test imports, rooted at the genesis
+type blockChain struct {
+	config  *types.ChainConfig
+	chainID *big.Int
+	blocks  map[types.Word]*chainBlock
+	head    *chainBlock
+}
+
+// Run imports the blocks of the test, checking that the valid ones are
+// accepted with the expected hash and the invalid ones rejected, then
+// compares the post state and the hash of the head block
+func (t *BlockTest) Run() error {
+	config, err := NetworkConfig(t.Network)
+	if err != nil {
+		return err
+	}
+	genesis := t.Genesis.ToHeader()
+	state := t.Pre.State()
+	if root := state.Root(); root != genesis.Root {
+		return fmt.Errorf("genesis state root mismatch: got %x, want %x", root, genesis.Root)
+	}
+	if hash := genesis.Hash(); hash != t.Genesis.Hash {
+		return fmt.Errorf("genesis hash mismatch: got %x, want %x", hash, t.Genesis.Hash)
+	}
+
+	chain := &blockChain{config: config, chainID: big.NewInt(1), blocks: make(map[types.Word]*chainBlock)}
+	if t.Config != nil && t.Config.ChainID != nil {
+		chain.chainID = t.Config.ChainID.ToInt()
+	}
+	chain.head = &chainBlock{header: genesis, state: state, td: new(big.Int).Set(genesis.Difficulty)}
+	chain.blocks[t.Genesis.Hash] = chain.head
+
+	for i, b := range t.Blocks {
+		hash, err := chain.insert(b.RLP)
+		switch {
+		case b.BlockHeader == nil && err == nil:
+			return fmt.Errorf("block %d was imported, but should have been rejected: %s", i, b.ExpectException)
+		case b.BlockHeader == nil:
+			// Rejected as expected
+		case err != nil:
+			return fmt.Errorf("block %d: %w", i, err)
+		case hash != b.BlockHeader.Hash:
+			return fmt.Errorf("block %d hash mismatch: got %x, want %x", i, hash, b.BlockHeader.Hash)
+		}
+	}
+
+	head := chain.head
+	if hash := head.header.Hash(); hash != t.LastBlockHash {
+		return fmt.Errorf("last block hash mismatch: got %x, want %x", hash, t.LastBlockHash)
+	}
+	if t.Post != nil {
+		if err := checkPostState(head.state, t.Post); err != nil {
+			return fmt.Errorf("post state: %w", err)
+		}
+	}
+	if t.PostStateHash != nil && head.header.Root != *t.PostStateHash {
+		return fmt.Errorf("post state root mismatch: got %x, want %x", head.header.Root, *t.PostStateHash)
+	}
+	return nil
+}
+
+// insert decodes, validates and executes a block on top of its parent and
+// returns its hash. The block becomes the head if it is a proof-of-stake
+// block or has the most total difficulty.
+func (c *blockChain) insert(raw []byte) (types.Word, error) {
+	block, err := types.DecodeBlock(raw)
+	if err != nil {
+		return types.Word{}, err
+	}
+	header := block.Header
+	parent, ok := c.blocks[header.ParentHash]
+	if !ok {
+		return types.Word{}, fmt.Errorf("%w: parent 0x%x", types.ErrUnknownAncestor, header.ParentHash)
+	}
+	fork := c.config.Fork(header.Number, header.Time)
+	if err := types.VerifyHeader(parent.header, header, fork); err != nil {
+		return types.Word{}, err
+	}
+	if err := block.ValidateBody(); err != nil {
+		return types.Word{}, err
+	}
+	state := parent.state.Copy()
+	if _, err := types.ProcessBlock(state, header, block.Transactions, block.Withdrawals, fork, c.chainID, c.getHash(parent)); err != nil {
+		return types.Word{}, err
+	}
+
+	hash := block.Hash()
+	imported := &chainBlock{header: header, state: state, td: new(big.Int).Add(parent.td, header.Difficulty)}
+	c.blocks[hash] = imported
+	if header.Difficulty.Sign() == 0 || imported.td.Cmp(c.head.td) > 0 {
+		c.head = imported
+	}
+	return hash, nil
+}
+
+// getHash returns the BLOCKHASH lookup of the children of parent, which
+// follows the chain back from parent
+func (c *blockChain) getHash(parent *chainBlock) func(uint64) types.Word {
+	return func(number uint64) types.Word {
+		for block := parent; block != nil; block = c.blocks[block.header.ParentHash] {
+			if block.header.Number == number {
+				return block.header.Hash()
+			}
+			if block.header.Number < number {
+				break
+			}
+		}
+		return types.Word{}
+	}
+}
+
+// checkPostState compares the state with the expected accounts and reports
+// the first difference
+func checkPostState(state *types.StateDB, post types.GenesisAlloc) error {
+	want := post.State()
+	if state.Root() == want.Root() {
+		return nil
+	}
+	for addr, acc := range want.Accounts {
+		switch {
+		case !state.Exist(addr):
+			return fmt.Errorf("account %x missing", addr)
+		case state.GetBalance(addr).Cmp(acc.Balance) != 0:
+			return fmt.Errorf("account %x balance: got %v, want %v", addr, state.GetBalance(addr), acc.Balance)
+		case state.GetNonce(addr) != acc.Nonce:
+			return fmt.Errorf("account %x nonce: got %d, want %d", addr, state.GetNonce(addr), acc.Nonce)
+		case !bytes.Equal(state.GetCode(addr), acc.Code):
+			return fmt.Errorf("account %x code: got %x, want %x", addr, state.GetCode(addr), acc.Code)
+		case state.StorageRoot(addr) != want.StorageRoot(addr):
+			return fmt.Errorf("account %x storage root: got %x, want %x", addr, state.StorageRoot(addr), want.StorageRoot(addr))
+		}
+	}
+	for addr := range state.Accounts {
+		if !want.Exist(addr) {
+			return fmt.Errorf("unexpected account %x", addr)
+		}
+	}
+	return fmt.Errorf("root mismatch: got %x, want %x", state.Root(), want.Root())
+}
+
+// transitionNetwork matches the names of fork transition networks, such as
+// ShanghaiToCancunAtTime15k or BerlinToLondonAt5
+var transitionNetwork = regexp.MustCompile(`^([A-Za-z]+)To([A-Za-z]+)At(Time)?(\d+)(k?)$`)
+
+// NetworkConfig returns the chain config of a test network: a fork active
+// from genesis, or a fork followed by another activating at a block number
+// or timestamp. Networks starting at Paris or later are proof-of-stake
+// from genesis.
+func NetworkConfig(network string) (*types.ChainConfig, error) {
+	match := transitionNetwork.FindStringSubmatch(network)
+	if match == nil {
+		fork, err := ParseTestFork(network)
+		if err != nil {
+			return nil, err
+		}
+		return forkConfig(network, fork, fork, 0), nil
+	}
+	from, err := ParseTestFork(match[1])
+	if err != nil {
+		return nil, err
+	}
+	to, err := ParseTestFork(match[2])
+	if err != nil {
+		return nil, err
+	}
+	at, err := strconv.ParseUint(match[4], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFork, network)
+	}
+	if match[5] == "k" {
+		at *= 1000
+	}
+	// Forks before Shanghai activate at block numbers, later ones at timestamps
+	if byTime := match[3] != ""; byTime != to.IsActive(types.Shanghai) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFork, network)
+	}
+	return forkConfig(match[1], from, to, at), nil
+}
+
+// forkConfig returns a config where the forks up to from, named fromName,
+// are active from genesis and those after it up to to activate at at
+func forkConfig(fromName string, from, to types.Fork, at uint64) *types.ChainConfig {
+	config := &types.ChainConfig{ChainID: big.NewInt(1)}
+	if from.IsActive(types.Shanghai) || strings.EqualFold(fromName, "Paris") || strings.EqualFold(fromName, "Merge") {
+		config.TerminalTotalDifficulty = new(big.Int)
+	}
+	activations := map[types.Fork]**uint64{
+		types.Istanbul: &config.IstanbulBlock,
+		types.Berlin:   &config.BerlinBlock,
+		types.London:   &config.LondonBlock,
+		types.Shanghai: &config.ShanghaiTime,
+		types.Cancun:   &config.CancunTime,
+		types.Prague:   &config.PragueTime,
+		types.Osaka:    &config.OsakaTime,
+	}
+	for fork, activation := range activations {
+		switch {
+		case fork <= from:
+			*activation = new(uint64)
+		case fork <= to:
+			*activation = &at
+		}
+	}
+	return config
+}
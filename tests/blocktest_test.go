@@ -0,0 +1,33 @@
+package tests
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// The chains in testdata/block were built with geth's chain maker, and
+// geth's evm blocktest passes them. They hold deposits, withdrawal and
+// consolidation requests, blob transactions, a contract creation and
+// blocks the tests expect to be rejected.
+func TestBlockTests(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "block", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no blockchain test fixtures")
+	}
+	for _, file := range files {
+		blockTests, err := LoadBlockTests(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, test := range blockTests {
+			t.Run(name, func(t *testing.T) {
+				if err := test.Run(); err != nil {
+					t.Error(err)
+				}
+			})
+		}
+	}
+}
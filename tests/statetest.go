@@ -0,0 +1,353 @@
+// Package tests runs the JSON test fixtures of ethereum/tests and
+// execution-spec-tests against the VM.
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/morelucks/minievm/crypto"
+	"github.com/morelucks/minievm/rlp"
+	types "github.com/morelucks/minievm/typess"
+)
+
+// ErrUnsupportedFork is returned for subtests of forks the VM does not implement
+var ErrUnsupportedFork = errors.New("unsupported fork")
+
+// StateTest is a GeneralStateTests fixture: a pre state, the block
+// environment, a transaction with lists of data, gas limits and values, and
+// per fork the expected post states of the combinations in use
+type StateTest struct {
+	Env    StateEnv               `json:"env"`
+	Pre    types.GenesisAlloc     `json:"pre"`
+	Tx     StateTransaction       `json:"transaction"`
+	Post   map[string][]StatePost `json:"post"`
+	Config *StateConfig           `json:"config"`
+}
+
+// StateConfig is the chain config execution-spec-tests fixtures add
+type StateConfig struct {
+	ChainID *types.HexBig `json:"chainid"`
+}
+
+// StateEnv is the block a state test's transaction executes in
+type StateEnv struct {
+	Coinbase      types.Address    `json:"currentCoinbase"`
+	Difficulty    *types.HexBig    `json:"currentDifficulty"`
+	Random        *types.Word      `json:"currentRandom"` // Makes the block post-merge
+	GasLimit      types.HexUint64  `json:"currentGasLimit"`
+	Number        types.HexUint64  `json:"currentNumber"`
+	Timestamp     types.HexUint64  `json:"currentTimestamp"`
+	BaseFee       *types.HexBig    `json:"currentBaseFee"`
+	ExcessBlobGas *types.HexUint64 `json:"currentExcessBlobGas"`
+}
+
+// StateTransaction is the transaction of a state test. Data, GasLimit and
+// Value list the alternatives the post states pick from by index; the
+// access lists go with the data.
+type StateTransaction struct {
+	Nonce                *types.HexBig        `json:"nonce"`
+	GasPrice             *types.HexBig        `json:"gasPrice"`
+	MaxFeePerGas         *types.HexBig        `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *types.HexBig        `json:"maxPriorityFeePerGas"`
+	To                   string               `json:"to"` // Empty for contract creation
+	Data                 []types.HexBytes     `json:"data"`
+	AccessLists          []*types.AccessList  `json:"accessLists"`
+	GasLimit             []types.HexUint64    `json:"gasLimit"`
+	Value                []string             `json:"value"`
+	SecretKey            types.HexBytes       `json:"secretKey"`
+	Sender               *types.Address       `json:"sender"`
+	BlobVersionedHashes  []types.Word         `json:"blobVersionedHashes"`
+	MaxFeePerBlobGas     *types.HexBig        `json:"maxFeePerBlobGas"`
+	AuthorizationList    []StateAuthorization `json:"authorizationList"`
+}
+
+// StateAuthorization is an EIP-7702 authorization of a state test
+type StateAuthorization struct {
+	ChainID *types.HexBig   `json:"chainId"`
+	Address types.Address   `json:"address"`
+	Nonce   types.HexUint64 `json:"nonce"`
+	V       types.HexUint64 `json:"v"`
+	R       *types.HexBig   `json:"r"`
+	S       *types.HexBig   `json:"s"`
+}
+
+// StatePost is an expected post state: the state root and the hash of the
+// logs, or the exception that makes the transaction invalid
+type StatePost struct {
+	Root            types.Word     `json:"hash"`
+	Logs            types.Word     `json:"logs"`
+	TxBytes         types.HexBytes `json:"txbytes"`
+	ExpectException string         `json:"expectException"`
+	Indexes         struct {
+		Data  int `json:"data"`
+		Gas   int `json:"gas"`
+		Value int `json:"value"`
+	} `json:"indexes"`
+}
+
+// StateSubtest selects one post state of a test
+type StateSubtest struct {
+	Fork  string
+	Index int
+}
+
+// LoadStateTests reads a fixture file, a JSON object of tests by name
+func LoadStateTests(path string) (map[string]*StateTest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var tests map[string]*StateTest
+	if err := json.Unmarshal(data, &tests); err != nil {
+		return nil, fmt.Errorf("invalid state test file %s: %w", path, err)
+	}
+	return tests, nil
+}
+
+// Subtests returns the post states of the test ordered by fork and index
+func (t *StateTest) Subtests() []StateSubtest {
+	var subtests []StateSubtest
+	for fork, posts := range t.Post {
+		for i := range posts {
+			subtests = append(subtests, StateSubtest{fork, i})
+		}
+	}
+	sort.Slice(subtests, func(i, j int) bool {
+		if subtests[i].Fork != subtests[j].Fork {
+			return subtests[i].Fork < subtests[j].Fork
+		}
+		return subtests[i].Index < subtests[j].Index
+	})
+	return subtests
+}
+
+// Run executes a subtest and checks the post state root and logs hash, or
+// that the transaction is rejected if the subtest expects an exception. It
+// returns the post state root. A non-nil tracer traces the execution.
+func (t *StateTest) Run(subtest StateSubtest, tracer types.Tracer) (types.Word, error) {
+	fork, err := ParseTestFork(subtest.Fork)
+	if err != nil {
+		return types.Word{}, err
+	}
+	posts := t.Post[subtest.Fork]
+	if subtest.Index < 0 || subtest.Index >= len(posts) {
+		return types.Word{}, fmt.Errorf("no post state %s/%d", subtest.Fork, subtest.Index)
+	}
+	post := posts[subtest.Index]
+
+	state := t.Pre.State()
+	block := t.blockContext(fork)
+	block.Tracer = tracer
+	result, err := t.apply(state, block, post, fork)
+	switch {
+	case err != nil && post.ExpectException == "":
+		return state.Root(), fmt.Errorf("unexpected error: %w", err)
+	case err != nil:
+		// Rejected as expected; the state is unchanged
+		return state.Root(), nil
+	case post.ExpectException != "":
+		return state.Root(), fmt.Errorf("expected error %q, got no error", post.ExpectException)
+	}
+
+	// The coinbase is touched even when it earns nothing, so an empty
+	// coinbase is removed (EIP-161)
+	state.AddBalance(block.Coinbase, new(big.Int))
+	state.Finalise()
+	root := state.Root()
+	if root != post.Root {
+		return root, fmt.Errorf("post state root mismatch: got %x, want %x", root, post.Root)
+	}
+	if logs := LogsHash(result.Logs); logs != post.Logs {
+		return root, fmt.Errorf("post state logs hash mismatch: got %x, want %x", logs, post.Logs)
+	}
+	return root, nil
+}
+
+// apply checks the transaction of post and applies it to state
+func (t *StateTest) apply(state *types.StateDB, block *types.BlockContext, post StatePost, fork types.Fork) (*types.ExecutionResult, error) {
+	msg, err := t.Tx.toMessage(post, fork)
+	if err != nil {
+		return nil, err
+	}
+	// Blocks bound the number of blobs, which state tests check here
+	if cfg := types.BlobParams(fork); cfg != nil && len(msg.BlobHashes) > cfg.Max {
+		return nil, fmt.Errorf("%w: %d blobs, max %d per block", types.ErrTooManyBlobs, len(msg.BlobHashes), cfg.Max)
+	}
+	if len(post.TxBytes) > 0 {
+		tx, err := types.DecodeTransaction(post.TxBytes)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.Sender(); err != nil {
+			return nil, err
+		}
+	}
+	return types.ApplyMessage(state, block, msg, fork)
+}
+
+// blockContext returns the block of the env. Tests before London have no
+// base fee and those from London with a random value are post-merge.
+func (t *StateTest) blockContext(fork types.Fork) *types.BlockContext {
+	env := t.Env
+	block := &types.BlockContext{
+		Coinbase:   env.Coinbase,
+		Number:     uint64(env.Number),
+		Time:       uint64(env.Timestamp),
+		GasLimit:   uint64(env.GasLimit),
+		ChainID:    big.NewInt(1),
+		Difficulty: new(big.Int),
+		GetHash:    testBlockHash,
+	}
+	if t.Config != nil && t.Config.ChainID != nil {
+		block.ChainID = t.Config.ChainID.ToInt()
+	}
+	if env.Difficulty != nil {
+		block.Difficulty = env.Difficulty.ToInt()
+	}
+	if fork.IsActive(types.London) {
+		// Retesteth's genesis base fee 0x10 falls to 0x0a in the next block
+		block.BaseFee = big.NewInt(0x0a)
+		if env.BaseFee != nil {
+			block.BaseFee = env.BaseFee.ToInt()
+		}
+		if env.Random != nil {
+			random := *env.Random
+			block.Random, block.Difficulty = &random, new(big.Int)
+		}
+	}
+	var excessBlobGas uint64
+	if env.ExcessBlobGas != nil {
+		excessBlobGas = uint64(*env.ExcessBlobGas)
+	}
+	block.BlobBaseFee = types.CalcBlobFee(excessBlobGas, fork)
+	return block
+}
+
+// toMessage builds the message of the data, gas and value the post state
+// selects
+func (tx *StateTransaction) toMessage(post StatePost, fork types.Fork) (*types.Message, error) {
+	idx := post.Indexes
+	switch {
+	case idx.Data < 0 || idx.Data >= len(tx.Data):
+		return nil, fmt.Errorf("tx data index %d out of bounds", idx.Data)
+	case idx.Gas < 0 || idx.Gas >= len(tx.GasLimit):
+		return nil, fmt.Errorf("tx gas limit index %d out of bounds", idx.Gas)
+	case idx.Value < 0 || idx.Value >= len(tx.Value):
+		return nil, fmt.Errorf("tx value index %d out of bounds", idx.Value)
+	}
+
+	msg := &types.Message{
+		Value:      new(big.Int),
+		GasLimit:   uint64(tx.GasLimit[idx.Gas]),
+		Data:       tx.Data[idx.Data],
+		BlobHashes: tx.BlobVersionedHashes,
+	}
+	if tx.Nonce != nil {
+		// Nonces beyond 64 bits can not be encoded (EIP-2681)
+		if !tx.Nonce.ToInt().IsUint64() {
+			return nil, fmt.Errorf("nonce %v exceeds 2^64-1", tx.Nonce.ToInt())
+		}
+		msg.Nonce = tx.Nonce.ToInt().Uint64()
+	}
+	switch {
+	case tx.Sender != nil:
+		msg.From = *tx.Sender
+	case len(tx.SecretKey) > 0:
+		pub, err := crypto.PubkeyFromPrivate(tx.SecretKey)
+		if err != nil {
+			return nil, err
+		}
+		msg.From = crypto.PubkeyToAddress(pub)
+	}
+	if tx.To != "" {
+		to, err := types.HexToAddress(tx.To)
+		if err != nil {
+			return nil, fmt.Errorf("invalid to address: %w", err)
+		}
+		msg.To = &to
+	}
+	// Value hex encoding is messy: "0x" means zero
+	if value := tx.Value[idx.Value]; value != "0x" {
+		var v types.HexBig
+		if err := v.UnmarshalText([]byte(value)); err != nil {
+			return nil, fmt.Errorf("invalid tx value: %w", err)
+		}
+		msg.Value = v.ToInt()
+	}
+	if idx.Data < len(tx.AccessLists) && tx.AccessLists[idx.Data] != nil {
+		msg.AccessList = *tx.AccessLists[idx.Data]
+	}
+
+	if tx.GasPrice != nil {
+		msg.GasPrice = tx.GasPrice.ToInt()
+	}
+	if tx.MaxFeePerGas != nil {
+		msg.GasFeeCap = tx.MaxFeePerGas.ToInt()
+		msg.GasTipCap = msg.GasFeeCap
+	}
+	if tx.MaxPriorityFeePerGas != nil {
+		msg.GasTipCap = tx.MaxPriorityFeePerGas.ToInt()
+	}
+	if msg.GasPrice == nil && (msg.GasFeeCap == nil || !fork.IsActive(types.London)) {
+		return nil, errors.New("no gas price provided")
+	}
+	if tx.MaxFeePerBlobGas != nil {
+		msg.BlobFeeCap = tx.MaxFeePerBlobGas.ToInt()
+	}
+
+	if tx.AuthorizationList != nil {
+		msg.AuthList = make([]types.SetCodeAuthorization, len(tx.AuthorizationList))
+		for i, auth := range tx.AuthorizationList {
+			if auth.ChainID == nil || auth.R == nil || auth.S == nil || auth.V > 0xff {
+				return nil, fmt.Errorf("invalid authorization %d", i)
+			}
+			msg.AuthList[i] = types.SetCodeAuthorization{
+				ChainID: auth.ChainID.ToInt(),
+				Address: auth.Address,
+				Nonce:   uint64(auth.Nonce),
+				V:       uint8(auth.V),
+				R:       auth.R.ToInt(),
+				S:       auth.S.ToInt(),
+			}
+		}
+	}
+	return msg, nil
+}
+
+// LogsHash returns the hash of the logs state tests compare,
+// keccak256(rlp(logs))
+func LogsHash(logs []*types.Log) types.Word {
+	encoded, err := rlp.EncodeToBytes(logs)
+	if err != nil {
+		panic(err)
+	}
+	return types.Word(crypto.Keccak256(encoded))
+}
+
+// testBlockHash is the hash BLOCKHASH returns in tests,
+// keccak256 of the decimal block number
+func testBlockHash(number uint64) types.Word {
+	return types.Word(crypto.Keccak256([]byte(strconv.FormatUint(number, 10))))
+}
+
+// ParseTestFork looks up the fork of a fixture. Paris (the merge) runs as
+// London: it only turns DIFFICULTY into PREVRANDAO, which follows from the
+// block having a random value. Forks with extra EIPs such as
+// "London+3855" are not supported.
+func ParseTestFork(name string) (types.Fork, error) {
+	if strings.EqualFold(name, "Paris") || strings.EqualFold(name, "Merge") {
+		return types.London, nil
+	}
+	fork, ok := types.ParseFork(name)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUnsupportedFork, name)
+	}
+	return fork, nil
+}
@@ -0,0 +1,33 @@
+package tests
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// The fixtures in testdata/state were filled with geth's evm statetest,
+// which passes them too
+func TestStateTests(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "state", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no state test fixtures")
+	}
+	for _, file := range files {
+		stateTests, err := LoadStateTests(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, test := range stateTests {
+			for _, subtest := range test.Subtests() {
+				t.Run(name+"/"+subtest.Fork, func(t *testing.T) {
+					if _, err := test.Run(subtest, nil); err != nil {
+						t.Error(err)
+					}
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	types "github.com/morelucks/minievm/typess"
+)
+
+// The traces in testdata/trace were written by geth's evm statetest
+// --trace for each subtest of the fixtures in testdata/state. Error
+// messages differ between clients, so only their presence is compared.
+func TestTraces(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "state", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		stateTests, err := LoadStateTests(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, test := range stateTests {
+			for _, subtest := range test.Subtests() {
+				id := fmt.Sprintf("%s-%s-%d", name, subtest.Fork, subtest.Index)
+				t.Run(id, func(t *testing.T) {
+					want := readTrace(t, filepath.Join("testdata", "trace", id+".jsonl"))
+					var buf bytes.Buffer
+					if _, err := test.Run(subtest, types.NewJSONTracer(&buf)); err != nil {
+						t.Fatal(err)
+					}
+					got := parseTrace(t, buf.Bytes())
+					for i := range max(len(got), len(want)) {
+						if i >= len(got) || i >= len(want) {
+							t.Fatalf("%d lines, want %d", len(got), len(want))
+						}
+						if !reflect.DeepEqual(got[i], want[i]) {
+							t.Fatalf("line %d\n got %v\nwant %v", i+1, got[i], want[i])
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+func readTrace(t *testing.T, path string) []map[string]any {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parseTrace(t, data)
+}
+
+// parseTrace decodes the lines of a trace, replacing error messages by true
+func parseTrace(t *testing.T, data []byte) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var line map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("line %d: %v", len(lines)+1, err)
+		}
+		if _, ok := line["error"]; ok {
+			line["error"] = true
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return lines
+}
@@ -0,0 +1,70 @@
+package trie
+
+// Keys are handled in three forms (Yellow Paper appendix C):
+//
+//   - keybytes: the raw key as given by the caller
+//   - hex: one nibble per byte, followed by the terminator 16 if the key
+//     leads to a value; used inside the trie
+//   - compact: hex-prefix encoding, two nibbles per byte with a flag
+//     nibble recording the parity and whether the node is a leaf; used
+//     when nodes are encoded
+
+const terminator = 16
+
+// keybytesToHex splits key into nibbles and appends the terminator
+func keybytesToHex(key []byte) []byte {
+	nibbles := make([]byte, len(key)*2+1)
+	for i, b := range key {
+		nibbles[i*2] = b / 16
+		nibbles[i*2+1] = b % 16
+	}
+	nibbles[len(nibbles)-1] = terminator
+	return nibbles
+}
+
+// hexToCompact is the hex-prefix function HP: the first nibble is
+// 2*leaf + odd, followed by a padding nibble when the length is even
+func hexToCompact(hex []byte) []byte {
+	var flag byte
+	if hasTerm(hex) {
+		flag = 2
+		hex = hex[:len(hex)-1]
+	}
+	buf := make([]byte, len(hex)/2+1)
+	buf[0] = flag << 4
+	if len(hex)%2 == 1 {
+		buf[0] |= 1<<4 | hex[0]
+		hex = hex[1:]
+	}
+	for i := 0; i < len(hex); i += 2 {
+		buf[i/2+1] = hex[i]<<4 | hex[i+1]
+	}
+	return buf
+}
+
+// compactToHex reverses hexToCompact
+func compactToHex(compact []byte) []byte {
+	if len(compact) == 0 {
+		return nil
+	}
+	hex := keybytesToHex(compact)
+	// Drop the terminator unless the flag marks a leaf
+	if hex[0] < 2 {
+		hex = hex[:len(hex)-1]
+	}
+	// Drop the flag nibble, and the padding nibble for even lengths
+	return hex[2-hex[0]&1:]
+}
+
+// prefixLen returns the length of the common prefix of a and b
+func prefixLen(a, b []byte) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+func hasTerm(hex []byte) bool {
+	return len(hex) > 0 && hex[len(hex)-1] == terminator
+}
@@ -0,0 +1,120 @@
+package trie
+
+import (
+	"github.com/morelucks/minievm/crypto"
+	"github.com/morelucks/minievm/rlp"
+)
+
+// node is one of the node kinds of the trie. Nodes are never modified once
+// built: updates copy the nodes on the path to the changed key, so the
+// cached hashes of untouched subtries stay valid.
+type node interface{}
+
+type (
+	// fullNode is a branch with one child per nibble and an optional value
+	// for the key ending here
+	fullNode struct {
+		Children [17]node // Children[16] holds the value
+		hash     []byte
+	}
+	// shortNode is an extension when Val is another node, or a leaf when
+	// Key ends with the terminator and Val is a valueNode
+	shortNode struct {
+		Key  []byte // Hex key, shared by everything below
+		Val  node
+		hash []byte
+	}
+	// hashNode references a node by its hash, as found in proofs
+	hashNode []byte
+	// valueNode is a value stored in the trie
+	valueNode []byte
+)
+
+func (n *fullNode) copy() *fullNode {
+	cpy := *n
+	cpy.hash = nil
+	return &cpy
+}
+
+// encodeNode returns the RLP encoding of n, with children included by
+// reference (Yellow Paper appendix D, function c)
+func encodeNode(n node) []byte {
+	switch n := n.(type) {
+	case *shortNode:
+		payload := rlp.AppendBytes(nil, hexToCompact(n.Key))
+		payload = appendRef(payload, n.Val)
+		return rlp.AppendList(nil, payload)
+	case *fullNode:
+		var payload []byte
+		for _, child := range n.Children {
+			payload = appendRef(payload, child)
+		}
+		return rlp.AppendList(nil, payload)
+	case hashNode:
+		return rlp.EncodeBytes(n)
+	case valueNode:
+		return rlp.EncodeBytes(n)
+	}
+	panic("trie: invalid node")
+}
+
+// appendRef appends how a parent refers to n: nodes encoding to fewer than
+// 32 bytes are embedded, longer ones are replaced by their hash
+func appendRef(dst []byte, n node) []byte {
+	switch n := n.(type) {
+	case nil:
+		return append(dst, rlp.EmptyString...)
+	case valueNode, hashNode:
+		return append(dst, encodeNode(n)...)
+	}
+	if hash := cachedHash(n); hash != nil {
+		return rlp.AppendBytes(dst, hash)
+	}
+	enc := encodeNode(n)
+	if len(enc) < 32 {
+		return append(dst, enc...)
+	}
+	return rlp.AppendBytes(dst, storeHash(n, enc))
+}
+
+// hashNodeRoot returns the hash of n as a root: unlike children, the root
+// is hashed even when its encoding is short
+func hashNodeRoot(n node) []byte {
+	if n == nil {
+		return EmptyRoot[:]
+	}
+	if hash := cachedHash(n); hash != nil {
+		return hash
+	}
+	if h, ok := n.(hashNode); ok {
+		return h
+	}
+	enc := encodeNode(n)
+	if len(enc) < 32 {
+		h := crypto.Keccak256(enc)
+		return h[:]
+	}
+	return storeHash(n, enc)
+}
+
+func cachedHash(n node) []byte {
+	switch n := n.(type) {
+	case *shortNode:
+		return n.hash
+	case *fullNode:
+		return n.hash
+	}
+	return nil
+}
+
+// storeHash hashes enc and caches the result in n
+func storeHash(n node, enc []byte) []byte {
+	h := crypto.Keccak256(enc)
+	switch n := n.(type) {
+	case *shortNode:
+		n.hash = h[:]
+	case *fullNode:
+		n.hash = h[:]
+	}
+	return h[:]
+}
@@ -0,0 +1,173 @@
+package trie
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/morelucks/minievm/crypto"
+	"github.com/morelucks/minievm/rlp"
+)
+
+// Merkle proofs: the encoded nodes on the path from the root to a key, as
+// returned by eth_getProof. Nodes embedded in their parent are not listed
+// separately, since they are part of the parent's encoding.
+
+var ErrInvalidNode = errors.New("trie: invalid node encoding")
+
+// Prove returns the proof for key. For a missing key the proof ends where
+// the path leaves the trie, which proves the key's absence.
+func (t *Trie) Prove(key []byte) [][]byte {
+	var proof [][]byte
+	hex := keybytesToHex(key)
+	n := t.root
+	for depth := 0; n != nil; depth++ {
+		if _, ok := n.(valueNode); ok {
+			break
+		}
+		if enc := encodeNode(n); depth == 0 || len(enc) >= 32 {
+			proof = append(proof, enc)
+		}
+		switch cur := n.(type) {
+		case *shortNode:
+			if len(hex) < len(cur.Key) || prefixLen(hex, cur.Key) != len(cur.Key) {
+				return proof
+			}
+			n, hex = cur.Val, hex[len(cur.Key):]
+		case *fullNode:
+			n, hex = cur.Children[hex[0]], hex[1:]
+		}
+	}
+	return proof
+}
+
+// VerifyProof checks proof against root and returns the value stored at
+// key, or nil if the proof shows that key is absent
+func VerifyProof(root [32]byte, key []byte, proof [][]byte) ([]byte, error) {
+	if root == EmptyRoot && len(proof) == 0 {
+		return nil, nil
+	}
+	nodes := make(map[[32]byte][]byte, len(proof))
+	for _, enc := range proof {
+		nodes[crypto.Keccak256(enc)] = enc
+	}
+	hex := keybytesToHex(key)
+	want := root
+	for i := 0; ; i++ {
+		enc, ok := nodes[want]
+		if !ok {
+			return nil, fmt.Errorf("trie: proof node %d (hash %x) missing", i, want)
+		}
+		n, err := decodeNode(enc)
+		if err != nil {
+			return nil, fmt.Errorf("trie: bad proof node %d: %w", i, err)
+		}
+		rest, child := walk(n, hex)
+		switch child := child.(type) {
+		case nil:
+			return nil, nil
+		case hashNode:
+			hex, want = rest, [32]byte(child)
+		case valueNode:
+			return child, nil
+		}
+	}
+}
+
+// walk follows key down from n through embedded nodes, stopping at a
+// value, a missing child or a reference to another proof node
+func walk(n node, key []byte) ([]byte, node) {
+	for {
+		switch cur := n.(type) {
+		case *shortNode:
+			if len(key) < len(cur.Key) || prefixLen(key, cur.Key) != len(cur.Key) {
+				return nil, nil
+			}
+			n, key = cur.Val, key[len(cur.Key):]
+		case *fullNode:
+			if len(key) == 0 {
+				return nil, nil
+			}
+			n, key = cur.Children[key[0]], key[1:]
+		case hashNode:
+			return key, cur
+		case valueNode:
+			return nil, cur
+		case nil:
+			return nil, nil
+		}
+	}
+}
+
+// decodeNode parses an encoded short or full node
+func decodeNode(enc []byte) (node, error) {
+	payload, rest, err := rlp.SplitList(enc)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: trailing data", ErrInvalidNode)
+	}
+	count, err := rlp.CountValues(payload)
+	if err != nil {
+		return nil, err
+	}
+	switch count {
+	case 2:
+		compact, rest, err := rlp.SplitString(payload)
+		if err != nil {
+			return nil, err
+		}
+		key := compactToHex(compact)
+		if hasTerm(key) {
+			value, _, err := rlp.SplitString(rest)
+			if err != nil {
+				return nil, err
+			}
+			return &shortNode{Key: key, Val: valueNode(value)}, nil
+		}
+		child, _, err := decodeRef(rest)
+		if err != nil {
+			return nil, err
+		}
+		return &shortNode{Key: key, Val: child}, nil
+	case 17:
+		n := &fullNode{}
+		for i := 0; i < 16; i++ {
+			if n.Children[i], payload, err = decodeRef(payload); err != nil {
+				return nil, err
+			}
+		}
+		value, _, err := rlp.SplitString(payload)
+		if err != nil {
+			return nil, err
+		}
+		if len(value) > 0 {
+			n.Children[16] = valueNode(value)
+		}
+		return n, nil
+	}
+	return nil, fmt.Errorf("%w: %d list elements", ErrInvalidNode, count)
+}
+
+// decodeRef parses a child reference: an embedded node, a hash, or the
+// empty string for no child
+func decodeRef(buf []byte) (node, []byte, error) {
+	kind, content, rest, err := rlp.Split(buf)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch {
+	case kind == rlp.List:
+		size := len(buf) - len(rest)
+		if size >= 32 {
+			return nil, nil, fmt.Errorf("%w: embedded node of %d bytes", ErrInvalidNode, size)
+		}
+		n, err := decodeNode(buf[:size])
+		return n, rest, err
+	case kind == rlp.String && len(content) == 0:
+		return nil, rest, nil
+	case kind == rlp.String && len(content) == 32:
+		return hashNode(content), rest, nil
+	}
+	return nil, nil, fmt.Errorf("%w: reference of %d bytes", ErrInvalidNode, len(content))
+}
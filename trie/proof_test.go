@@ -0,0 +1,166 @@
+package trie
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/morelucks/minievm/crypto"
+)
+
+// proofTrie holds values long enough that some nodes are stored by hash
+func proofTrie() *Trie {
+	trie := New()
+	for _, kv := range [][2]string{
+		{"doe", "reindeer"}, {"dog", "puppy"}, {"dogglesworth", "cat"},
+		{"horse", strings.Repeat("stallion", 5)}, {"shaman", strings.Repeat("horse", 8)},
+	} {
+		trie.Update([]byte(kv[0]), []byte(kv[1]))
+	}
+	return trie
+}
+
+func TestProve(t *testing.T) {
+	// Proof nodes from go-ethereum for the same trie, in any order
+	var (
+		root   = "f851808080808080a0015b6a482e27fb9a37f71660e47a85049939f35f7f76cb20b5da44427700855ca00683925448ae3c3680640b1439fb1a3d28e0f54c760a81e65280505251997ba8808080808080808080"
+		branch = "f85180808080a0c7c49278da140bad76c3088680f10280ce96d50c5ca9218f3576dde1fd7dd7bf808080a0ec8f4d4c88260fafde612ec5c176cd93be9fc108e9e35ca372d2b1dbe9e600bc8080808080808080"
+		dogs   = "e48216f6a0db6ae1fda66890f6693f36560d36b4dca68b4d838f17016b151efe1d4c95c453"
+	)
+	tests := []struct {
+		key   string
+		value string
+		proof []string
+	}{
+		{
+			key:   "dog",
+			value: "puppy",
+			proof: []string{root, branch, dogs,
+				"e4808080808080ce89376c6573776f72746883636174808080808080808080857075707079",
+				"f83b8080808080ca20887265696e6465657280a037efd11993cb04a54048c25320e9f29c50a432d28afdf01598b2978ce1ca3068808080808080808080",
+			},
+		},
+		{
+			key:   "horse",
+			value: strings.Repeat("stallion", 5),
+			proof: []string{root, branch,
+				"ef85206f727365a87374616c6c696f6e7374616c6c696f6e7374616c6c696f6e7374616c6c696f6e7374616c6c696f6e",
+			},
+		},
+		{key: "dot", proof: []string{root, branch, dogs}},
+		{
+			key: "zebra",
+			proof: []string{root,
+				"f0863368616d616ea8686f727365686f727365686f727365686f727365686f727365686f727365686f727365686f727365",
+			},
+		},
+	}
+	trie := proofTrie()
+	if got := trie.Hash(); hex.EncodeToString(got[:]) != "074bc31a9cb48c814087bd5db56c7ff976e19fc6608100835848db4a0e60c902" {
+		t.Fatalf("root %x", got)
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			proof := trie.Prove([]byte(tt.key))
+			var got []string
+			for _, node := range proof {
+				got = append(got, hex.EncodeToString(node))
+			}
+			slices.Sort(got)
+			want := slices.Sorted(slices.Values(tt.proof))
+			if !slices.Equal(got, want) {
+				t.Errorf("proof\n got %v\nwant %v", got, want)
+			}
+			value, err := VerifyProof(trie.Hash(), []byte(tt.key), proof)
+			if err != nil {
+				t.Fatalf("verify: %v", err)
+			}
+			if tt.value == "" && value != nil || string(value) != tt.value {
+				t.Errorf("value %q, want %q", value, tt.value)
+			}
+		})
+	}
+}
+
+func TestProveMany(t *testing.T) {
+	trie := New()
+	for i := 0; i < 500; i++ {
+		trie.Update([]byte(fmt.Sprintf("key%d", i)), []byte(fmt.Sprintf("value%d", i)))
+	}
+	root := trie.Hash()
+	for i := 0; i < 600; i++ {
+		key := []byte(fmt.Sprintf("key%d", i))
+		value, err := VerifyProof(root, key, trie.Prove(key))
+		if err != nil {
+			t.Fatalf("%s: %v", key, err)
+		}
+		want := fmt.Sprintf("value%d", i)
+		if i >= 500 {
+			want = ""
+		}
+		if string(value) != want {
+			t.Fatalf("%s = %q, want %q", key, value, want)
+		}
+	}
+}
+
+func TestVerifyProofEmpty(t *testing.T) {
+	value, err := VerifyProof(EmptyRoot, []byte("dog"), New().Prove([]byte("dog")))
+	if value != nil || err != nil {
+		t.Errorf("got %q, %v, want nil, nil", value, err)
+	}
+}
+
+func TestVerifyProofErrors(t *testing.T) {
+	trie := proofTrie()
+	key := []byte("dog")
+	badNode := []byte{0xc3, 0x01, 0x02, 0x03} // A list of three values
+	tests := []struct {
+		name  string
+		root  func() [32]byte
+		proof func(proof [][]byte) [][]byte
+		err   error // nil for any failure
+	}{
+		{
+			name:  "wrongRoot",
+			root:  func() [32]byte { return [32]byte{1} },
+			proof: func(proof [][]byte) [][]byte { return proof },
+		},
+		{
+			name:  "missingNode",
+			proof: func(proof [][]byte) [][]byte { return proof[:len(proof)-1] },
+		},
+		{
+			name: "tamperedNode",
+			proof: func(proof [][]byte) [][]byte {
+				last := slices.Clone(proof[len(proof)-1])
+				last[len(last)-1] ^= 1
+				return append(proof[:len(proof)-1:len(proof)-1], last)
+			},
+		},
+		{
+			name:  "badNode",
+			root:  func() [32]byte { return crypto.Keccak256(badNode) },
+			proof: func([][]byte) [][]byte { return [][]byte{badNode} },
+			err:   ErrInvalidNode,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := trie.Hash()
+			if tt.root != nil {
+				root = tt.root()
+			}
+			_, err := VerifyProof(root, key, tt.proof(trie.Prove(key)))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("error %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+// Package trie implements the hexary Merkle Patricia Trie that commits to
+// the world state, account storage, transactions and receipts (Yellow
+// Paper appendix D).
+package trie
+
+import (
+	"github.com/morelucks/minievm/crypto"
+	"github.com/morelucks/minievm/rlp"
+)
+
+// EmptyRoot is the root hash of the empty trie, keccak256(rlp(""))
+var EmptyRoot = crypto.Keccak256(rlp.EmptyString)
+
+// Trie is an in-memory Merkle Patricia Trie. The zero value is an empty
+// trie ready to use.
+type Trie struct {
+	root node
+}
+
+// New returns an empty trie
+func New() *Trie {
+	return &Trie{}
+}
+
+// Get returns the value stored at key, or nil if there is none
+func (t *Trie) Get(key []byte) []byte {
+	n := t.root
+	hex := keybytesToHex(key)
+	for {
+		switch cur := n.(type) {
+		case nil:
+			return nil
+		case valueNode:
+			return append([]byte(nil), cur...)
+		case *shortNode:
+			if len(hex) < len(cur.Key) || prefixLen(hex, cur.Key) != len(cur.Key) {
+				return nil
+			}
+			n, hex = cur.Val, hex[len(cur.Key):]
+		case *fullNode:
+			n, hex = cur.Children[hex[0]], hex[1:]
+		default:
+			panic("trie: unexpected node in Get")
+		}
+	}
+}
+
+// Update stores value at key. An empty value deletes the key, since the
+// trie cannot tell an empty value from a missing one.
+func (t *Trie) Update(key, value []byte) {
+	if len(value) == 0 {
+		t.Delete(key)
+		return
+	}
+	t.root = insert(t.root, keybytesToHex(key), valueNode(append([]byte(nil), value...)))
+}
+
+// Delete removes key from the trie; missing keys are ignored
+func (t *Trie) Delete(key []byte) {
+	_, t.root = remove(t.root, keybytesToHex(key))
+}
+
+// Hash returns the root hash of the trie
+func (t *Trie) Hash() [32]byte {
+	return [32]byte(hashNodeRoot(t.root))
+}
+
+// insert returns n with value stored at the hex key
+func insert(n node, key []byte, value node) node {
+	if len(key) == 0 {
+		return value
+	}
+	switch n := n.(type) {
+	case nil:
+		return &shortNode{Key: key, Val: value}
+	case *shortNode:
+		matched := prefixLen(key, n.Key)
+		// The whole key of the node matches: insert below it
+		if matched == len(n.Key) {
+			return &shortNode{Key: n.Key, Val: insert(n.Val, key[matched:], value)}
+		}
+		// Otherwise branch at the first differing nibble
+		branch := &fullNode{}
+		branch.Children[n.Key[matched]] = insert(nil, n.Key[matched+1:], n.Val)
+		branch.Children[key[matched]] = insert(nil, key[matched+1:], value)
+		if matched == 0 {
+			return branch
+		}
+		return &shortNode{Key: key[:matched], Val: branch}
+	case *fullNode:
+		cpy := n.copy()
+		cpy.Children[key[0]] = insert(n.Children[key[0]], key[1:], value)
+		return cpy
+	}
+	panic("trie: unexpected node in insert")
+}
+
+// remove returns n without the hex key, collapsing nodes that are left
+// with a single child so the trie stays canonical. It reports whether the
+// key was found.
+func remove(n node, key []byte) (bool, node) {
+	switch n := n.(type) {
+	case nil:
+		return false, nil
+	case valueNode:
+		return true, nil
+	case *shortNode:
+		matched := prefixLen(key, n.Key)
+		if matched < len(n.Key) {
+			return false, n // key is not in the trie
+		}
+		if matched == len(key) {
+			return true, nil // the leaf itself
+		}
+		found, child := remove(n.Val, key[len(n.Key):])
+		if !found {
+			return false, n
+		}
+		switch child := child.(type) {
+		case nil:
+			return true, nil
+		case *shortNode:
+			// Merge the two keys instead of chaining short nodes
+			return true, &shortNode{Key: concat(n.Key, child.Key), Val: child.Val}
+		default:
+			return true, &shortNode{Key: n.Key, Val: child}
+		}
+	case *fullNode:
+		found, child := remove(n.Children[key[0]], key[1:])
+		if !found {
+			return false, n
+		}
+		cpy := n.copy()
+		cpy.Children[key[0]] = child
+		if child != nil {
+			return true, cpy
+		}
+		// Find out whether a single child is left
+		pos := -1
+		for i, c := range cpy.Children {
+			if c != nil {
+				if pos != -1 {
+					return true, cpy
+				}
+				pos = i
+			}
+		}
+		if pos == terminator {
+			// Only the value is left: it becomes a leaf with an empty key
+			return true, &shortNode{Key: []byte{terminator}, Val: cpy.Children[terminator]}
+		}
+		// A single child: fold the branch into a short node
+		if only, ok := cpy.Children[pos].(*shortNode); ok {
+			return true, &shortNode{Key: concat([]byte{byte(pos)}, only.Key), Val: only.Val}
+		}
+		return true, &shortNode{Key: []byte{byte(pos)}, Val: cpy.Children[pos]}
+	}
+	panic("trie: unexpected node in remove")
+}
+
+func concat(a, b []byte) []byte {
+	return append(append(make([]byte, 0, len(a)+len(b)), a...), b...)
+}
@@ -0,0 +1,66 @@
+package trie
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestEmptyRoot(t *testing.T) {
+	want := "56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421"
+	if got := New().Hash(); hex.EncodeToString(got[:]) != want {
+		t.Errorf("empty root %x, want %s", got, want)
+	}
+	if hex.EncodeToString(EmptyRoot[:]) != want {
+		t.Errorf("EmptyRoot %x, want %s", EmptyRoot, want)
+	}
+}
+
+func TestRoot(t *testing.T) {
+	tests := []struct {
+		name string
+		kvs  [][2]string // An empty value deletes the key
+		root string
+	}{
+		{
+			name: "dogs",
+			kvs:  [][2]string{{"doe", "reindeer"}, {"dog", "puppy"}, {"dogglesworth", "cat"}},
+			root: "8aad789dff2f538bca5d8ea56e8abe10f4c7ba3a5dea95fea4cd6e7c3a1168d3",
+		},
+		{
+			name: "single",
+			kvs:  [][2]string{{"A", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"}},
+			root: "d23786fb4a010da3ce639d66d5e904a11dbc02746d1ce25029e53290cabf28ab",
+		},
+		{
+			name: "deletes",
+			kvs: [][2]string{
+				{"do", "verb"}, {"ether", "wookiedoo"}, {"horse", "stallion"}, {"shaman", "horse"},
+				{"doge", "coin"}, {"ether", ""}, {"dog", "puppy"}, {"shaman", ""},
+			},
+			root: "5991bb8c6514148a29db676a14ac506cd2cd5775ace63c30a4fe457715e9ac84",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trie := New()
+			for _, kv := range tt.kvs {
+				trie.Update([]byte(kv[0]), []byte(kv[1]))
+			}
+			if got := trie.Hash(); hex.EncodeToString(got[:]) != tt.root {
+				t.Errorf("root %x, want %s", got, tt.root)
+			}
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	trie := New()
+	trie.Update([]byte("doe"), []byte("reindeer"))
+	trie.Update([]byte("dog"), []byte("puppy"))
+	if got := string(trie.Get([]byte("dog"))); got != "puppy" {
+		t.Errorf("dog = %q, want puppy", got)
+	}
+	if got := trie.Get([]byte("do")); got != nil {
+		t.Errorf("do = %q, want nil", got)
+	}
+}
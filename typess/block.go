@@ -0,0 +1,264 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/morelucks/minievm/crypto"
+	"github.com/morelucks/minievm/rlp"
+)
+
+// EmptyUncleHash is the ommers hash of a block without ommers, keccak256(rlp([]))
+var EmptyUncleHash = Word(crypto.Keccak256(rlp.EmptyList))
+
+// Header is a block header (Yellow Paper section 4.3). The optional fields
+// at the end were added by later forks and are nil before them.
+type Header struct {
+	ParentHash  Word     // H_p
+	UncleHash   Word     // H_o
+	Coinbase    Address  // H_c
+	Root        Word     // H_r - State root after the block
+	TxHash      Word     // H_t - Transactions root
+	ReceiptHash Word     // H_e - Receipts root
+	Bloom       Bloom    // H_b
+	Difficulty  *big.Int // H_d - Zero from the merge
+	Number      uint64   // H_i
+	GasLimit    uint64   // H_l
+	GasUsed     uint64   // H_g
+	Time        uint64   // H_s
+	Extra       []byte   // H_x
+	MixDigest   Word     // H_m - PREVRANDAO from the merge (EIP-4399)
+	Nonce       [8]byte  // H_n
+
+	BaseFee          *big.Int `rlp:"optional"` // H_f - EIP-1559 (London)
+	WithdrawalsHash  *Word    `rlp:"optional"` // EIP-4895 (Shanghai)
+	BlobGasUsed      *uint64  `rlp:"optional"` // EIP-4844 (Cancun)
+	ExcessBlobGas    *uint64  `rlp:"optional"` // EIP-4844 (Cancun)
+	ParentBeaconRoot *Word    `rlp:"optional"` // EIP-4788 (Cancun)
+	RequestsHash     *Word    `rlp:"optional"` // EIP-7685 (Prague)
+}
+
+// Hash returns the block hash, keccak256 of the RLP encoded header
+func (h *Header) Hash() Word {
+	return Word(crypto.Keccak256(mustEncode(h)))
+}
+
+// Context returns the block context transactions of the block execute in
+// under fork. getHash looks up the hashes of earlier blocks for BLOCKHASH
+// and may be nil.
+func (h *Header) Context(fork Fork, chainID *big.Int, getHash func(uint64) Word) *BlockContext {
+	block := &BlockContext{
+		Coinbase:   h.Coinbase,
+		Number:     h.Number,
+		Time:       h.Time,
+		GasLimit:   h.GasLimit,
+		BaseFee:    h.BaseFee,
+		ChainID:    chainID,
+		Difficulty: h.Difficulty,
+		GetHash:    getHash,
+	}
+	if h.ExcessBlobGas != nil {
+		block.BlobBaseFee = CalcBlobFee(*h.ExcessBlobGas, fork)
+	}
+	// After the merge the mix digest holds the beacon chain randomness
+	if h.Difficulty == nil || h.Difficulty.Sign() == 0 {
+		random := h.MixDigest
+		block.Random = &random
+	}
+	return block
+}
+
+// MaxExtraDataSize is the maximum length of the header's extra data
+const MaxExtraDataSize = 32
+
+// Errors for headers that do not follow their parent
+var (
+	ErrUnknownAncestor   = errors.New("unknown ancestor")
+	ErrInvalidNumber     = errors.New("invalid block number")
+	ErrOlderBlockTime    = errors.New("timestamp older than parent")
+	ErrExtraDataTooLong  = errors.New("extra-data too long")
+	ErrGasUsedAboveLimit = errors.New("invalid gasUsed")
+	ErrInvalidHeader     = errors.New("invalid header")
+)
+
+// VerifyHeader checks that header can follow parent under the rules of
+// fork: its number, parent hash, timestamp and gas limit, the base fee and
+// blob gas fields, and the presence of the fields each fork adds
+func VerifyHeader(parent, header *Header, fork Fork) error {
+	switch {
+	case header.ParentHash != parent.Hash():
+		return fmt.Errorf("%w: parent hash 0x%x", ErrUnknownAncestor, header.ParentHash)
+	case header.Number != parent.Number+1:
+		return fmt.Errorf("%w: have %d, want %d", ErrInvalidNumber, header.Number, parent.Number+1)
+	case header.Time <= parent.Time:
+		return fmt.Errorf("%w: %d, parent %d", ErrOlderBlockTime, header.Time, parent.Time)
+	case len(header.Extra) > MaxExtraDataSize:
+		return fmt.Errorf("%w: %d > %d", ErrExtraDataTooLong, len(header.Extra), MaxExtraDataSize)
+	case header.GasUsed > header.GasLimit:
+		return fmt.Errorf("%w: have %d, gasLimit %d", ErrGasUsedAboveLimit, header.GasUsed, header.GasLimit)
+	}
+	if err := verifyFees(parent, header, fork); err != nil {
+		return err
+	}
+
+	// Proof-of-stake blocks, from Shanghai here, have no work or ommers
+	if fork.IsActive(Shanghai) {
+		switch {
+		case header.Difficulty != nil && header.Difficulty.Sign() != 0:
+			return fmt.Errorf("%w: nonzero difficulty", ErrInvalidHeader)
+		case header.Nonce != [8]byte{}:
+			return fmt.Errorf("%w: nonzero nonce", ErrInvalidHeader)
+		case header.UncleHash != EmptyUncleHash:
+			return fmt.Errorf("%w: non-empty uncle hash", ErrInvalidHeader)
+		}
+	}
+	fields := []struct {
+		name    string
+		present bool
+		fork    Fork
+	}{
+		{"withdrawalsRoot", header.WithdrawalsHash != nil, Shanghai},
+		{"parentBeaconBlockRoot", header.ParentBeaconRoot != nil, Cancun},
+		{"requestsHash", header.RequestsHash != nil, Prague},
+	}
+	for _, f := range fields {
+		if want := fork.IsActive(f.fork); f.present != want {
+			return fmt.Errorf("%w: %s present %v, want %v", ErrInvalidHeader, f.name, f.present, want)
+		}
+	}
+	return nil
+}
+
+// Withdrawal is a validator withdrawal pushed from the beacon chain
+// (EIP-4895). Amount is in gwei.
+type Withdrawal struct {
+	Index     uint64
+	Validator uint64
+	Address   Address
+	Amount    uint64
+}
+
+// WithdrawalsRoot returns the root stored in the header of a block with
+// the given withdrawals
+func WithdrawalsRoot(withdrawals []*Withdrawal) Word {
+	return deriveRoot(len(withdrawals), func(i int) []byte { return mustEncode(withdrawals[i]) })
+}
+
+// Block is a header with its body: the transactions, the ommers and, from
+// Shanghai, the withdrawals
+type Block struct {
+	Header       *Header
+	Transactions []*Transaction
+	Uncles       []*Header
+	Withdrawals  []*Withdrawal // nil before Shanghai
+}
+
+// Errors for block bodies that do not match their header
+var (
+	ErrInvalidTxRoot          = errors.New("transaction root hash mismatch")
+	ErrInvalidUncleHash       = errors.New("uncle root hash mismatch")
+	ErrInvalidWithdrawalsRoot = errors.New("withdrawals root hash mismatch")
+	ErrUnclesNotSupported     = errors.New("blocks with uncles are not supported")
+)
+
+// extBlock is the RLP layout of a block. Typed transactions are byte
+// strings holding their envelope, legacy ones are lists.
+type extBlock struct {
+	Header      *Header
+	Txs         []rlp.RawValue
+	Uncles      []*Header
+	Withdrawals []*Withdrawal `rlp:"optional"`
+}
+
+// DecodeBlock decodes an RLP encoded block
+func DecodeBlock(raw []byte) (*Block, error) {
+	var ext extBlock
+	if err := rlp.DecodeBytes(raw, &ext); err != nil {
+		return nil, fmt.Errorf("decode block: %w", err)
+	}
+	txs, err := decodeTxList(ext.Txs)
+	if err != nil {
+		return nil, fmt.Errorf("decode block: %w", err)
+	}
+	return &Block{Header: ext.Header, Transactions: txs, Uncles: ext.Uncles, Withdrawals: ext.Withdrawals}, nil
+}
+
+// DecodeTransactions decodes an RLP list of transactions in the form
+// blocks hold them
+func DecodeTransactions(raw []byte) ([]*Transaction, error) {
+	var encs []rlp.RawValue
+	if err := rlp.DecodeBytes(raw, &encs); err != nil {
+		return nil, fmt.Errorf("decode transactions: %w", err)
+	}
+	return decodeTxList(encs)
+}
+
+// EncodeTransactions is the inverse of DecodeTransactions
+func EncodeTransactions(txs []*Transaction) []byte {
+	encs := make([]rlp.RawValue, len(txs))
+	for i, tx := range txs {
+		enc := tx.Encode()
+		if tx.Type != LegacyTxType {
+			enc = mustEncode(enc) // Typed envelopes are wrapped in a byte string
+		}
+		encs[i] = enc
+	}
+	return mustEncode(encs)
+}
+
+func decodeTxList(encs []rlp.RawValue) ([]*Transaction, error) {
+	var txs []*Transaction
+	for i, enc := range encs {
+		tx, err := DecodeBlockTransaction(enc)
+		if err != nil {
+			return nil, fmt.Errorf("tx %d: %w", i, err)
+		}
+		txs = append(txs, tx)
+	}
+	return txs, nil
+}
+
+// DecodeBlockTransaction decodes an item of a block's transaction list:
+// a legacy transaction's list or a byte string holding a typed envelope
+func DecodeBlockTransaction(enc rlp.RawValue) (*Transaction, error) {
+	kind, content, _, err := rlp.Split(enc)
+	if err != nil {
+		return nil, err
+	}
+	if kind == rlp.List {
+		content = enc
+	}
+	return DecodeTransaction(content)
+}
+
+// Hash returns the hash of the block's header
+func (b *Block) Hash() Word {
+	return b.Header.Hash()
+}
+
+// ValidateBody checks the transactions, ommers and withdrawals against the
+// roots in the header. Ommers are not supported, so blocks must have none.
+func (b *Block) ValidateBody() error {
+	h := b.Header
+	if hash := Word(crypto.Keccak256(mustEncode(b.Uncles))); hash != h.UncleHash {
+		return fmt.Errorf("%w (remote: %x local: %x)", ErrInvalidUncleHash, h.UncleHash, hash)
+	}
+	if len(b.Uncles) > 0 {
+		return fmt.Errorf("%w: %d uncles", ErrUnclesNotSupported, len(b.Uncles))
+	}
+	if hash := TransactionsRoot(b.Transactions); hash != h.TxHash {
+		return fmt.Errorf("%w (remote: %x local: %x)", ErrInvalidTxRoot, h.TxHash, hash)
+	}
+	switch {
+	case h.WithdrawalsHash == nil && b.Withdrawals != nil:
+		return fmt.Errorf("%w: withdrawals without withdrawals root", ErrInvalidWithdrawalsRoot)
+	case h.WithdrawalsHash != nil && b.Withdrawals == nil:
+		return fmt.Errorf("%w: missing withdrawals", ErrInvalidWithdrawalsRoot)
+	case h.WithdrawalsHash != nil:
+		if hash := WithdrawalsRoot(b.Withdrawals); hash != *h.WithdrawalsHash {
+			return fmt.Errorf("%w (remote: %x local: %x)", ErrInvalidWithdrawalsRoot, *h.WithdrawalsHash, hash)
+		}
+	}
+	return nil
+}
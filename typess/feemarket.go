@@ -0,0 +1,207 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Fee market: the EIP-1559 base fee and the EIP-4844 blob base fee, both
+// derived from the parent block
+
+const (
+	InitialBaseFee           uint64 = 1_000_000_000 // Base fee of the first London block
+	BaseFeeChangeDenominator uint64 = 8             // Bounds the base fee change to 1/8 per block
+	ElasticityMultiplier     uint64 = 2             // Gas limit over gas target
+	GasLimitBoundDivisor     uint64 = 1024          // Bounds the gas limit change per block
+	MinGasLimit              uint64 = 5000
+
+	GasPerBlob     uint64 = 1 << 17 // Blob gas used by each blob
+	MinBlobBaseFee int64  = 1       // Wei per blob gas at zero excess
+	BlobBaseCost   int64  = 1 << 13 // EIP-7918 (Osaka): execution gas a blob is priced at least at
+)
+
+var (
+	ErrInvalidBaseFee       = errors.New("invalid baseFee")
+	ErrInvalidGasLimit      = errors.New("invalid gas limit")
+	ErrInvalidExcessBlobGas = errors.New("invalid excessBlobGas")
+	ErrInvalidBlobGasUsed   = errors.New("invalid blobGasUsed")
+)
+
+// BlobConfig is the blob schedule of a fork: the target and maximum number
+// of blobs per block and the update fraction that sets how fast the blob
+// base fee reacts to blocks above or below the target
+type BlobConfig struct {
+	Target         int
+	Max            int
+	UpdateFraction uint64
+}
+
+var blobConfigs = map[Fork]*BlobConfig{
+	Cancun: {Target: 3, Max: 6, UpdateFraction: 3338477},
+	Prague: {Target: 6, Max: 9, UpdateFraction: 5007716}, // EIP-7691
+	Osaka:  {Target: 6, Max: 9, UpdateFraction: 5007716},
+}
+
+// BlobParams returns the blob schedule of fork, nil before Cancun
+func BlobParams(fork Fork) *BlobConfig {
+	return blobConfigs[fork]
+}
+
+// TargetBlobGas and MaxBlobGas are the per-block blob gas target and limit
+func (c *BlobConfig) TargetBlobGas() uint64 { return uint64(c.Target) * GasPerBlob }
+func (c *BlobConfig) MaxBlobGas() uint64    { return uint64(c.Max) * GasPerBlob }
+
+// BlobBaseFee returns the price of a unit of blob gas for the given excess:
+// MIN_BASE_FEE_PER_BLOB_GAS * e^(excess / update fraction)
+func (c *BlobConfig) BlobBaseFee(excessBlobGas uint64) *big.Int {
+	return fakeExponential(big.NewInt(MinBlobBaseFee), new(big.Int).SetUint64(excessBlobGas), new(big.Int).SetUint64(c.UpdateFraction))
+}
+
+// CalcBaseFee returns the base fee of the child of parent (EIP-1559). It
+// moves by up to 1/8 towards keeping blocks at half of the gas limit; the
+// first London block, whose parent has no base fee, starts at 1 gwei.
+func CalcBaseFee(parent *Header) *big.Int {
+	if parent.BaseFee == nil {
+		return new(big.Int).SetUint64(InitialBaseFee)
+	}
+	target := parent.GasLimit / ElasticityMultiplier
+	if parent.GasUsed == target {
+		return new(big.Int).Set(parent.BaseFee)
+	}
+
+	delta := new(big.Int)
+	if parent.GasUsed > target {
+		delta.SetUint64(parent.GasUsed - target)
+	} else {
+		delta.SetUint64(target - parent.GasUsed)
+	}
+	delta.Mul(delta, parent.BaseFee)
+	delta.Div(delta, new(big.Int).SetUint64(target))
+	delta.Div(delta, new(big.Int).SetUint64(BaseFeeChangeDenominator))
+
+	if parent.GasUsed > target {
+		// Full blocks raise the fee by at least 1 wei
+		if delta.Sign() == 0 {
+			delta.SetInt64(1)
+		}
+		return delta.Add(parent.BaseFee, delta)
+	}
+	fee := delta.Sub(parent.BaseFee, delta)
+	if fee.Sign() < 0 {
+		fee.SetInt64(0)
+	}
+	return fee
+}
+
+// CalcExcessBlobGas returns the excess blob gas of a block of fork whose
+// parent is parent: the blob gas used above the target, accumulated over
+// the chain. From Osaka a blob price below the reserve price (EIP-7918)
+// only lets the excess grow.
+func CalcExcessBlobGas(parent *Header, fork Fork) uint64 {
+	cfg := BlobParams(fork)
+	if cfg == nil {
+		return 0
+	}
+	var parentExcess, parentUsed uint64
+	if parent.ExcessBlobGas != nil {
+		parentExcess = *parent.ExcessBlobGas
+	}
+	if parent.BlobGasUsed != nil {
+		parentUsed = *parent.BlobGasUsed
+	}
+	excess := parentExcess + parentUsed
+	if excess < cfg.TargetBlobGas() {
+		return 0
+	}
+	if fork.IsActive(Osaka) && parent.BaseFee != nil {
+		reserve := new(big.Int).Mul(big.NewInt(BlobBaseCost), parent.BaseFee)
+		blobPrice := new(big.Int).Mul(cfg.BlobBaseFee(parentExcess), new(big.Int).SetUint64(GasPerBlob))
+		if reserve.Cmp(blobPrice) > 0 {
+			return parentExcess + parentUsed*uint64(cfg.Max-cfg.Target)/uint64(cfg.Max)
+		}
+	}
+	return excess - cfg.TargetBlobGas()
+}
+
+// CalcBlobFee returns the blob base fee of a block of fork with the given
+// excess blob gas, nil before Cancun
+func CalcBlobFee(excessBlobGas uint64, fork Fork) *big.Int {
+	cfg := BlobParams(fork)
+	if cfg == nil {
+		return nil
+	}
+	return cfg.BlobBaseFee(excessBlobGas)
+}
+
+// VerifyGasLimit checks that the gas limit moved by less than 1/1024 of
+// the parent's and stays above the minimum
+func VerifyGasLimit(parentGasLimit, gasLimit uint64) error {
+	diff := max(parentGasLimit, gasLimit) - min(parentGasLimit, gasLimit)
+	if bound := parentGasLimit / GasLimitBoundDivisor; diff >= bound {
+		return fmt.Errorf("%w: have %d, want %d +/- %d", ErrInvalidGasLimit, gasLimit, parentGasLimit, bound-1)
+	}
+	if gasLimit < MinGasLimit {
+		return fmt.Errorf("%w: below %d", ErrInvalidGasLimit, MinGasLimit)
+	}
+	return nil
+}
+
+// verifyFees checks the gas limit, base fee and blob gas fields of header
+// against its parent for the rules of fork
+func verifyFees(parent, header *Header, fork Fork) error {
+	parentGasLimit := parent.GasLimit
+	if fork.IsActive(London) && parent.BaseFee == nil {
+		// The first London block doubles the limit so the target is unchanged
+		parentGasLimit *= ElasticityMultiplier
+	}
+	if err := VerifyGasLimit(parentGasLimit, header.GasLimit); err != nil {
+		return err
+	}
+
+	switch {
+	case !fork.IsActive(London) && header.BaseFee != nil:
+		return fmt.Errorf("%w: base fee before London", ErrInvalidBaseFee)
+	case fork.IsActive(London) && header.BaseFee == nil:
+		return fmt.Errorf("%w: header is missing baseFee", ErrInvalidBaseFee)
+	case fork.IsActive(London):
+		if want := CalcBaseFee(parent); header.BaseFee.Cmp(want) != 0 {
+			return fmt.Errorf("%w: have %v, want %v, parentBaseFee %v, parentGasUsed %d", ErrInvalidBaseFee, header.BaseFee, want, parent.BaseFee, parent.GasUsed)
+		}
+	}
+
+	cfg := BlobParams(fork)
+	if cfg == nil {
+		if header.ExcessBlobGas != nil || header.BlobGasUsed != nil {
+			return fmt.Errorf("%w: blob gas fields before Cancun", ErrInvalidBlobGasUsed)
+		}
+		return nil
+	}
+	if header.ExcessBlobGas == nil {
+		return fmt.Errorf("%w: header is missing excessBlobGas", ErrInvalidExcessBlobGas)
+	}
+	if header.BlobGasUsed == nil {
+		return fmt.Errorf("%w: header is missing blobGasUsed", ErrInvalidBlobGasUsed)
+	}
+	if used := *header.BlobGasUsed; used > cfg.MaxBlobGas() || used%GasPerBlob != 0 {
+		return fmt.Errorf("%w: %d, maximum %d in multiples of %d", ErrInvalidBlobGasUsed, used, cfg.MaxBlobGas(), GasPerBlob)
+	}
+	if want := CalcExcessBlobGas(parent, fork); *header.ExcessBlobGas != want {
+		return fmt.Errorf("%w: have %d, want %d", ErrInvalidExcessBlobGas, *header.ExcessBlobGas, want)
+	}
+	return nil
+}
+
+// fakeExponential approximates factor * e^(numerator / denominator) with
+// integer arithmetic, as specified by EIP-4844
+func fakeExponential(factor, numerator, denominator *big.Int) *big.Int {
+	output := new(big.Int)
+	accum := new(big.Int).Mul(factor, denominator)
+	for i := int64(1); accum.Sign() > 0; i++ {
+		output.Add(output, accum)
+		accum.Mul(accum, numerator)
+		accum.Div(accum, denominator)
+		accum.Div(accum, big.NewInt(i))
+	}
+	return output.Div(output, denominator)
+}
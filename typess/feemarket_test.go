@@ -0,0 +1,206 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+// Vectors from go-ethereum's consensus/misc/eip1559 and eip4844 tests
+
+func TestCalcBaseFee(t *testing.T) {
+	tests := []struct {
+		name          string
+		parentBaseFee *big.Int
+		gasLimit      uint64
+		gasUsed       uint64
+		want          int64
+	}{
+		{"atTarget", big.NewInt(1_000_000_000), 20_000_000, 10_000_000, 1_000_000_000},
+		{"belowTarget", big.NewInt(1_000_000_000), 20_000_000, 9_000_000, 987_500_000},
+		{"aboveTarget", big.NewInt(1_000_000_000), 20_000_000, 11_000_000, 1_012_500_000},
+		{"empty", big.NewInt(1_000_000_000), 20_000_000, 0, 875_000_000},
+		{"full", big.NewInt(1_000_000_000), 20_000_000, 20_000_000, 1_125_000_000},
+		{"minimumIncrease", big.NewInt(7), 20_000_000, 10_000_001, 8},
+		{"firstLondonBlock", nil, 20_000_000, 20_000_000, 1_000_000_000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent := &Header{GasLimit: tt.gasLimit, GasUsed: tt.gasUsed, BaseFee: tt.parentBaseFee}
+			if got := CalcBaseFee(parent); got.Cmp(big.NewInt(tt.want)) != 0 {
+				t.Errorf("base fee %v, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyGasLimit(t *testing.T) {
+	tests := []struct {
+		parent, limit uint64
+		ok            bool
+	}{
+		{20_000_000, 20_000_000, true},
+		{20_000_000, 20_019_530, true},  // Upper limit
+		{20_000_000, 20_019_531, false}, // Upper limit + 1
+		{20_000_000, 19_980_470, true},  // Lower limit
+		{20_000_000, 19_980_469, false}, // Lower limit - 1
+		{40_000_000, 40_039_061, true},
+		{40_000_000, 40_039_062, false},
+		{40_000_000, 39_960_939, true},
+		{40_000_000, 39_960_938, false},
+		{5000, 5000, true},
+		{5003, 4999, false}, // Below the minimum
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d-%d", tt.parent, tt.limit), func(t *testing.T) {
+			err := VerifyGasLimit(tt.parent, tt.limit)
+			if tt.ok != (err == nil) || err != nil && !errors.Is(err, ErrInvalidGasLimit) {
+				t.Errorf("error %v, want ok %t", err, tt.ok)
+			}
+		})
+	}
+}
+
+// The first London block may double the gas limit, keeping the target
+func TestVerifyFeesLondonTransition(t *testing.T) {
+	tests := []struct {
+		limit uint64
+		ok    bool
+	}{
+		{20_000_000, true},
+		{20_019_530, true},
+		{20_019_531, false},
+		{19_980_470, true},
+		{19_980_469, false},
+	}
+	parent := &Header{GasLimit: 10_000_000, GasUsed: 5_000_000}
+	for _, tt := range tests {
+		header := &Header{GasLimit: tt.limit, BaseFee: big.NewInt(1_000_000_000)}
+		if err := verifyFees(parent, header, London); tt.ok != (err == nil) {
+			t.Errorf("gas limit %d: error %v, want ok %t", tt.limit, err, tt.ok)
+		}
+	}
+}
+
+func TestCalcExcessBlobGas(t *testing.T) {
+	target := uint64(BlobParams(Cancun).Target)
+	targetGas := BlobParams(Cancun).TargetBlobGas()
+	pragueTargetGas := BlobParams(Prague).TargetBlobGas()
+	tests := []struct {
+		name    string
+		fork    Fork
+		excess  uint64
+		blobs   uint64
+		baseFee int64 // Parent base fee; 0 for none
+		want    uint64
+	}{
+		// The excess stays at zero while blocks are at or below the target
+		{name: "none", fork: Cancun, excess: 0, blobs: 0, want: 0},
+		{name: "belowTarget", fork: Cancun, excess: 0, blobs: 1, want: 0},
+		{name: "atTarget", fork: Cancun, excess: 0, blobs: target, want: 0},
+		// and grows by the overshoot above it
+		{name: "aboveTarget", fork: Cancun, excess: 0, blobs: target + 1, want: GasPerBlob},
+		{name: "aboveTargetWithExcess", fork: Cancun, excess: 1, blobs: target + 1, want: GasPerBlob + 1},
+		{name: "twoAboveTarget", fork: Cancun, excess: 1, blobs: target + 2, want: 2*GasPerBlob + 1},
+		// It shrinks by the undershoot, down to zero
+		{name: "keep", fork: Cancun, excess: targetGas, blobs: target, want: targetGas},
+		{name: "shrink", fork: Cancun, excess: targetGas, blobs: target - 1, want: targetGas - GasPerBlob},
+		{name: "shrinkTwo", fork: Cancun, excess: targetGas, blobs: target - 2, want: targetGas - 2*GasPerBlob},
+		{name: "shrinkToZero", fork: Cancun, excess: GasPerBlob - 1, blobs: target - 1, want: 0},
+		// Prague raises the target to 6 blobs (EIP-7691)
+		{name: "pragueAtCancunMax", fork: Prague, excess: 0, blobs: 6, want: 0},
+		{name: "pragueAboveTarget", fork: Prague, excess: 0, blobs: 9, want: 3 * GasPerBlob},
+		// From Osaka the excess only grows while blobs are priced below the
+		// reserve price (EIP-7918)
+		{name: "belowReservePrice", fork: Osaka, excess: 0, blobs: 6, baseFee: 1_000_000_000, want: pragueTargetGas * 3 / 9},
+		{name: "aboveReservePrice", fork: Osaka, excess: 0, blobs: 6, baseFee: 1, want: 0},
+		{name: "preCancun", fork: Shanghai, excess: 0, blobs: 6, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			used := tt.blobs * GasPerBlob
+			parent := &Header{ExcessBlobGas: &tt.excess, BlobGasUsed: &used}
+			if tt.baseFee != 0 {
+				parent.BaseFee = big.NewInt(tt.baseFee)
+			}
+			if got := CalcExcessBlobGas(parent, tt.fork); got != tt.want {
+				t.Errorf("excess blob gas %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// Blocks of go-ethereum's post-Osaka test, under blob parameter only forks
+// with larger schedules
+func TestCalcExcessBlobGasSchedules(t *testing.T) {
+	tests := []struct {
+		cfg                   *BlobConfig
+		excess, used, baseFee uint64
+		want                  uint64
+	}{
+		{&BlobConfig{Target: 9, Max: 14, UpdateFraction: 8832827}, 5149252, 1310720, 30, 5617366},
+		{&BlobConfig{Target: 21, Max: 32, UpdateFraction: 20609697}, 19251039, 2490368, 50, 20107103},
+	}
+	saved := blobConfigs[Osaka]
+	defer func() { blobConfigs[Osaka] = saved }()
+	for i, tt := range tests {
+		blobConfigs[Osaka] = tt.cfg
+		parent := &Header{ExcessBlobGas: &tt.excess, BlobGasUsed: &tt.used, BaseFee: new(big.Int).SetUint64(tt.baseFee)}
+		if got := CalcExcessBlobGas(parent, Osaka); got != tt.want {
+			t.Errorf("test %d: excess blob gas %d, want %d", i, got, tt.want)
+		}
+	}
+}
+
+func TestCalcBlobFee(t *testing.T) {
+	tests := []struct {
+		excess uint64
+		want   int64
+	}{
+		{0, 1},
+		{2314057, 1},
+		{2314058, 2},
+		{10 * 1024 * 1024, 23},
+	}
+	for _, tt := range tests {
+		if got := CalcBlobFee(tt.excess, Cancun); got.Cmp(big.NewInt(tt.want)) != 0 {
+			t.Errorf("excess %d: blob fee %v, want %d", tt.excess, got, tt.want)
+		}
+	}
+	if got := CalcBlobFee(0, Shanghai); got != nil {
+		t.Errorf("blob fee %v before Cancun, want nil", got)
+	}
+}
+
+func TestFakeExponential(t *testing.T) {
+	tests := []struct {
+		factor, numerator, denominator int64
+		want                           int64
+	}{
+		{1, 0, 1, 1},
+		{38493, 0, 1000, 38493},
+		{0, 1234, 2345, 0},
+		{1, 2, 1, 6}, // approximate 7.389
+		{1, 4, 2, 6},
+		{1, 3, 1, 16}, // approximate 20.09
+		{1, 6, 2, 18},
+		{1, 4, 1, 49}, // approximate 54.60
+		{1, 8, 2, 50},
+		{10, 8, 2, 542}, // approximate 540.598
+		{11, 8, 2, 596}, // approximate 600.58
+		{1, 5, 1, 136},  // approximate 148.4
+		{1, 5, 2, 11},   // approximate 12.18
+		{2, 5, 2, 23},   // approximate 24.36
+		{1, 50000000, 2225652, 5709098764},
+	}
+	for _, tt := range tests {
+		f, n, d := big.NewInt(tt.factor), big.NewInt(tt.numerator), big.NewInt(tt.denominator)
+		if got := fakeExponential(f, n, d); got.Int64() != tt.want {
+			t.Errorf("fakeExponential(%d, %d, %d) = %v, want %d", tt.factor, tt.numerator, tt.denominator, got, tt.want)
+		}
+		if f.Int64() != tt.factor || n.Int64() != tt.numerator || d.Int64() != tt.denominator {
+			t.Errorf("fakeExponential modified its arguments: %v %v %v", f, n, d)
+		}
+	}
+}
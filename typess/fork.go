@@ -0,0 +1,49 @@
+package types
+
+import "strings"
+
+// Fork identifies the protocol upgrade whose rules the VM follows.
+// Forks are ordered, so a later fork includes every rule of the earlier ones.
+type Fork uint8
+
+const (
+	Istanbul Fork = iota
+	Berlin
+	London
+	Shanghai
+	Cancun
+	Prague
+	Osaka
+)
+
+var forkNames = map[Fork]string{
+	Istanbul: "Istanbul",
+	Berlin:   "Berlin",
+	London:   "London",
+	Shanghai: "Shanghai",
+	Cancun:   "Cancun",
+	Prague:   "Prague",
+	Osaka:    "Osaka",
+}
+
+func (f Fork) String() string {
+	if name, ok := forkNames[f]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+// ParseFork looks up a fork by name, ignoring case
+func ParseFork(name string) (Fork, bool) {
+	for fork, forkName := range forkNames {
+		if strings.EqualFold(forkName, name) {
+			return fork, true
+		}
+	}
+	return 0, false
+}
+
+// IsActive returns true if the rules of fork other apply under f
+func (f Fork) IsActive(other Fork) bool {
+	return f >= other
+}
@@ -0,0 +1,248 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// Genesis files in the format of geth's genesis.json: the chain config,
+// the header fields of block 0 and the alloc of initial accounts
+
+const (
+	GenesisGasLimit   uint64 = 4712388 // Gas limit of genesis files that leave it out
+	GenesisDifficulty        = 131072  // Difficulty of proof-of-work genesis files that leave it out
+)
+
+// EmptyRequestsHash is the requests hash of a block without requests,
+// sha256 of nothing (EIP-7685)
+var EmptyRequestsHash = Word(sha256.Sum256(nil))
+
+var ErrMissingChainConfig = errors.New("genesis has no chain config")
+
+// ChainConfig is the config section of a genesis file: the chain ID and the
+// block numbers and timestamps the forks activate at. Nil means never.
+type ChainConfig struct {
+	ChainID *big.Int `json:"chainId"`
+
+	IstanbulBlock *uint64 `json:"istanbulBlock,omitempty"`
+	BerlinBlock   *uint64 `json:"berlinBlock,omitempty"`
+	LondonBlock   *uint64 `json:"londonBlock,omitempty"`
+
+	// Forks after the merge activate by timestamp
+	ShanghaiTime *uint64 `json:"shanghaiTime,omitempty"`
+	CancunTime   *uint64 `json:"cancunTime,omitempty"`
+	PragueTime   *uint64 `json:"pragueTime,omitempty"`
+	OsakaTime    *uint64 `json:"osakaTime,omitempty"`
+
+	// Zero for chains that are proof-of-stake from genesis
+	TerminalTotalDifficulty *big.Int `json:"terminalTotalDifficulty,omitempty"`
+
+	Ethash *struct{} `json:"ethash,omitempty"` // Set for proof-of-work chains
+}
+
+// IsMerged reports whether blocks are proof-of-stake from genesis, which
+// makes them carry PREVRANDAO instead of a difficulty
+func (c *ChainConfig) IsMerged() bool {
+	return c.TerminalTotalDifficulty != nil && c.TerminalTotalDifficulty.Sign() == 0
+}
+
+// Fork returns the fork whose rules apply to the block with the given
+// number and timestamp. A fork only activates once all earlier ones have;
+// blocks before Istanbul, the earliest fork the VM knows, follow Istanbul.
+func (c *ChainConfig) Fork(number, time uint64) Fork {
+	schedule := []struct {
+		fork       Fork
+		activation *uint64
+		at         uint64
+	}{
+		{Berlin, c.BerlinBlock, number},
+		{London, c.LondonBlock, number},
+		{Shanghai, c.ShanghaiTime, time},
+		{Cancun, c.CancunTime, time},
+		{Prague, c.PragueTime, time},
+		{Osaka, c.OsakaTime, time},
+	}
+	fork := Istanbul
+	for _, s := range schedule {
+		if s.activation == nil || *s.activation > s.at {
+			break
+		}
+		fork = s.fork
+	}
+	return fork
+}
+
+// GenesisAccount is an account of the genesis alloc
+type GenesisAccount struct {
+	Balance *HexBig       `json:"balance"`
+	Nonce   HexUint64     `json:"nonce,omitempty"`
+	Code    HexBytes      `json:"code,omitempty"`
+	Storage map[Word]Word `json:"storage,omitempty"`
+}
+
+// GenesisAlloc maps addresses to their initial accounts
+type GenesisAlloc map[Address]GenesisAccount
+
+// State returns a state holding the accounts, with a missing balance read
+// as zero. Empty accounts are kept; they are not touched.
+func (ga GenesisAlloc) State() *StateDB {
+	state := NewStateDB()
+	for addr, alloc := range ga {
+		acc := newAccount()
+		if alloc.Balance != nil {
+			acc.Balance.Set(alloc.Balance.ToInt())
+		}
+		acc.Nonce = uint64(alloc.Nonce)
+		acc.Code = alloc.Code
+		for key, value := range alloc.Storage {
+			acc.Storage.Store(key, value)
+		}
+		state.Accounts[addr] = acc
+	}
+	return state
+}
+
+// Alloc is the inverse of GenesisAlloc.State: the accounts of the state as
+// an alloc, leaving out zero storage slots
+func (s *StateDB) Alloc() GenesisAlloc {
+	alloc := make(GenesisAlloc, len(s.Accounts))
+	for addr, acc := range s.Accounts {
+		account := GenesisAccount{Balance: NewHexBig(acc.Balance), Nonce: HexUint64(acc.Nonce), Code: acc.Code}
+		for key, value := range acc.Storage.Data {
+			if value == (Byte32{}) {
+				continue
+			}
+			if account.Storage == nil {
+				account.Storage = make(map[Word]Word)
+			}
+			account.Storage[Word(key)] = Word(value)
+		}
+		alloc[addr] = account
+	}
+	return alloc
+}
+
+// validate checks that every account has a balance
+func (ga GenesisAlloc) validate() error {
+	for addr, acc := range ga {
+		if acc.Balance == nil {
+			return fmt.Errorf("account %x has no balance", addr)
+		}
+	}
+	return nil
+}
+
+// Genesis is a genesis.json file. Number, GasUsed, ParentHash and the fee
+// fields are only set by test fixtures.
+type Genesis struct {
+	Config     *ChainConfig `json:"config"`
+	Nonce      HexUint64    `json:"nonce"`
+	Timestamp  HexUint64    `json:"timestamp"`
+	ExtraData  HexBytes     `json:"extraData"`
+	GasLimit   HexUint64    `json:"gasLimit"`
+	Difficulty *HexBig      `json:"difficulty"`
+	Mixhash    Word         `json:"mixHash"`
+	Coinbase   Address      `json:"coinbase"`
+	Alloc      GenesisAlloc `json:"alloc"`
+
+	Number        HexUint64  `json:"number"`
+	GasUsed       HexUint64  `json:"gasUsed"`
+	ParentHash    Word       `json:"parentHash"`
+	BaseFee       *HexBig    `json:"baseFeePerGas"`
+	ExcessBlobGas *HexUint64 `json:"excessBlobGas"`
+	BlobGasUsed   *HexUint64 `json:"blobGasUsed"`
+}
+
+// LoadGenesis reads a genesis.json file
+func LoadGenesis(path string) (*Genesis, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGenesis(data)
+}
+
+// ParseGenesis decodes a genesis.json document
+func ParseGenesis(data []byte) (*Genesis, error) {
+	g := new(Genesis)
+	if err := json.Unmarshal(data, g); err != nil {
+		return nil, fmt.Errorf("invalid genesis: %w", err)
+	}
+	if g.Config == nil {
+		return nil, ErrMissingChainConfig
+	}
+	if err := g.Alloc.validate(); err != nil {
+		return nil, fmt.Errorf("invalid genesis: %w", err)
+	}
+	return g, nil
+}
+
+// Fork returns the fork of the genesis block
+func (g *Genesis) Fork() Fork {
+	return g.Config.Fork(uint64(g.Number), uint64(g.Timestamp))
+}
+
+// ToBlock returns the genesis header and the initial state. The header
+// has the fields of the genesis fork, with the defaults geth fills in.
+func (g *Genesis) ToBlock() (*Header, *StateDB) {
+	state := g.Alloc.State()
+	header := &Header{
+		ParentHash:  g.ParentHash,
+		UncleHash:   EmptyUncleHash,
+		Coinbase:    g.Coinbase,
+		Root:        state.Root(),
+		TxHash:      EmptyRootHash,
+		ReceiptHash: EmptyRootHash,
+		Number:      uint64(g.Number),
+		GasLimit:    uint64(g.GasLimit),
+		GasUsed:     uint64(g.GasUsed),
+		Time:        uint64(g.Timestamp),
+		Extra:       g.ExtraData,
+		MixDigest:   g.Mixhash,
+	}
+	binary.BigEndian.PutUint64(header.Nonce[:], uint64(g.Nonce))
+	if header.GasLimit == 0 {
+		header.GasLimit = GenesisGasLimit
+	}
+	switch {
+	case g.Difficulty != nil:
+		header.Difficulty = new(big.Int).Set(g.Difficulty.ToInt())
+	case g.Config.Ethash == nil:
+		header.Difficulty = new(big.Int)
+	case g.Mixhash == Word{}:
+		header.Difficulty = big.NewInt(GenesisDifficulty)
+	}
+
+	fork := g.Fork()
+	if fork.IsActive(London) {
+		header.BaseFee = new(big.Int).SetUint64(InitialBaseFee)
+		if g.BaseFee != nil {
+			header.BaseFee.Set(g.BaseFee.ToInt())
+		}
+	}
+	if fork.IsActive(Shanghai) {
+		withdrawalsHash := EmptyRootHash
+		header.WithdrawalsHash = &withdrawalsHash
+	}
+	if fork.IsActive(Cancun) {
+		// The genesis block has no parent beacon block
+		header.ParentBeaconRoot = new(Word)
+		header.ExcessBlobGas, header.BlobGasUsed = new(uint64), new(uint64)
+		if g.ExcessBlobGas != nil {
+			*header.ExcessBlobGas = uint64(*g.ExcessBlobGas)
+		}
+		if g.BlobGasUsed != nil {
+			*header.BlobGasUsed = uint64(*g.BlobGasUsed)
+		}
+	}
+	if fork.IsActive(Prague) {
+		requestsHash := EmptyRequestsHash
+		header.RequestsHash = &requestsHash
+	}
+	return header, state
+}
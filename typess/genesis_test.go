@@ -0,0 +1,25 @@
+package types
+
+import (
+	"encoding/hex"
+	"os"
+	"testing"
+)
+
+func TestSepoliaGenesis(t *testing.T) {
+	data, err := os.ReadFile("testdata/genesis_sepolia.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	genesis, err := ParseGenesis(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, state := genesis.ToBlock()
+	if root, want := state.Root(), "5eb6e371a698b8d68f665192350ffcecbbbf322916f4b51bd79bb6887da3f494"; hex.EncodeToString(root[:]) != want {
+		t.Errorf("state root %x, want %s", root, want)
+	}
+	if hash, want := header.Hash(), "25a5cc106eea7138acab33231d7160d69cb777ee0c2c553fcddf5138993e6dd9"; hex.EncodeToString(hash[:]) != want {
+		t.Errorf("block hash %x, want %s", hash, want)
+	}
+}
@@ -0,0 +1,151 @@
+package types
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Hex encoding used by JSON inputs such as access lists, genesis files and tests
+
+// ParseHexBytes decodes a hex string with an optional 0x prefix, allowing an odd length
+func ParseHexBytes(s string) ([]byte, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	if len(s)%2 == 1 {
+		s = "0" + s
+	}
+	return hex.DecodeString(s)
+}
+
+// HexToAddress decodes a hex address, left-padding short values
+func HexToAddress(s string) (Address, error) {
+	var addr Address
+	err := addr.UnmarshalText([]byte(s))
+	return addr, err
+}
+
+// mustAddress is HexToAddress for well-formed constants
+func mustAddress(s string) Address {
+	addr, err := HexToAddress(s)
+	if err != nil {
+		panic(err)
+	}
+	return addr
+}
+
+// mustWord decodes a well-formed hex word constant
+func mustWord(s string) Word {
+	var w Word
+	if err := w.UnmarshalText([]byte(s)); err != nil {
+		panic(err)
+	}
+	return w
+}
+
+func (a Address) MarshalText() ([]byte, error) {
+	return []byte("0x" + hex.EncodeToString(a[:])), nil
+}
+
+func (a *Address) UnmarshalText(text []byte) error {
+	data, err := ParseHexBytes(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %w", text, err)
+	}
+	if len(data) > len(a) {
+		return fmt.Errorf("invalid address %q: too long", text)
+	}
+	*a = Address{}
+	copy(a[len(a)-len(data):], data)
+	return nil
+}
+
+func (w Word) MarshalText() ([]byte, error) {
+	return []byte("0x" + hex.EncodeToString(w[:])), nil
+}
+
+// UnmarshalText accepts any hex value up to 32 bytes, left-padding short ones
+func (w *Word) UnmarshalText(text []byte) error {
+	data, err := ParseHexBytes(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid word %q: %w", text, err)
+	}
+	if len(data) > len(w) {
+		return fmt.Errorf("invalid word %q: too long", text)
+	}
+	*w = NewWordFromBytes(data)
+	return nil
+}
+
+// HexBytes is a byte slice encoded as 0x-prefixed hex
+type HexBytes []byte
+
+func (b HexBytes) MarshalText() ([]byte, error) {
+	return []byte("0x" + hex.EncodeToString(b)), nil
+}
+
+func (b *HexBytes) UnmarshalText(text []byte) error {
+	data, err := ParseHexBytes(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid hex bytes %q: %w", text, err)
+	}
+	*b = data
+	return nil
+}
+
+// HexUint64 is a quantity encoded as 0x-prefixed hex without leading zeros.
+// Decimal input is accepted too.
+type HexUint64 uint64
+
+func (q HexUint64) MarshalText() ([]byte, error) {
+	return []byte("0x" + strconv.FormatUint(uint64(q), 16)), nil
+}
+
+func (q *HexUint64) UnmarshalText(text []byte) error {
+	x, err := parseQuantity(string(text), 64)
+	if err != nil {
+		return err
+	}
+	*q = HexUint64(x.Uint64())
+	return nil
+}
+
+// HexBig is a big quantity encoded like HexUint64, up to 256 bits
+type HexBig big.Int
+
+func NewHexBig(x *big.Int) *HexBig {
+	return (*HexBig)(new(big.Int).Set(x))
+}
+
+// ToInt returns the value as a big.Int
+func (q *HexBig) ToInt() *big.Int {
+	return (*big.Int)(q)
+}
+
+func (q HexBig) MarshalText() ([]byte, error) {
+	return []byte("0x" + (*big.Int)(&q).Text(16)), nil
+}
+
+func (q *HexBig) UnmarshalText(text []byte) error {
+	x, err := parseQuantity(string(text), 256)
+	if err != nil {
+		return err
+	}
+	*q = HexBig(*x)
+	return nil
+}
+
+// parseQuantity reads a hex or decimal unsigned integer of at most bits bits
+func parseQuantity(s string, bits int) (*big.Int, error) {
+	x, ok := new(big.Int), false
+	if digits, isHex := strings.CutPrefix(strings.ToLower(s), "0x"); isHex {
+		x, ok = x.SetString(digits, 16)
+	} else {
+		x, ok = x.SetString(s, 10)
+	}
+	if !ok || x.Sign() < 0 || x.BitLen() > bits {
+		return nil, fmt.Errorf("invalid %d-bit quantity %q", bits, s)
+	}
+	return x, nil
+}
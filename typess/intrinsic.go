@@ -0,0 +1,80 @@
+package types
+
+// Intrinsic gas constants
+const (
+	GasTx                     uint64 = 21000 // G_transaction - Paid by every transaction
+	GasTxCreate               uint64 = 32000 // G_txcreate - Added for contract creation
+	GasTxDataZero             uint64 = 4     // G_txdatazero - Per zero byte of data
+	GasTxDataNonZero          uint64 = 16    // G_txdatanonzero - Per non-zero byte (EIP-2028)
+	GasTxAccessListAddress    uint64 = 2400  // Per address in the access list (EIP-2930)
+	GasTxAccessListStorageKey uint64 = 1900  // Per storage key in the access list (EIP-2930)
+	GasInitCodeWord           uint64 = 2     // Per 32-byte word of init code (EIP-3860)
+	GasTxDataFloorToken       uint64 = 10    // Floor price per calldata token (EIP-7623)
+	TxTokensPerNonZeroByte    uint64 = 4     // Calldata tokens of a non-zero byte (EIP-7623)
+	GasTxAuthorization        uint64 = 25000 // PER_EMPTY_ACCOUNT_COST - Per EIP-7702 authorization
+	GasTxAuthorizationBase    uint64 = 12500 // PER_AUTH_BASE_COST - Kept when the authority already exists
+)
+
+// AccessTuple is an account and the storage slots a transaction declares it will touch
+type AccessTuple struct {
+	Address     Address `json:"address"`
+	StorageKeys []Word  `json:"storageKeys"`
+}
+
+// AccessList is the EIP-2930 list of accounts and slots accessed by a transaction
+type AccessList []AccessTuple
+
+// StorageKeys returns the total number of storage keys in the list
+func (al AccessList) StorageKeys() int {
+	count := 0
+	for _, tuple := range al {
+		count += len(tuple.StorageKeys)
+	}
+	return count
+}
+
+// IntrinsicGas returns the gas a transaction pays before any code runs
+// (Yellow Paper g_0): the base cost, the creation surcharge, calldata bytes,
+// access list entries (Berlin), init code words (Shanghai) and set-code
+// authorizations (Prague)
+func IntrinsicGas(data []byte, accessList AccessList, authorizations int, isCreate bool, fork Fork) uint64 {
+	gas := GasTx
+	if isCreate {
+		gas += GasTxCreate
+	}
+
+	zeros := uint64(0)
+	for _, b := range data {
+		if b == 0 {
+			zeros++
+		}
+	}
+	gas += zeros*GasTxDataZero + (uint64(len(data))-zeros)*GasTxDataNonZero
+
+	if fork.IsActive(Berlin) {
+		gas += uint64(len(accessList)) * GasTxAccessListAddress
+		gas += uint64(accessList.StorageKeys()) * GasTxAccessListStorageKey
+	}
+	if isCreate && fork.IsActive(Shanghai) {
+		gas += wordCount(data) * GasInitCodeWord
+	}
+	if fork.IsActive(Prague) {
+		gas += uint64(authorizations) * GasTxAuthorization
+	}
+	return gas
+}
+
+// FloorDataGas returns the minimum gas a transaction is charged under
+// EIP-7623 (Prague): 21000 plus 10 per calldata token, where a zero byte
+// is one token and a non-zero byte four
+func FloorDataGas(data []byte) uint64 {
+	tokens := uint64(0)
+	for _, b := range data {
+		if b == 0 {
+			tokens++
+		} else {
+			tokens += TxTokensPerNonZeroByte
+		}
+	}
+	return GasTx + tokens*GasTxDataFloorToken
+}
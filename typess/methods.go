@@ -1,8 +1,14 @@
 package types
 
 import (
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"math"
 	"math/big"
+	"math/bits"
+
+	"github.com/morelucks/minievm/crypto"
 )
 
 // Constructor functions
@@ -32,6 +38,9 @@ func NewVM(code []byte, gasLimit uint64) *VM {
 		Stack:    NewStack(),
 		Memory:   NewMemory(),
 		Storage:  NewStorage(),
+		Fork:     Istanbul,
+		Value:    new(big.Int),
+		GasPrice: new(big.Int),
 	}
 }
 
@@ -100,22 +109,34 @@ func (vm *VM) ConsumeGas(amount uint64) error {
 	return nil
 }
 
+// RefundGas adds to the refund counter. Refunds never become spendable gas
+// during execution; they are paid out, capped, when the transaction ends.
 func (vm *VM) RefundGas(amount uint64) {
-	// Gas refunds are capped at gasLimit/2 (pre-Berlin)
-	maxRefund := vm.GasLimit / 2
-	currentRefund := vm.GasLimit - vm.Gas
-	if currentRefund+amount > maxRefund {
-		vm.Gas = vm.GasLimit - maxRefund
-	} else {
-		vm.Gas += amount
-		if vm.Gas > vm.GasLimit {
-			vm.Gas = vm.GasLimit
-		}
+	vm.Refund += amount
+}
+
+// SubRefund takes back a refund granted earlier in the transaction,
+// e.g. when a cleared storage slot is written again (EIP-2200)
+func (vm *VM) SubRefund(amount uint64) {
+	if amount > vm.Refund {
+		panic(fmt.Sprintf("refund counter below zero (gas: %d > refund: %d)", amount, vm.Refund))
+	}
+	vm.Refund -= amount
+}
+
+// CappedRefund returns the part of the refund counter paid back for a
+// transaction that used gasUsed: at most 1/2 of it before London and 1/5
+// from London (EIP-3529)
+func CappedRefund(refund, gasUsed uint64, fork Fork) uint64 {
+	quotient := RefundQuotient
+	if fork.IsActive(London) {
+		quotient = RefundQuotientEIP3529
 	}
+	return min(refund, gasUsed/quotient)
 }
 
 // MemoryExpansionGas calculates gas cost for memory expansion
-// Formula: (newSize^2 - oldSize^2) / 512 + 3 * (newSize - oldSize)
+// Formula: newSize^2/512 - oldSize^2/512 + 3 * (newSize - oldSize)
 // where sizes are in words (32 bytes)
 func MemoryExpansionGas(oldSize, newSize uint64) uint64 {
 	if newSize <= oldSize {
@@ -130,8 +151,9 @@ func MemoryExpansionGas(oldSize, newSize uint64) uint64 {
 		return 0
 	}
 
-	// Quadratic cost: (newWords^2 - oldWords^2) / 512
-	quadCost := (newWords*newWords - oldWords*oldWords) / 512
+	// Quadratic cost: newWords^2/512 - oldWords^2/512, each term rounded
+	// down as the memory cost function C_mem is
+	quadCost := newWords*newWords/512 - oldWords*oldWords/512
 
 	// Linear cost: 3 * (newWords - oldWords)
 	linearCost := 3 * (newWords - oldWords)
@@ -141,10 +163,19 @@ func MemoryExpansionGas(oldSize, newSize uint64) uint64 {
 
 // Execute runs the bytecode interpreter loop
 // Implements the execution cycle from Yellow Paper Section 9
-func (vm *VM) Execute() error {
+func (vm *VM) Execute() (err error) {
+	if vm.Tracer != nil {
+		// The instruction that halted the frame with err
+		defer func() { vm.traceStep(err) }()
+	}
+	opcode := byte(STOP)
 	for vm.HasMore() {
+		if vm.Tracer != nil {
+			vm.captureStep(vm.Code[vm.PC])
+		}
+
 		// Fetch opcode at current PC
-		opcode := vm.Fetch()
+		opcode = vm.Fetch()
 
 		// Get base gas cost for this opcode
 		gasCost := GetOpcodeGasCost(opcode)
@@ -158,6 +189,14 @@ func (vm *VM) Execute() error {
 		if err := vm.executeOpcode(opcode); err != nil {
 			return err // Execution error
 		}
+		if vm.Tracer != nil {
+			vm.traceStep(nil)
+		}
+	}
+	// Running past the end of the code is an implicit STOP
+	if vm.Tracer != nil && opcode != STOP && opcode != RETURN && opcode != SELFDESTRUCT {
+		vm.captureStep(STOP)
+		vm.traceStep(nil)
 	}
 	return nil
 }
@@ -171,90 +210,1064 @@ func (vm *VM) executeOpcode(opcode byte) error {
 		return nil
 
 	case ADD:
-		vm.Stack.Add()
+		return vm.Stack.Add()
 	case SUB:
-		vm.Stack.Sub()
+		return vm.Stack.Sub()
 	case MUL:
-		vm.Stack.Mul()
+		return vm.Stack.Mul()
 	case DIV:
-		vm.Stack.Div()
+		return vm.Stack.Div()
+	case SDIV:
+		return vm.Stack.SDiv()
 	case MOD:
-		vm.Stack.Mod()
+		return vm.Stack.Mod()
+	case SMOD:
+		return vm.Stack.SMod()
 	case ADDMOD:
-		vm.Stack.AddMod()
+		return vm.Stack.AddMod()
 	case MULMOD:
-		vm.Stack.MulMod()
+		return vm.Stack.MulMod()
 	case EXP:
-		vm.Stack.Exp()
-		// TODO: Add dynamic gas cost for EXP (50 per byte)
+		// Plus 50 gas per byte of the exponent (EIP-160)
+		if vm.Stack.Size() < 2 {
+			return ErrStackUnderflow
+		}
+		exponentBytes := (vm.Stack.PeekAt(1).ToBigInt().BitLen() + 7) / 8
+		if err := vm.ConsumeGas(GasExpByte * uint64(exponentBytes)); err != nil {
+			return err
+		}
+		return vm.Stack.Exp()
+	case SIGNEXTEND:
+		return vm.Stack.SignExtend()
 
 	case LT:
-		vm.Stack.Lt()
+		return vm.Stack.Lt()
 	case GT:
-		vm.Stack.Gt()
+		return vm.Stack.Gt()
+	case SLT:
+		return vm.Stack.Slt()
+	case SGT:
+		return vm.Stack.Sgt()
 	case EQ:
-		vm.Stack.Eq()
+		return vm.Stack.Eq()
 	case ISZERO:
-		vm.Stack.IsZero()
+		return vm.Stack.IsZero()
 
 	case AND:
-		vm.Stack.And()
+		return vm.Stack.And()
 	case OR:
-		vm.Stack.Or()
+		return vm.Stack.Or()
 	case XOR:
-		vm.Stack.Xor()
+		return vm.Stack.Xor()
 	case NOT:
-		vm.Stack.Not()
+		return vm.Stack.Not()
 	case BYTE:
-		vm.Stack.Byte()
+		return vm.Stack.Byte()
 	case SHL:
-		vm.Stack.Shl()
+		return vm.Stack.Shl()
 	case SHR:
-		vm.Stack.Shr()
+		return vm.Stack.Shr()
+	case SAR:
+		return vm.Stack.Sar()
+	case CLZ:
+		if !vm.Fork.IsActive(Osaka) {
+			return fmt.Errorf("%w: 0x%02x", ErrInvalidOpcode, opcode)
+		}
+		return vm.Stack.Clz()
+
+	case KECCAK256:
+		args, err := vm.Stack.PopN(2)
+		if err != nil {
+			return err
+		}
+		offset, size, err := vm.memoryRange(args[0], args[1])
+		if err != nil {
+			return err
+		}
+		if err := vm.ConsumeGas(GasSHA3Word * ((size + 31) / 32)); err != nil {
+			return err
+		}
+		return vm.Stack.Push(Word(crypto.Keccak256(vm.Memory.GetCopy(offset, size))))
 
 	case POP:
-		vm.Stack.Pop()
+		_, err := vm.Stack.Pop()
+		return err
 
+	case MLOAD:
+		offsetWord, err := vm.Stack.Pop()
+		if err != nil {
+			return err
+		}
+		offset, _, err := vm.memoryRange(offsetWord, NewWord(32))
+		if err != nil {
+			return err
+		}
+		return vm.Stack.Push(NewWordFromBytes(vm.Memory.GetCopy(offset, 32)))
+	case MSTORE:
+		args, err := vm.Stack.PopN(2)
+		if err != nil {
+			return err
+		}
+		offset, _, err := vm.memoryRange(args[0], NewWord(32))
+		if err != nil {
+			return err
+		}
+		value := args[1]
+		vm.Memory.Set(offset, value[:])
+	case MSTORE8:
+		args, err := vm.Stack.PopN(2)
+		if err != nil {
+			return err
+		}
+		offset, _, err := vm.memoryRange(args[0], NewWord(1))
+		if err != nil {
+			return err
+		}
+		value := args[1]
+		vm.Memory.Set(offset, value[31:])
+	case SLOAD:
+		key, err := vm.Stack.Pop()
+		if err != nil {
+			return err
+		}
+		if err := vm.ConsumeGas(vm.sloadGas(key)); err != nil {
+			return err
+		}
+		return vm.Stack.Push(vm.loadSlot(key))
+	case SSTORE:
+		if vm.ReadOnly {
+			return ErrWriteProtection
+		}
+		if vm.Gas <= GasSStoreSentry {
+			return &OutOfGasError{Required: GasSStoreSentry + 1, Remaining: vm.Gas}
+		}
+		args, err := vm.Stack.PopN(2)
+		if err != nil {
+			return err
+		}
+		key, value := args[0], args[1]
+		if err := vm.ConsumeGas(vm.sstoreGas(key, value)); err != nil {
+			return err
+		}
+		if vm.State != nil {
+			vm.State.SetState(vm.Address, key, value)
+		} else {
+			vm.Storage.Store(key, value)
+		}
+
+	case TLOAD:
+		if !vm.Fork.IsActive(Cancun) {
+			return fmt.Errorf("%w: 0x%02x", ErrInvalidOpcode, opcode)
+		}
+		key, err := vm.Stack.Pop()
+		if err != nil {
+			return err
+		}
+		if vm.State == nil {
+			return vm.Stack.Push(vm.transientStorage().Load(key))
+		}
+		return vm.Stack.Push(vm.State.GetTransientState(vm.Address, key))
+	case TSTORE:
+		if !vm.Fork.IsActive(Cancun) {
+			return fmt.Errorf("%w: 0x%02x", ErrInvalidOpcode, opcode)
+		}
+		if vm.ReadOnly {
+			return ErrWriteProtection
+		}
+		args, err := vm.Stack.PopN(2)
+		if err != nil {
+			return err
+		}
+		if vm.State == nil {
+			vm.transientStorage().Store(args[0], args[1])
+		} else {
+			vm.State.SetTransientState(vm.Address, args[0], args[1])
+		}
+
+	case JUMP:
+		dest, err := vm.Stack.Pop()
+		if err != nil {
+			return err
+		}
+		return vm.jump(dest)
+	case JUMPI:
+		args, err := vm.Stack.PopN(2)
+		if err != nil {
+			return err
+		}
+		if dest, cond := args[0], args[1]; cond != (Word{}) {
+			return vm.jump(dest)
+		}
+	case JUMPDEST:
+		// Only marks a valid destination
+	case PC:
+		return vm.Stack.Push(NewWord(vm.PC - 1))
+	case MCOPY:
+		if !vm.Fork.IsActive(Cancun) {
+			return fmt.Errorf("%w: 0x%02x", ErrInvalidOpcode, opcode)
+		}
+		args, err := vm.Stack.PopN(3)
+		if err != nil {
+			return err
+		}
+		// Memory expands to cover both ranges, which may overlap
+		dest, length, err := vm.memoryRange(args[0], args[2])
+		if err != nil {
+			return err
+		}
+		src, _, err := vm.memoryRange(args[1], args[2])
+		if err != nil {
+			return err
+		}
+		if err := vm.ConsumeGas(GasCopyWord * ((length + 31) / 32)); err != nil {
+			return err
+		}
+		vm.Memory.Set(dest, vm.Memory.GetCopy(src, length))
+	case MSIZE:
+		return vm.Stack.Push(NewWord(vm.Memory.Len()))
+	case GAS:
+		return vm.Stack.Push(NewWord(vm.Gas))
+
+	case BLOCKHASH:
+		// Only the 256 most recent complete blocks are available
+		numberWord, err := vm.Stack.Pop()
+		if err != nil {
+			return err
+		}
+		number, ok := numberWord.ToUint64()
+		block := vm.block()
+		if ok && number < block.Number && number+256 >= block.Number && block.GetHash != nil {
+			return vm.Stack.Push(block.GetHash(number))
+		}
+		return vm.Stack.Push(Word{})
+	case COINBASE:
+		return vm.Stack.Push(NewWordFromBytes(vm.block().Coinbase[:]))
+	case TIMESTAMP:
+		return vm.Stack.Push(NewWord(vm.block().Time))
+	case NUMBER:
+		return vm.Stack.Push(NewWord(vm.block().Number))
+	case PREVRANDAO:
+		block := vm.block()
+		switch {
+		case block.Random != nil:
+			return vm.Stack.Push(*block.Random)
+		case block.Difficulty != nil:
+			return vm.Stack.Push(BigIntToWord(block.Difficulty))
+		}
+		return vm.Stack.Push(Word{})
+	case GASLIMIT:
+		return vm.Stack.Push(NewWord(vm.block().GasLimit))
+	case CHAINID:
+		return vm.Stack.Push(BigIntToWord(chainIDOf(vm.block())))
+	case BLOBHASH:
+		if !vm.Fork.IsActive(Cancun) {
+			return fmt.Errorf("%w: 0x%02x", ErrInvalidOpcode, opcode)
+		}
+		indexWord, err := vm.Stack.Pop()
+		if err != nil {
+			return err
+		}
+		index, ok := indexWord.ToUint64()
+		if ok && index < uint64(len(vm.BlobHashes)) {
+			return vm.Stack.Push(vm.BlobHashes[index])
+		}
+		return vm.Stack.Push(Word{})
+	case BLOBBASEFEE:
+		if !vm.Fork.IsActive(Cancun) {
+			return fmt.Errorf("%w: 0x%02x", ErrInvalidOpcode, opcode)
+		}
+		blobBaseFee := vm.block().BlobBaseFee
+		if blobBaseFee == nil {
+			blobBaseFee = new(big.Int)
+		}
+		return vm.Stack.Push(BigIntToWord(blobBaseFee))
+	case BASEFEE:
+		if !vm.Fork.IsActive(London) {
+			return fmt.Errorf("%w: 0x%02x", ErrInvalidOpcode, opcode)
+		}
+		baseFee := vm.block().BaseFee
+		if baseFee == nil {
+			baseFee = new(big.Int)
+		}
+		return vm.Stack.Push(BigIntToWord(baseFee))
+
+	case ADDRESS:
+		return vm.Stack.Push(NewWordFromBytes(vm.Address[:]))
+	case BALANCE:
+		addrWord, err := vm.Stack.Pop()
+		if err != nil {
+			return err
+		}
+		addr := addrWord.ToAddress()
+		if err := vm.ConsumeGas(vm.accountAccessGas(addr, GasBalance)); err != nil {
+			return err
+		}
+		return vm.Stack.Push(vm.balanceOf(addr))
+	case SELFBALANCE:
+		return vm.Stack.Push(vm.balanceOf(vm.Address))
+	case ORIGIN:
+		return vm.Stack.Push(NewWordFromBytes(vm.Origin[:]))
+	case CALLER:
+		return vm.Stack.Push(NewWordFromBytes(vm.Caller[:]))
+	case CALLVALUE:
+		return vm.Stack.Push(BigIntToWord(vm.Value))
+	case GASPRICE:
+		return vm.Stack.Push(BigIntToWord(vm.GasPrice))
+	case CALLDATALOAD:
+		offsetWord, err := vm.Stack.Pop()
+		if err != nil {
+			return err
+		}
+		offset, ok := offsetWord.ToUint64()
+		if !ok {
+			offset = ^uint64(0)
+		}
+		return vm.Stack.Push(NewWordFromBytes(sliceData(vm.Input, offset, 32)))
+	case CALLDATASIZE:
+		return vm.Stack.Push(NewWord(uint64(len(vm.Input))))
+	case CODESIZE:
+		return vm.Stack.Push(NewWord(uint64(len(vm.Code))))
+	case CALLDATACOPY:
+		return vm.copyToMemory(vm.Input)
+	case CODECOPY:
+		return vm.copyToMemory(vm.Code)
+	case EXTCODESIZE:
+		addr, err := vm.accessAccount(GasExtCode)
+		if err != nil {
+			return err
+		}
+		return vm.Stack.Push(NewWord(uint64(len(vm.codeAt(addr)))))
+	case EXTCODECOPY:
+		addr, err := vm.accessAccount(GasExtCode)
+		if err != nil {
+			return err
+		}
+		return vm.copyToMemory(vm.codeAt(addr))
+	case EXTCODEHASH:
+		// Empty accounts hash to zero; delegated EOAs hash their designator
+		addr, err := vm.accessAccount(GasExtCode)
+		if err != nil {
+			return err
+		}
+		if vm.State == nil || vm.State.Empty(addr) {
+			return vm.Stack.Push(Word{})
+		}
+		return vm.Stack.Push(Word(crypto.Keccak256(vm.State.GetCode(addr))))
+
+	case RETURNDATASIZE:
+		return vm.Stack.Push(NewWord(uint64(len(vm.ReturnData))))
+	case RETURNDATACOPY:
+		args, err := vm.Stack.PopN(3)
+		if err != nil {
+			return err
+		}
+		offset, length, err := vm.memoryRange(args[0], args[2])
+		if err != nil {
+			return err
+		}
+		if err := vm.ConsumeGas(GasCopyWord * ((length + 31) / 32)); err != nil {
+			return err
+		}
+		// Checked once the copy is paid for, as geth does
+		dataOffset, ok := args[1].ToUint64()
+		if !ok || dataOffset+length < dataOffset || dataOffset+length > uint64(len(vm.ReturnData)) {
+			return ErrReturnDataOutOfBounds
+		}
+		vm.Memory.Set(offset, vm.ReturnData[dataOffset:dataOffset+length])
+
+	case PUSH0:
+		if !vm.Fork.IsActive(Shanghai) {
+			return fmt.Errorf("%w: 0x%02x", ErrInvalidOpcode, opcode)
+		}
+		return vm.Stack.Push(Word{})
 	case PUSH1, PUSH2, PUSH3, PUSH4, PUSH5, PUSH6, PUSH7, PUSH8,
 		PUSH9, PUSH10, PUSH11, PUSH12, PUSH13, PUSH14, PUSH15, PUSH16,
 		PUSH17, PUSH18, PUSH19, PUSH20, PUSH21, PUSH22, PUSH23, PUSH24,
 		PUSH25, PUSH26, PUSH27, PUSH28, PUSH29, PUSH30, PUSH31, PUSH32:
-		// PUSH operations: read 1-32 immediate bytes
+		// PUSH operations: read 1-32 immediate bytes. Bytes past the end
+		// of the code read as zero.
 		bytesToRead := int(opcode - PUSH1 + 1)
-		if !vm.HasMore() || vm.PC+uint64(bytesToRead) > uint64(len(vm.Code)) {
-			return fmt.Errorf("invalid PUSH: not enough bytes")
-		}
 
 		// Read immediate bytes
 		immediate := make([]byte, 32) // Always 32 bytes, left-padded
 		startIdx := 32 - bytesToRead
-		for i := 0; i < bytesToRead; i++ {
+		for i := 0; i < bytesToRead && vm.HasMore(); i++ {
 			immediate[startIdx+i] = vm.Fetch()
 		}
 
 		// Push to stack
-		vm.Stack.Push(NewWordFromBytes(immediate))
+		return vm.Stack.Push(NewWordFromBytes(immediate))
 
 	case DUP1, DUP2, DUP3, DUP4, DUP5, DUP6, DUP7, DUP8,
 		DUP9, DUP10, DUP11, DUP12, DUP13, DUP14, DUP15, DUP16:
 		// DUP operations: duplicate stack item at position (opcode - DUP1)
 		index := int(opcode - DUP1)
-		vm.Stack.Dup(index)
+		return vm.Stack.Dup(index)
 
 	case SWAP1, SWAP2, SWAP3, SWAP4, SWAP5, SWAP6, SWAP7, SWAP8,
 		SWAP9, SWAP10, SWAP11, SWAP12, SWAP13, SWAP14, SWAP15, SWAP16:
-		// SWAP operations: swap top with item at position (opcode - SWAP1)
-		index := int(opcode - SWAP1)
-		vm.Stack.Swap(index)
+		// SWAP operations: swap top with item at position (opcode - SWAP1 + 1)
+		index := int(opcode-SWAP1) + 1
+		return vm.Stack.Swap(index)
+
+	case DUPN, SWAPN, EXCHANGE:
+		// EIP-663: the stack position is taken from a one byte immediate
+		if !vm.EnableEIP663 {
+			return fmt.Errorf("%w: 0x%02x", ErrInvalidOpcode, opcode)
+		}
+		if !vm.HasMore() {
+			return fmt.Errorf("%w 0x%02x: missing immediate", ErrInvalidOpcode, opcode)
+		}
+		imm := vm.Fetch()
+		switch opcode {
+		case DUPN:
+			return vm.Stack.Dup(int(imm))
+		case SWAPN:
+			return vm.Stack.Swap(int(imm) + 1)
+		case EXCHANGE:
+			// High nibble selects the first item, low nibble the distance to the second
+			n := int(imm>>4) + 1
+			m := int(imm&0x0f) + 1
+			return vm.Stack.Exchange(n, n+m)
+		}
+
+	case CALL, CALLCODE, DELEGATECALL, STATICCALL:
+		return vm.call(opcode)
+
+	case CREATE, CREATE2:
+		return vm.create(opcode)
+
+	case SELFDESTRUCT:
+		return vm.selfDestruct()
+
+	case LOG0, LOG1, LOG2, LOG3, LOG4:
+		if vm.ReadOnly {
+			return ErrWriteProtection
+		}
+		args, err := vm.Stack.PopN(2 + int(opcode-LOG0))
+		if err != nil {
+			return err
+		}
+		offset, size, err := vm.memoryRange(args[0], args[1])
+		if err != nil {
+			return err
+		}
+		if err := vm.ConsumeGas(GasLogData * size); err != nil {
+			return err
+		}
+		topics := args[2:]
+		vm.Logs = append(vm.Logs, &Log{Address: vm.Address, Topics: topics, Data: vm.Memory.GetCopy(offset, size)})
+
+	case RETURN, REVERT:
+		args, err := vm.Stack.PopN(2)
+		if err != nil {
+			return err
+		}
+		offset, size, err := vm.memoryRange(args[0], args[1])
+		if err != nil {
+			return err
+		}
+		vm.Output = vm.Memory.GetCopy(offset, size)
+		vm.SetPC(uint64(len(vm.Code))) // Halt
+		if opcode == REVERT {
+			return ErrExecutionReverted
+		}
 
 	default:
 		// Unknown/invalid opcode
-		return fmt.Errorf("invalid opcode: 0x%02x", opcode)
+		return fmt.Errorf("%w: 0x%02x", ErrInvalidOpcode, opcode)
+	}
+
+	return nil
+}
+
+// call implements CALL, CALLCODE, DELEGATECALL and STATICCALL. The callee
+// runs in a child frame sharing the world state; without a state only
+// precompiles can be called and other calls succeed with empty return data.
+func (vm *VM) call(opcode byte) error {
+	argCount := 6
+	if opcode == CALL || opcode == CALLCODE {
+		argCount = 7
+	}
+	args, err := vm.Stack.PopN(argCount)
+	if err != nil {
+		return err
+	}
+	gasWord, addr, args := args[0], args[1].ToAddress(), args[2:]
+	value := Word{}
+	if opcode == CALL || opcode == CALLCODE {
+		value, args = args[0], args[1:]
+	}
+	argsOffset, argsSize := args[0], args[1]
+	retOffset, retSize := args[2], args[3]
+
+	transfersValue := value != Word{}
+	if opcode == CALL && transfersValue && vm.ReadOnly {
+		return ErrWriteProtection
+	}
+
+	// Accessing the callee (EIP-2929) and, for a delegated EOA, the
+	// account its code is loaded from (EIP-7702)
+	accessGas := vm.accountAccessGas(addr, GasCall)
+	code := vm.codeAt(addr)
+	if target, ok := ParseDelegation(code); ok && vm.Fork.IsActive(Prague) {
+		accessGas += vm.accountAccessGas(target, GasCall)
+		code = vm.codeAt(target)
+	}
+	if err := vm.ConsumeGas(accessGas); err != nil {
+		return err
+	}
+
+	inOffset, inSize, err := vm.memoryRange(argsOffset, argsSize)
+	if err != nil {
+		return err
+	}
+	outOffset, outSize, err := vm.memoryRange(retOffset, retSize)
+	if err != nil {
+		return err
+	}
+
+	if transfersValue {
+		valueGas := GasCallValue
+		if opcode == CALL && vm.State != nil && vm.State.Empty(addr) {
+			valueGas += GasNewAccount // EIP-161: only value transfers create accounts
+		}
+		if err := vm.ConsumeGas(valueGas); err != nil {
+			return err
+		}
+	}
+
+	// EIP-150: forward at most all but one 64th of the remaining gas
+	callGas := vm.Gas - vm.Gas/64
+	if requested, ok := gasWord.ToUint64(); ok && requested < callGas {
+		callGas = requested
+	}
+	if err := vm.ConsumeGas(callGas); err != nil {
+		return err
+	}
+	if transfersValue {
+		callGas += GasCallStipend
+	}
+
+	input := vm.Memory.GetCopy(inOffset, inSize)
+	output, gasLeft, err := vm.callFrame(opcode, addr, code, input, value.ToBigInt(), callGas)
+
+	// Return unused gas to the caller
+	vm.Gas += gasLeft
+	vm.ReturnData = output
+	if len(output) > int(outSize) {
+		output = output[:outSize]
+	}
+	vm.Memory.Set(outOffset, output)
+
+	if err == nil {
+		return vm.Stack.Push(NewWord(1))
+	}
+	return vm.Stack.Push(NewWord(0))
+}
+
+// callFrame runs a message call from this frame with the given gas and
+// returns its output and unused gas. On failure the state is reverted; a
+// revert keeps the output and gas, other errors consume both.
+func (vm *VM) callFrame(opcode byte, addr Address, code, input []byte, value *big.Int, gas uint64) (output []byte, gasLeft uint64, err error) {
+	// The call is traced before the instructions of the frame it starts,
+	// and the frame once it returns
+	if vm.Tracer != nil {
+		vm.traceStep(nil)
+		defer func() { vm.traceExit(output, gas-gasLeft, err) }()
+	}
+	// Too deep calls and unaffordable transfers fail before starting
+	if vm.Depth >= CallDepth {
+		return nil, gas, ErrDepth
+	}
+	if vm.State == nil {
+		if !vm.IsPrecompile(addr) {
+			return nil, gas, nil
+		}
+		output, gasLeft, err = vm.RunPrecompile(addr, input, gas)
+		if err != nil {
+			return nil, 0, err
+		}
+		return output, gasLeft, nil
+	}
+	if value.Sign() != 0 && vm.State.GetBalance(vm.Address).Cmp(value) < 0 {
+		return nil, gas, ErrInsufficientBalance
+	}
+
+	snapshot := vm.State.Snapshot()
+	child := vm.newChild(code, gas, input)
+	switch opcode {
+	case CALL:
+		child.Address, child.Caller, child.Value = addr, vm.Address, value
+		if value.Sign() != 0 {
+			vm.State.SubBalance(vm.Address, value)
+			vm.State.AddBalance(addr, value)
+		} else {
+			vm.State.Touch(addr)
+		}
+	case CALLCODE:
+		// Runs addr's code on this account; the value goes to itself
+		child.Address, child.Caller, child.Value = vm.Address, vm.Address, value
+	case DELEGATECALL:
+		// Runs addr's code on this account, keeping the caller and value
+		child.Address, child.Caller, child.Value = vm.Address, vm.Caller, vm.Value
+	case STATICCALL:
+		child.Address, child.Caller = addr, vm.Address
+		child.ReadOnly = true
+		vm.State.Touch(addr)
+	}
+
+	switch {
+	case vm.IsPrecompile(addr):
+		output, child.Gas, err = vm.RunPrecompile(addr, input, gas)
+	case len(code) == 0:
+		// Nothing to run
+	default:
+		child.Storage = vm.State.GetOrNewAccount(child.Address).Storage
+		err = child.Execute()
+		output = child.Output
+	}
+
+	if err != nil {
+		vm.State.RevertToSnapshot(snapshot)
+		if errors.Is(err, ErrExecutionReverted) {
+			return output, child.Gas, err
+		}
+		return nil, 0, err
+	}
+	vm.Refund = child.Refund
+	vm.Logs = append(vm.Logs, child.Logs...)
+	return output, child.Gas, nil
+}
+
+// create implements CREATE and CREATE2: the init code taken from memory
+// runs in a child frame and its output becomes the code of the new
+// account, whose address is pushed (0 on failure)
+func (vm *VM) create(opcode byte) error {
+	if vm.ReadOnly {
+		return ErrWriteProtection
+	}
+	argCount := 3
+	if opcode == CREATE2 {
+		argCount = 4
+	}
+	args, err := vm.Stack.PopN(argCount)
+	if err != nil {
+		return err
+	}
+	value := args[0].ToBigInt()
+	offset, size, err := vm.memoryRange(args[1], args[2])
+	if err != nil {
+		return err
+	}
+	words := (size + 31) / 32
+	if opcode == CREATE2 {
+		// Hashing the init code for the address
+		if err := vm.ConsumeGas(GasSHA3Word * words); err != nil {
+			return err
+		}
+	}
+	if vm.Fork.IsActive(Shanghai) {
+		// EIP-3860: limit and meter init code
+		if size > MaxInitCodeSize {
+			return ErrMaxInitCodeSize
+		}
+		if err := vm.ConsumeGas(GasInitCodeWord * words); err != nil {
+			return err
+		}
+	}
+
+	vm.ReturnData = nil
+	if vm.State == nil {
+		// Without a world state there is nowhere to deploy
+		return vm.Stack.Push(Word{})
+	}
+
+	// The creation is traced before the instructions of the init code and,
+	// unlike a call, without the gas it passes on
+	if vm.Tracer != nil {
+		vm.traceStep(nil)
+	}
+
+	// EIP-150: forward all but one 64th of the remaining gas
+	gas := vm.Gas - vm.Gas/64
+	if err := vm.ConsumeGas(gas); err != nil {
+		return err
+	}
+
+	initCode := vm.Memory.GetCopy(offset, size)
+	addr := CreateAddress(vm.Address, vm.State.GetNonce(vm.Address))
+	if opcode == CREATE2 {
+		addr = Create2Address(vm.Address, args[3], initCode)
+	}
+	output, gasLeft, err := vm.createFrame(addr, initCode, value, gas)
+
+	// Return unused gas to the caller; only a revert returns data
+	vm.Gas += gasLeft
+	if errors.Is(err, ErrExecutionReverted) {
+		vm.ReturnData = output
+	}
+	if err != nil {
+		return vm.Stack.Push(Word{})
+	}
+	return vm.Stack.Push(NewWordFromBytes(addr[:]))
+}
+
+// createFrame deploys a contract at addr from this frame and returns the
+// output of reverted init code and the unused gas. Failures are handled as
+// in callFrame; a collision at addr consumes the gas.
+func (vm *VM) createFrame(addr Address, initCode []byte, value *big.Int, gas uint64) (output []byte, gasLeft uint64, err error) {
+	if vm.Tracer != nil {
+		// The frame returns the deployed code
+		defer func() {
+			traced := output
+			if err == nil {
+				traced = vm.State.GetCode(addr)
+			}
+			vm.traceExit(traced, gas-gasLeft, err)
+		}()
+	}
+	if vm.Depth >= CallDepth {
+		return nil, gas, ErrDepth
+	}
+	if vm.State.GetBalance(vm.Address).Cmp(value) < 0 {
+		return nil, gas, ErrInsufficientBalance
+	}
+	nonce := vm.State.GetNonce(vm.Address)
+	if nonce == math.MaxUint64 {
+		return nil, gas, ErrNonceMax
+	}
+	vm.State.SetNonce(vm.Address, nonce+1)
+	if vm.Fork.IsActive(Berlin) {
+		vm.State.AddAddressToAccessList(addr)
+	}
+	if vm.State.hasContract(addr) {
+		return nil, 0, ErrContractAddressCollision
+	}
+
+	snapshot := vm.State.Snapshot()
+	vm.State.SetNonce(addr, 1) // EIP-161
+	vm.State.markCreated(addr)
+	vm.State.SubBalance(vm.Address, value)
+	vm.State.AddBalance(addr, value)
+
+	child := vm.newChild(initCode, gas, nil)
+	child.Address, child.Caller, child.Value = addr, vm.Address, value
+	child.Storage = vm.State.GetOrNewAccount(addr).Storage
+	err = child.Execute()
+	output = child.Output
+	if err == nil {
+		err = checkDeployedCode(vm.Fork, output, child.Gas)
+	}
+	if err != nil {
+		vm.State.RevertToSnapshot(snapshot)
+		if errors.Is(err, ErrExecutionReverted) {
+			return output, child.Gas, err
+		}
+		return nil, 0, err
+	}
+	vm.State.SetCode(addr, output)
+	vm.Refund = child.Refund
+	vm.Logs = append(vm.Logs, child.Logs...)
+	return nil, child.Gas - uint64(len(output))*GasCodeDeposit, nil
+}
+
+// selfDestruct implements SELFDESTRUCT: the balance of the account goes
+// to the beneficiary and the frame halts. From Cancun the account is only
+// deleted if it was created in the same transaction (EIP-6780).
+func (vm *VM) selfDestruct() error {
+	if vm.ReadOnly {
+		return ErrWriteProtection
+	}
+	beneficiaryWord, err := vm.Stack.Pop()
+	if err != nil {
+		return err
+	}
+	beneficiary := beneficiaryWord.ToAddress()
+	if vm.State == nil {
+		vm.SetPC(uint64(len(vm.Code))) // Halt
+		return nil
+	}
+
+	// EIP-2929 charges a cold beneficiary but not a warm one
+	var gas uint64
+	if vm.Fork.IsActive(Berlin) && !vm.State.AddressInAccessList(beneficiary) {
+		vm.State.AddAddressToAccessList(beneficiary)
+		gas += GasColdAccountAccess
+	}
+	balance := vm.State.GetBalance(vm.Address)
+	if balance.Sign() != 0 && vm.State.Empty(beneficiary) {
+		gas += GasNewAccount // EIP-161
+	}
+	if err := vm.ConsumeGas(gas); err != nil {
+		return err
+	}
+	if !vm.Fork.IsActive(London) && !vm.State.HasSelfDestructed(vm.Address) {
+		vm.Refund += GasSelfDestructRefund
+	}
+	// geth traces the transfer as a frame of its own, after the instruction
+	if vm.Tracer != nil {
+		vm.traceStep(nil)
+		vm.traceExit(nil, 0, nil)
+	}
+
+	vm.State.SubBalance(vm.Address, balance)
+	vm.State.AddBalance(beneficiary, balance)
+	if !vm.Fork.IsActive(Cancun) || vm.State.isNewContract(vm.Address) {
+		vm.State.SelfDestruct(vm.Address)
+	}
+	vm.SetPC(uint64(len(vm.Code))) // Halt
+	return nil
+}
+
+// newChild creates the frame of a message call made by vm. The caller sets
+// the addresses and value.
+func (vm *VM) newChild(code []byte, gas uint64, input []byte) *VM {
+	child := NewVM(code, gas)
+	child.Fork = vm.Fork
+	child.EnableEIP663 = vm.EnableEIP663
+	child.State = vm.State
+	child.Block = vm.Block
+	child.Origin = vm.Origin
+	child.GasPrice = vm.GasPrice
+	child.BlobHashes = vm.BlobHashes
+	child.Input = input
+	child.Depth = vm.Depth + 1
+	child.ReadOnly = vm.ReadOnly
+	child.Refund = vm.Refund
+	child.Tracer = vm.Tracer
+	child.precompiles = vm.precompiles
+	return child
+}
+
+// resolveCode returns the code run when addr is called: the code of the
+// account it delegates to from Prague (EIP-7702), otherwise its own
+func resolveCode(state *StateDB, fork Fork, addr Address) []byte {
+	code := state.GetCode(addr)
+	if target, ok := ParseDelegation(code); ok && fork.IsActive(Prague) {
+		return state.GetCode(target)
+	}
+	return code
+}
+
+// jump moves the PC to dest, which must be a JUMPDEST opcode rather than
+// part of a PUSH immediate
+func (vm *VM) jump(dest Word) error {
+	pc, ok := dest.ToUint64()
+	if vm.jumpdests == nil {
+		vm.jumpdests = analyzeJumpdests(vm.Code)
+	}
+	if !ok || pc >= uint64(len(vm.Code)) || !vm.jumpdests[pc] {
+		return ErrInvalidJump
+	}
+	vm.SetPC(pc)
+	return nil
+}
+
+// analyzeJumpdests marks the JUMPDEST opcodes of code, skipping over the
+// immediates of PUSH1..PUSH32
+func analyzeJumpdests(code []byte) []bool {
+	dests := make([]bool, len(code))
+	for pc := 0; pc < len(code); pc++ {
+		switch op := code[pc]; {
+		case op == JUMPDEST:
+			dests[pc] = true
+		case op >= PUSH1 && op <= PUSH32:
+			pc += int(op - PUSH1 + 1)
+		}
+	}
+	return dests
+}
+
+// block returns the block the frame runs in; a bare VM sees an empty one
+func (vm *VM) block() *BlockContext {
+	if vm.Block == nil {
+		return &BlockContext{}
+	}
+	return vm.Block
+}
+
+// codeAt returns the code stored at addr, without following delegations
+func (vm *VM) codeAt(addr Address) []byte {
+	if vm.State == nil {
+		return nil
+	}
+	return vm.State.GetCode(addr)
+}
+
+// accessAccount pops an address for the EXTCODE* opcodes and charges for
+// accessing it
+func (vm *VM) accessAccount(istanbulCost uint64) (Address, error) {
+	addrWord, err := vm.Stack.Pop()
+	if err != nil {
+		return Address{}, err
 	}
+	addr := addrWord.ToAddress()
+	return addr, vm.ConsumeGas(vm.accountAccessGas(addr, istanbulCost))
+}
 
+// accountAccessGas marks addr as accessed and returns the cost of touching
+// it: istanbulCost before Berlin, then warm or cold access (EIP-2929)
+func (vm *VM) accountAccessGas(addr Address, istanbulCost uint64) uint64 {
+	if !vm.Fork.IsActive(Berlin) {
+		return istanbulCost
+	}
+	if vm.State == nil || vm.State.AddressInAccessList(addr) {
+		return GasWarmStorageRead
+	}
+	vm.State.AddAddressToAccessList(addr)
+	return GasColdAccountAccess
+}
+
+// copyToMemory implements the *COPY opcodes that read from a byte source:
+// it pops the memory offset, source offset and length and charges the
+// expansion and per-word costs. Bytes past the end of source read as zero.
+func (vm *VM) copyToMemory(source []byte) error {
+	args, err := vm.Stack.PopN(3)
+	if err != nil {
+		return err
+	}
+	dataOffset, ok := args[1].ToUint64()
+	if !ok {
+		dataOffset = ^uint64(0)
+	}
+	offset, length, err := vm.memoryRange(args[0], args[2])
+	if err != nil {
+		return err
+	}
+	if err := vm.ConsumeGas(GasCopyWord * ((length + 31) / 32)); err != nil {
+		return err
+	}
+	vm.Memory.Set(offset, sliceData(source, dataOffset, length))
 	return nil
 }
 
+// accessSlot marks a storage slot of the running contract as accessed and
+// returns the EIP-2929 surcharge if this is its first access (Berlin)
+func (vm *VM) accessSlot(key Word) uint64 {
+	if !vm.Fork.IsActive(Berlin) || vm.State == nil || vm.State.SlotInAccessList(vm.Address, key) {
+		return 0
+	}
+	vm.State.AddSlotToAccessList(vm.Address, key)
+	return GasColdSLoad
+}
+
+// sloadGas prices SLOAD: a flat 800 before Berlin, then warm or cold access
+func (vm *VM) sloadGas(key Word) uint64 {
+	if !vm.Fork.IsActive(Berlin) {
+		return GasSLoad
+	}
+	if cold := vm.accessSlot(key); cold != 0 {
+		return cold
+	}
+	return GasWarmStorageRead
+}
+
+// sstoreGas prices SSTORE with net gas metering (EIP-2200, adjusted by
+// EIP-2929 and EIP-3529) and updates the refund counter. The cost depends on
+// the slot's value at the start of the transaction (original), its current
+// value and the new value. Without a world state the current value is taken
+// as the original.
+func (vm *VM) sstoreGas(key, value Word) uint64 {
+	readCost, resetCost, clearRefund := GasSStoreNoop, GasSStoreReset, GasSStoreClearRefund
+	if vm.Fork.IsActive(Berlin) {
+		readCost, resetCost = GasWarmStorageRead, GasSStoreReset-GasColdSLoad
+	}
+	if vm.Fork.IsActive(London) {
+		clearRefund = GasSStoreClearRefundEIP3529
+	}
+	cost := vm.accessSlot(key)
+
+	current := vm.loadSlot(key)
+	original := current
+	if vm.State != nil {
+		original = vm.State.GetCommittedState(vm.Address, key)
+	}
+	zero := Word{}
+
+	if current == value {
+		return cost + readCost // No-op
+	}
+	if original == current {
+		// First write to the slot in this transaction
+		if original == zero {
+			return cost + GasSStore
+		}
+		if value == zero {
+			vm.RefundGas(clearRefund)
+		}
+		return cost + resetCost
+	}
+
+	// The slot is already dirty: only refunds change
+	if original != zero {
+		if current == zero {
+			vm.SubRefund(clearRefund) // Undo the refund for clearing it earlier
+		} else if value == zero {
+			vm.RefundGas(clearRefund)
+		}
+	}
+	if original == value {
+		// Reset to the original value: refund the difference to a plain read
+		if original == zero {
+			vm.RefundGas(GasSStore - readCost)
+		} else {
+			vm.RefundGas(resetCost - readCost)
+		}
+	}
+	return cost + readCost
+}
+
+// loadSlot reads a storage slot of the running contract. With a world state
+// it is read from there, where SSTORE writes and reverts apply.
+func (vm *VM) loadSlot(key Word) Word {
+	if vm.State != nil {
+		return vm.State.GetState(vm.Address, key)
+	}
+	return vm.Storage.Load(key)
+}
+
+// transientStorage returns the transient storage of a VM running without a
+// world state
+func (vm *VM) transientStorage() *Storage {
+	if vm.transient == nil {
+		vm.transient = NewStorage()
+	}
+	return vm.transient
+}
+
+// balanceOf returns the balance of addr as a Word, zero without a state
+func (vm *VM) balanceOf(addr Address) Word {
+	if vm.State == nil {
+		return Word{}
+	}
+	return BigIntToWord(vm.State.GetBalance(addr))
+}
+
+// memoryRange converts an offset/size pair taken from the stack, charges
+// memory expansion gas and grows memory to cover it.
+// A zero size never expands memory, whatever the offset.
+func (vm *VM) memoryRange(offsetWord, sizeWord Word) (uint64, uint64, error) {
+	size, ok := sizeWord.ToUint64()
+	if !ok || size > MaxMemorySize {
+		return 0, 0, &OutOfGasError{Required: vm.Gas + 1, Remaining: vm.Gas}
+	}
+	if size == 0 {
+		return 0, 0, nil
+	}
+	offset, ok := offsetWord.ToUint64()
+	if !ok || offset > MaxMemorySize-size {
+		return 0, 0, &OutOfGasError{Required: vm.Gas + 1, Remaining: vm.Gas}
+	}
+
+	end := offset + size
+	if err := vm.ConsumeGas(MemoryExpansionGas(vm.Memory.Len(), end)); err != nil {
+		return 0, 0, err
+	}
+	vm.Memory.Resize(end)
+	return offset, size, nil
+}
+
 // GetOpcodeGasCost returns the base gas cost for an opcode (Istanbul fork)
 // Note: Some opcodes have dynamic costs (EXP, SHA3, memory ops, etc.)
 // that need additional calculation
@@ -264,14 +1277,20 @@ func GetOpcodeGasCost(opcode byte) uint64 {
 		return GasZero
 	case ADD, SUB, LT, GT, SLT, SGT, EQ, AND, OR, XOR, NOT, BYTE, SHL, SHR, SAR, ISZERO:
 		return GasVeryLow
-	case MUL, DIV, SDIV, MOD, SMOD, SIGNEXTEND:
+	case MUL, DIV, SDIV, MOD, SMOD, SIGNEXTEND, CLZ:
 		return GasLow
 	case ADDMOD, MULMOD:
 		return GasMid
 	case EXP:
 		return GasExp // Base cost, additional cost per byte
-	case POP:
+	case POP, PC, PUSH0:
 		return GasBase
+	case JUMP:
+		return GasMid
+	case JUMPI:
+		return GasHigh
+	case JUMPDEST:
+		return GasJumpDest
 	case PUSH1, PUSH2, PUSH3, PUSH4, PUSH5, PUSH6, PUSH7, PUSH8,
 		PUSH9, PUSH10, PUSH11, PUSH12, PUSH13, PUSH14, PUSH15, PUSH16,
 		PUSH17, PUSH18, PUSH19, PUSH20, PUSH21, PUSH22, PUSH23, PUSH24,
@@ -283,6 +1302,42 @@ func GetOpcodeGasCost(opcode byte) uint64 {
 	case SWAP1, SWAP2, SWAP3, SWAP4, SWAP5, SWAP6, SWAP7, SWAP8,
 		SWAP9, SWAP10, SWAP11, SWAP12, SWAP13, SWAP14, SWAP15, SWAP16:
 		return GasVeryLow
+	case DUPN, SWAPN, EXCHANGE:
+		return GasVeryLow
+	case MLOAD, MSTORE, MSTORE8, RETURNDATACOPY, MCOPY:
+		return GasVeryLow // Plus memory expansion and copy costs
+	case KECCAK256:
+		return GasSHA3 // Plus memory expansion and per-word costs
+	case MSIZE, GAS, RETURNDATASIZE:
+		return GasBase
+	case ADDRESS, ORIGIN, CALLER, CALLVALUE, CALLDATASIZE, CODESIZE, GASPRICE:
+		return GasBase
+	case CALLDATALOAD, CALLDATACOPY, CODECOPY:
+		return GasVeryLow // Copies add memory expansion and per-word costs
+	case BALANCE, EXTCODESIZE, EXTCODECOPY, EXTCODEHASH:
+		return GasZero // Account access, charged during execution (EIP-2929 from Berlin)
+	case SELFBALANCE:
+		return GasLow
+	case COINBASE, TIMESTAMP, NUMBER, PREVRANDAO, GASLIMIT, CHAINID, BASEFEE, BLOBBASEFEE:
+		return GasBase
+	case BLOBHASH:
+		return GasVeryLow
+	case BLOCKHASH:
+		return GasExtStep
+	case LOG0, LOG1, LOG2, LOG3, LOG4:
+		return GasLog + GasLogTopic*uint64(opcode-LOG0) // Plus memory expansion and data
+	case RETURN, REVERT:
+		return GasZero // Plus memory expansion
+	case SLOAD, SSTORE:
+		return GasZero // Charged during execution, depends on the fork and slot state
+	case TLOAD, TSTORE:
+		return GasWarmStorageRead // EIP-1153
+	case CALL, CALLCODE, DELEGATECALL, STATICCALL:
+		return GasZero // Account access plus value, memory and forwarded gas
+	case CREATE, CREATE2:
+		return GasCreate // Plus memory expansion, init code words and forwarded gas
+	case SELFDESTRUCT:
+		return GasSelfDestruct // Plus account access and creation
 	default:
 		// Unknown opcode - return high cost to discourage execution
 		return GasHigh
@@ -294,9 +1349,33 @@ func (w Word) ToBigInt() *big.Int {
 	return new(big.Int).SetBytes(w[:])
 }
 
-// Helper function to convert big.Int to Word
+// Helper function to convert Word to uint64, reporting whether it fits
+func (w Word) ToUint64() (uint64, bool) {
+	for _, b := range w[:24] {
+		if b != 0 {
+			return 0, false
+		}
+	}
+	return binary.BigEndian.Uint64(w[24:]), true
+}
+
+// Helper function to take the low 20 bytes of a Word as an Address
+func (w Word) ToAddress() Address {
+	var addr Address
+	copy(addr[:], w[12:])
+	return addr
+}
+
+// two256 is the modulus of EVM arithmetic, 2^256
+var two256 = new(big.Int).Lsh(big.NewInt(1), 256)
+
+// Helper function to convert big.Int to Word; negative values wrap around
+// modulo 2^256, as SUB underflows do
 func BigIntToWord(val *big.Int) Word {
 	var w Word
+	if val.Sign() < 0 {
+		val = new(big.Int).Mod(val, two256)
+	}
 	bytes := val.Bytes()
 	if len(bytes) > 32 {
 		// Truncate if too large
@@ -308,6 +1387,15 @@ func BigIntToWord(val *big.Int) Word {
 	return w
 }
 
+// ToSignedBigInt reads w as a two's complement signed integer
+func (w Word) ToSignedBigInt() *big.Int {
+	val := w.ToBigInt()
+	if w[0]&0x80 != 0 {
+		val.Sub(val, two256)
+	}
+	return val
+}
+
 // Helper function to create Word from uint64
 func NewWord(val uint64) Word {
 	return BigIntToWord(new(big.Int).SetUint64(val))
@@ -324,18 +1412,41 @@ func NewWordFromBytes(data []byte) Word {
 	return w
 }
 
-// Stack operations
-func (s *Stack) Push(data Word) {
+// Stack operations. Running out of items or past MaximumDepth halts
+// execution with ErrStackUnderflow or ErrStackOverflow.
+func (s *Stack) Push(data Word) error {
 	if len(s.Data) >= int(MaximumDepth) {
-		panic("stack overflow")
+		return ErrStackOverflow
 	}
-	s.Data = append(s.Data, data)
+	s.push(data)
+	return nil
 }
 
-func (s *Stack) Pop() Word {
+func (s *Stack) Pop() (Word, error) {
 	if len(s.Data) == 0 {
-		panic("stack underflow")
+		return Word{}, ErrStackUnderflow
 	}
+	return s.pop(), nil
+}
+
+// PopN pops n items, the top of the stack first
+func (s *Stack) PopN(n int) ([]Word, error) {
+	if len(s.Data) < n {
+		return nil, ErrStackUnderflow
+	}
+	items := make([]Word, n)
+	for i := range items {
+		items[i] = s.pop()
+	}
+	return items, nil
+}
+
+// push and pop skip the depth checks, for operations that made them
+func (s *Stack) push(data Word) {
+	s.Data = append(s.Data, data)
+}
+
+func (s *Stack) pop() Word {
 	lastIndex := len(s.Data) - 1
 	lastItem := s.Data[lastIndex]
 	s.Data = s.Data[:lastIndex]
@@ -356,21 +1467,32 @@ func (s *Stack) PeekAt(index int) Word {
 	return s.Data[len(s.Data)-1-index]
 }
 
-func (s *Stack) Dup(index int) {
+func (s *Stack) Dup(index int) error {
 	if index >= len(s.Data) {
-		panic("stack underflow")
+		return ErrStackUnderflow
 	}
-	val := s.Data[len(s.Data)-1-index]
-	s.Push(val)
+	return s.Push(s.Data[len(s.Data)-1-index])
 }
 
-func (s *Stack) Swap(index int) {
+// Swap exchanges the top item with the one at depth index (1 = second)
+func (s *Stack) Swap(index int) error {
 	if index >= len(s.Data) {
-		panic("stack underflow")
+		return ErrStackUnderflow
 	}
 	top := len(s.Data) - 1
 	target := top - index
 	s.Data[top], s.Data[target] = s.Data[target], s.Data[top]
+	return nil
+}
+
+// Exchange swaps the items at depths n and m (0 = top of stack)
+func (s *Stack) Exchange(n, m int) error {
+	if n >= len(s.Data) || m >= len(s.Data) {
+		return ErrStackUnderflow
+	}
+	top := len(s.Data) - 1
+	s.Data[top-n], s.Data[top-m] = s.Data[top-m], s.Data[top-n]
+	return nil
 }
 
 func (s *Stack) Size() int {
@@ -378,285 +1500,428 @@ func (s *Stack) Size() int {
 }
 
 // Arithmetic operations
-func (s *Stack) Add() {
+func (s *Stack) Add() error {
 	if len(s.Data) < 2 {
-		panic("stack underflow")
+		return ErrStackUnderflow
 	}
-	a := s.Pop()
-	b := s.Pop()
+	a := s.pop()
+	b := s.pop()
 	result := new(big.Int).Add(a.ToBigInt(), b.ToBigInt())
-	s.Push(BigIntToWord(result))
+	s.push(BigIntToWord(result))
+	return nil
 }
 
-func (s *Stack) Sub() {
+func (s *Stack) Sub() error {
 	if len(s.Data) < 2 {
-		panic("stack underflow")
+		return ErrStackUnderflow
 	}
-	a := s.Pop()
-	b := s.Pop()
+	a := s.pop()
+	b := s.pop()
 	result := new(big.Int).Sub(a.ToBigInt(), b.ToBigInt())
-	s.Push(BigIntToWord(result))
+	s.push(BigIntToWord(result))
+	return nil
 }
 
-func (s *Stack) Mul() {
+func (s *Stack) Mul() error {
 	if len(s.Data) < 2 {
-		panic("stack underflow")
+		return ErrStackUnderflow
 	}
-	a := s.Pop()
-	b := s.Pop()
+	a := s.pop()
+	b := s.pop()
 	result := new(big.Int).Mul(a.ToBigInt(), b.ToBigInt())
-	s.Push(BigIntToWord(result))
+	s.push(BigIntToWord(result))
+	return nil
 }
 
-func (s *Stack) Div() {
+func (s *Stack) Div() error {
 	if len(s.Data) < 2 {
-		panic("stack underflow")
+		return ErrStackUnderflow
 	}
-	a := s.Pop()
-	b := s.Pop()
+	a := s.pop()
+	b := s.pop()
 
 	if b.ToBigInt().Sign() == 0 {
 		// Division by zero returns zero in EVM
-		s.Push(NewWord(0))
-		return
+		s.push(NewWord(0))
+		return nil
 	}
 
 	result := new(big.Int).Div(a.ToBigInt(), b.ToBigInt())
-	s.Push(BigIntToWord(result))
+	s.push(BigIntToWord(result))
+	return nil
 }
 
-func (s *Stack) Mod() {
+func (s *Stack) Mod() error {
 	if len(s.Data) < 2 {
-		panic("stack underflow")
+		return ErrStackUnderflow
 	}
-	a := s.Pop()
-	b := s.Pop()
+	a := s.pop()
+	b := s.pop()
 
 	if b.ToBigInt().Sign() == 0 {
 		// Modulo by zero returns zero in EVM
-		s.Push(NewWord(0))
-		return
+		s.push(NewWord(0))
+		return nil
 	}
 
 	result := new(big.Int).Mod(a.ToBigInt(), b.ToBigInt())
-	s.Push(BigIntToWord(result))
+	s.push(BigIntToWord(result))
+	return nil
 }
 
-func (s *Stack) AddMod() {
+// SDiv divides two's complement integers, rounding towards zero. Division
+// by zero returns zero; -2^255 / -1 overflows back to -2^255.
+func (s *Stack) SDiv() error {
+	if len(s.Data) < 2 {
+		return ErrStackUnderflow
+	}
+	a := s.pop()
+	b := s.pop()
+
+	if b.ToBigInt().Sign() == 0 {
+		s.push(NewWord(0))
+		return nil
+	}
+
+	result := new(big.Int).Quo(a.ToSignedBigInt(), b.ToSignedBigInt())
+	s.push(BigIntToWord(result))
+	return nil
+}
+
+// SMod is the remainder of SDiv, taking the sign of the dividend
+func (s *Stack) SMod() error {
+	if len(s.Data) < 2 {
+		return ErrStackUnderflow
+	}
+	a := s.pop()
+	b := s.pop()
+
+	if b.ToBigInt().Sign() == 0 {
+		s.push(NewWord(0))
+		return nil
+	}
+
+	result := new(big.Int).Rem(a.ToSignedBigInt(), b.ToSignedBigInt())
+	s.push(BigIntToWord(result))
+	return nil
+}
+
+func (s *Stack) AddMod() error {
 	if len(s.Data) < 3 {
-		panic("stack underflow")
+		return ErrStackUnderflow
 	}
-	a := s.Pop()
-	b := s.Pop()
-	n := s.Pop()
+	a := s.pop()
+	b := s.pop()
+	n := s.pop()
 
 	if n.ToBigInt().Sign() == 0 {
 		// Modulo by zero returns zero in EVM
-		s.Push(NewWord(0))
-		return
+		s.push(NewWord(0))
+		return nil
 	}
 
 	result := new(big.Int).Add(a.ToBigInt(), b.ToBigInt())
 	result.Mod(result, n.ToBigInt())
-	s.Push(BigIntToWord(result))
+	s.push(BigIntToWord(result))
+	return nil
 }
 
-func (s *Stack) MulMod() {
+func (s *Stack) MulMod() error {
 	if len(s.Data) < 3 {
-		panic("stack underflow")
+		return ErrStackUnderflow
 	}
-	a := s.Pop()
-	b := s.Pop()
-	n := s.Pop()
+	a := s.pop()
+	b := s.pop()
+	n := s.pop()
 
 	if n.ToBigInt().Sign() == 0 {
 		// Modulo by zero returns zero in EVM
-		s.Push(NewWord(0))
-		return
+		s.push(NewWord(0))
+		return nil
 	}
 
 	result := new(big.Int).Mul(a.ToBigInt(), b.ToBigInt())
 	result.Mod(result, n.ToBigInt())
-	s.Push(BigIntToWord(result))
+	s.push(BigIntToWord(result))
+	return nil
 }
 
-func (s *Stack) Exp() {
+func (s *Stack) Exp() error {
 	if len(s.Data) < 2 {
-		panic("stack underflow")
+		return ErrStackUnderflow
 	}
-	base := s.Pop()
-	exp := s.Pop()
+	base := s.pop()
+	exp := s.pop()
 
-	// Limit exponent to prevent excessive computation
-	if exp.ToBigInt().BitLen() > 256 {
-		panic("exponent too large")
-	}
+	result := new(big.Int).Exp(base.ToBigInt(), exp.ToBigInt(), two256)
+	s.push(BigIntToWord(result))
+	return nil
+}
 
-	result := new(big.Int).Exp(base.ToBigInt(), exp.ToBigInt(), nil)
-	s.Push(BigIntToWord(result))
+// SignExtend extends the sign bit of the byte at index (counting from the
+// least significant) of the second item to the whole word
+func (s *Stack) SignExtend() error {
+	if len(s.Data) < 2 {
+		return ErrStackUnderflow
+	}
+	index := s.pop()
+	value := s.pop()
+
+	if idx, ok := index.ToUint64(); ok && idx < 31 {
+		signByte := 31 - int(idx)
+		fill := byte(0)
+		if value[signByte]&0x80 != 0 {
+			fill = 0xff
+		}
+		for i := 0; i < signByte; i++ {
+			value[i] = fill
+		}
+	}
+	s.push(value)
+	return nil
 }
 
 // Comparison operations
-func (s *Stack) Lt() {
+func (s *Stack) Lt() error {
 	if len(s.Data) < 2 {
-		panic("stack underflow")
+		return ErrStackUnderflow
 	}
-	a := s.Pop()
-	b := s.Pop()
+	a := s.pop()
+	b := s.pop()
 
 	if a.ToBigInt().Cmp(b.ToBigInt()) < 0 {
-		s.Push(NewWord(1))
+		s.push(NewWord(1))
 	} else {
-		s.Push(NewWord(0))
+		s.push(NewWord(0))
 	}
+	return nil
 }
 
-func (s *Stack) Gt() {
+func (s *Stack) Gt() error {
 	if len(s.Data) < 2 {
-		panic("stack underflow")
+		return ErrStackUnderflow
 	}
-	a := s.Pop()
-	b := s.Pop()
+	a := s.pop()
+	b := s.pop()
 
 	if a.ToBigInt().Cmp(b.ToBigInt()) > 0 {
-		s.Push(NewWord(1))
+		s.push(NewWord(1))
 	} else {
-		s.Push(NewWord(0))
+		s.push(NewWord(0))
 	}
+	return nil
 }
 
-func (s *Stack) Eq() {
+// Slt and Sgt compare the top two items as two's complement integers
+func (s *Stack) Slt() error {
 	if len(s.Data) < 2 {
-		panic("stack underflow")
+		return ErrStackUnderflow
+	}
+	a := s.pop()
+	b := s.pop()
+
+	if a.ToSignedBigInt().Cmp(b.ToSignedBigInt()) < 0 {
+		s.push(NewWord(1))
+	} else {
+		s.push(NewWord(0))
+	}
+	return nil
+}
+
+func (s *Stack) Sgt() error {
+	if len(s.Data) < 2 {
+		return ErrStackUnderflow
+	}
+	a := s.pop()
+	b := s.pop()
+
+	if a.ToSignedBigInt().Cmp(b.ToSignedBigInt()) > 0 {
+		s.push(NewWord(1))
+	} else {
+		s.push(NewWord(0))
+	}
+	return nil
+}
+
+func (s *Stack) Eq() error {
+	if len(s.Data) < 2 {
+		return ErrStackUnderflow
 	}
-	a := s.Pop()
-	b := s.Pop()
+	a := s.pop()
+	b := s.pop()
 
 	if a.ToBigInt().Cmp(b.ToBigInt()) == 0 {
-		s.Push(NewWord(1))
+		s.push(NewWord(1))
 	} else {
-		s.Push(NewWord(0))
+		s.push(NewWord(0))
 	}
+	return nil
 }
 
-func (s *Stack) IsZero() {
+func (s *Stack) IsZero() error {
 	if len(s.Data) < 1 {
-		panic("stack underflow")
+		return ErrStackUnderflow
 	}
-	a := s.Pop()
+	a := s.pop()
 
 	if a.ToBigInt().Sign() == 0 {
-		s.Push(NewWord(1))
+		s.push(NewWord(1))
 	} else {
-		s.Push(NewWord(0))
+		s.push(NewWord(0))
 	}
+	return nil
 }
 
 // Bitwise operations
-func (s *Stack) And() {
+func (s *Stack) And() error {
 	if len(s.Data) < 2 {
-		panic("stack underflow")
+		return ErrStackUnderflow
 	}
-	a := s.Pop()
-	b := s.Pop()
+	a := s.pop()
+	b := s.pop()
 
 	var result Word
 	for i := 0; i < 32; i++ {
 		result[i] = a[i] & b[i]
 	}
-	s.Push(result)
+	s.push(result)
+	return nil
 }
 
-func (s *Stack) Or() {
+func (s *Stack) Or() error {
 	if len(s.Data) < 2 {
-		panic("stack underflow")
+		return ErrStackUnderflow
 	}
-	a := s.Pop()
-	b := s.Pop()
+	a := s.pop()
+	b := s.pop()
 
 	var result Word
 	for i := 0; i < 32; i++ {
 		result[i] = a[i] | b[i]
 	}
-	s.Push(result)
+	s.push(result)
+	return nil
 }
 
-func (s *Stack) Xor() {
+func (s *Stack) Xor() error {
 	if len(s.Data) < 2 {
-		panic("stack underflow")
+		return ErrStackUnderflow
 	}
-	a := s.Pop()
-	b := s.Pop()
+	a := s.pop()
+	b := s.pop()
 
 	var result Word
 	for i := 0; i < 32; i++ {
 		result[i] = a[i] ^ b[i]
 	}
-	s.Push(result)
+	s.push(result)
+	return nil
 }
 
-func (s *Stack) Not() {
+func (s *Stack) Not() error {
 	if len(s.Data) < 1 {
-		panic("stack underflow")
+		return ErrStackUnderflow
 	}
-	a := s.Pop()
+	a := s.pop()
 
 	var result Word
 	for i := 0; i < 32; i++ {
 		result[i] = ^a[i]
 	}
-	s.Push(result)
+	s.push(result)
+	return nil
 }
 
-func (s *Stack) Byte() {
+func (s *Stack) Byte() error {
 	if len(s.Data) < 2 {
-		panic("stack underflow")
+		return ErrStackUnderflow
 	}
-	index := s.Pop()
-	value := s.Pop()
+	index := s.pop()
+	value := s.pop()
 
-	idx := int(index.ToBigInt().Uint64())
-	if idx >= 32 {
-		s.Push(NewWord(0))
-		return
+	idx, ok := index.ToUint64()
+	if !ok || idx >= 32 {
+		s.push(NewWord(0))
+		return nil
 	}
 
 	result := uint64(value[idx])
-	s.Push(NewWord(result))
+	s.push(NewWord(result))
+	return nil
 }
 
-func (s *Stack) Shl() {
+func (s *Stack) Shl() error {
 	if len(s.Data) < 2 {
-		panic("stack underflow")
+		return ErrStackUnderflow
 	}
-	shift := s.Pop()
-	value := s.Pop()
+	shift := s.pop()
+	value := s.pop()
 
-	shiftAmount := shift.ToBigInt().Uint64()
-	if shiftAmount >= 256 {
-		s.Push(NewWord(0))
-		return
+	shiftAmount, ok := shift.ToUint64()
+	if !ok || shiftAmount >= 256 {
+		s.push(NewWord(0))
+		return nil
 	}
 
 	result := new(big.Int).Lsh(value.ToBigInt(), uint(shiftAmount))
-	s.Push(BigIntToWord(result))
+	s.push(BigIntToWord(result))
+	return nil
 }
 
-func (s *Stack) Shr() {
+func (s *Stack) Shr() error {
 	if len(s.Data) < 2 {
-		panic("stack underflow")
+		return ErrStackUnderflow
 	}
-	shift := s.Pop()
-	value := s.Pop()
+	shift := s.pop()
+	value := s.pop()
 
-	shiftAmount := shift.ToBigInt().Uint64()
-	if shiftAmount >= 256 {
-		s.Push(NewWord(0))
-		return
+	shiftAmount, ok := shift.ToUint64()
+	if !ok || shiftAmount >= 256 {
+		s.push(NewWord(0))
+		return nil
 	}
 
 	result := new(big.Int).Rsh(value.ToBigInt(), uint(shiftAmount))
-	s.Push(BigIntToWord(result))
+	s.push(BigIntToWord(result))
+	return nil
+}
+
+// Sar shifts right, filling with the sign bit; shifts of 256 or more
+// leave 0 or -1
+func (s *Stack) Sar() error {
+	if len(s.Data) < 2 {
+		return ErrStackUnderflow
+	}
+	shift := s.pop()
+	value := s.pop()
+
+	shiftAmount, ok := shift.ToUint64()
+	if !ok || shiftAmount > 255 {
+		shiftAmount = 255
+	}
+
+	result := new(big.Int).Rsh(value.ToSignedBigInt(), uint(shiftAmount))
+	s.push(BigIntToWord(result))
+	return nil
+}
+
+// Clz pushes the number of leading zero bits of the top item (256 for zero)
+func (s *Stack) Clz() error {
+	if len(s.Data) < 1 {
+		return ErrStackUnderflow
+	}
+	value := s.pop()
+
+	count := 0
+	for i := 0; i < 32; i++ {
+		if value[i] != 0 {
+			count += bits.LeadingZeros8(value[i])
+			break
+		}
+		count += 8
+	}
+	s.push(NewWord(uint64(count)))
+	return nil
 }
 
 // Memory operations
@@ -684,6 +1949,39 @@ func (m *Memory) Load(offset byte) Word {
 	return result
 }
 
+// Len returns the memory size in bytes (always a multiple of 32)
+func (m *Memory) Len() uint64 {
+	return uint64(len(m.Data)) * 32
+}
+
+// Resize grows memory to cover size bytes, rounded up to whole words
+func (m *Memory) Resize(size uint64) {
+	words := int((size + 31) / 32)
+	if words > len(m.Data) {
+		newMemoryData := make([]Byte32, words)
+		copy(newMemoryData, m.Data)
+		m.Data = newMemoryData
+	}
+}
+
+// Set writes data at a byte offset; memory must already cover the range
+func (m *Memory) Set(offset uint64, data []byte) {
+	for i, b := range data {
+		pos := offset + uint64(i)
+		m.Data[pos/32][pos%32] = b
+	}
+}
+
+// GetCopy returns a copy of size bytes starting at a byte offset
+func (m *Memory) GetCopy(offset, size uint64) []byte {
+	result := make([]byte, size)
+	for i := range result {
+		pos := offset + uint64(i)
+		result[i] = m.Data[pos/32][pos%32]
+	}
+	return result
+}
+
 // Storage operations
 func (s *Storage) Store(key Word, value Word) {
 	var key32, value32 Byte32
@@ -0,0 +1,341 @@
+package types
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// runOp executes opcode on args, given top first, and returns the result
+func runOp(t *testing.T, opcode byte, args []string) Word {
+	t.Helper()
+	var code []byte
+	for i := len(args) - 1; i >= 0; i-- {
+		val, ok := new(big.Int).SetString(args[i], 16)
+		if !ok {
+			t.Fatalf("bad argument %q", args[i])
+		}
+		word := BigIntToWord(val)
+		code = append(append(code, PUSH32), word[:]...)
+	}
+	vm := NewVM(append(code, opcode), 1_000_000)
+	vm.Fork = Osaka
+	if err := vm.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	return vm.Stack.Peek()
+}
+
+// Expected results from geth
+func TestOps(t *testing.T) {
+	tests := []struct {
+		name   string
+		opcode byte
+		args   []string // Top of the stack first
+		want   string
+	}{
+		{"sdivNegative", SDIV, []string{"fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff6", "3"}, "fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffd"},
+		{"sdivOverflow", SDIV, []string{"8000000000000000000000000000000000000000000000000000000000000000", "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"}, "8000000000000000000000000000000000000000000000000000000000000000"},
+		{"sdivByZero", SDIV, []string{"7", "0"}, "0"},
+		{"smodNegativeDividend", SMOD, []string{"fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff6", "3"}, "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"},
+		{"smodNegativeDivisor", SMOD, []string{"a", "fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffd"}, "1"},
+		{"smodByZero", SMOD, []string{"fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff6", "0"}, "0"},
+		{"signextendNegativeByte", SIGNEXTEND, []string{"0", "ff"}, "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"},
+		{"signextendPositiveByte", SIGNEXTEND, []string{"0", "7f"}, "7f"},
+		{"signextendSecondByte", SIGNEXTEND, []string{"1", "1280ff"}, "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff80ff"},
+		{"signextendWholeWord", SIGNEXTEND, []string{"1f", "80"}, "80"},
+		{"signextendLargeIndex", SIGNEXTEND, []string{"100000000000000000000000000000000000000000000000000", "ff"}, "ff"},
+		{"sltNegative", SLT, []string{"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", "1"}, "1"},
+		{"sltEqual", SLT, []string{"fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffb", "fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffb"}, "0"},
+		{"sgtNegative", SGT, []string{"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", "1"}, "0"},
+		{"sgtPositive", SGT, []string{"2", "fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffe"}, "1"},
+		{"sarNegative", SAR, []string{"4", "fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff0"}, "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"},
+		{"sarNegativeLargeShift", SAR, []string{"100", "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"}, "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"},
+		{"sarPositiveLargeShift", SAR, []string{"12c", "5"}, "0"},
+		{"sarHugeShift", SAR, []string{"10000000000000000000000000", "fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff9"}, "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"},
+		{"sarSignBit", SAR, []string{"1", "8000000000000000000000000000000000000000000000000000000000000000"}, "c000000000000000000000000000000000000000000000000000000000000000"},
+		{"expWraps", EXP, []string{"3", "3e8"}, "ce065bd2a048f32939dc42ec08348318c4940c56f7867dbe5616937bd3b85b21"},
+		{"expHugeExponent", EXP, []string{"2", "8000000000000000000000000000000000000000000000000000000000000007"}, "0"},
+		{"shlHugeShift", SHL, []string{"10000000000000001", "1"}, "0"},
+		{"shrHugeShift", SHR, []string{"10000000000000001", "8000000000000000000000000000000000000000000000000000000000000000"}, "0"},
+		{"clzZero", CLZ, []string{"0"}, "100"}, // EIP-7939 examples
+		{"clzTopBit", CLZ, []string{"8000000000000000000000000000000000000000000000000000000000000000"}, "0"},
+		{"clzAllOnes", CLZ, []string{"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"}, "0"},
+		{"clzSecondBit", CLZ, []string{"4000000000000000000000000000000000000000000000000000000000000000"}, "1"},
+		{"clzOne", CLZ, []string{"1"}, "ff"},
+		{"byteHugeIndex", BYTE, []string{"1000000000000001f", "ab"}, "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, _ := new(big.Int).SetString(tt.want, 16)
+			if got := runOp(t, tt.opcode, tt.args); got != BigIntToWord(want) {
+				t.Errorf("got %x, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClzFork(t *testing.T) {
+	vm := NewVM([]byte{PUSH1, 1, CLZ}, 100)
+	vm.Fork = Prague
+	if err := vm.Execute(); err == nil {
+		t.Error("CLZ ran before Osaka")
+	}
+}
+
+func TestEIP663(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    []byte
+		disable bool
+		stack   []uint64 // Bottom first
+		err     error
+		invalid bool // Fails as an invalid instruction
+	}{
+		{"dupn", []byte{PUSH1, 1, PUSH1, 2, PUSH1, 3, DUPN, 2}, false, []uint64{1, 2, 3, 1}, nil, false},
+		{"swapn", []byte{PUSH1, 1, PUSH1, 2, PUSH1, 3, SWAPN, 1}, false, []uint64{3, 2, 1}, nil, false},
+		{"exchange", []byte{PUSH1, 1, PUSH1, 2, PUSH1, 3, PUSH1, 4, EXCHANGE, 0x01}, false, []uint64{3, 2, 1, 4}, nil, false},
+		{"dupnUnderflow", []byte{PUSH1, 1, DUPN, 1}, false, nil, ErrStackUnderflow, false},
+		{"swapnUnderflow", []byte{PUSH1, 1, PUSH1, 2, SWAPN, 1}, false, nil, ErrStackUnderflow, false},
+		{"exchangeUnderflow", []byte{PUSH1, 1, PUSH1, 2, PUSH1, 3, EXCHANGE, 0x01}, false, nil, ErrStackUnderflow, false},
+		{"dupnMissingImmediate", []byte{PUSH1, 1, DUPN}, false, nil, nil, true},
+		{"swapnMissingImmediate", []byte{PUSH1, 1, PUSH1, 2, SWAPN}, false, nil, nil, true},
+		{"exchangeMissingImmediate", []byte{PUSH1, 1, PUSH1, 2, EXCHANGE}, false, nil, nil, true},
+		{"disabled", []byte{PUSH1, 1, DUPN, 0}, true, nil, nil, true}, // Not part of Osaka
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := NewVM(tt.code, 100)
+			vm.Fork = Osaka
+			vm.EnableEIP663 = !tt.disable
+			err := vm.Execute()
+			switch {
+			case tt.invalid:
+				if err == nil || errors.Is(err, ErrStackUnderflow) {
+					t.Fatalf("error %v, want an invalid instruction", err)
+				}
+				return
+			case err != tt.err:
+				t.Fatalf("error %v, want %v", err, tt.err)
+			case err != nil:
+				return
+			}
+			if len(vm.Stack.Data) != len(tt.stack) {
+				t.Fatalf("stack size %d, want %d", len(vm.Stack.Data), len(tt.stack))
+			}
+			for i, want := range tt.stack {
+				if vm.Stack.Data[i] != NewWord(want) {
+					t.Errorf("stack[%d] = %x, want %d", i, vm.Stack.Data[i], want)
+				}
+			}
+			if used, want := 100-vm.Gas, 3*uint64(bytes.Count(tt.code, []byte{PUSH1})+1); used != want {
+				t.Errorf("gas used %d, want %d", used, want) // Every instruction costs 3
+			}
+		})
+	}
+}
+
+func TestExpGas(t *testing.T) {
+	// 3 + 3 for the pushes, 10 + 50 for EXP with a one-byte exponent
+	vm := NewVM([]byte{PUSH1, 3, PUSH1, 10, EXP}, 100)
+	if err := vm.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if used := 100 - vm.Gas; used != 66 {
+		t.Errorf("gas used %d, want 66", used)
+	}
+}
+
+func TestStackErrors(t *testing.T) {
+	vm := NewVM([]byte{PUSH1, 1, SDIV}, 100)
+	if err := vm.Execute(); err != ErrStackUnderflow {
+		t.Errorf("error %v, want %v", err, ErrStackUnderflow)
+	}
+	code := make([]byte, 0, 2*int(MaximumDepth+1))
+	for i := 0; i <= int(MaximumDepth); i++ {
+		code = append(code, PUSH1, 1)
+	}
+	vm = NewVM(code, 10_000)
+	if err := vm.Execute(); err != ErrStackOverflow {
+		t.Errorf("error %v, want %v", err, ErrStackOverflow)
+	}
+}
+
+// Results and gas from geth
+func TestMemoryOps(t *testing.T) {
+	word := "0102030405060708091011121314151617181920212223242526272829303132"
+	tests := []struct {
+		name  string
+		code  string // Runs after storing word at offset 0
+		stack []string
+		gas   uint64
+	}{
+		{"keccak256", "6020600020", []string{"2cfe17dc69e953b28d77cdb7cdc86ce378dfe1e846f4be9cbe9dfb18efa5dfb5"}, 54},
+		{"transientStorage", "6007602a5d602a5c", []string{"0000000000000000000000000000000000000000000000000000000000000007"}, 221},
+		{"expandUsedMemory", "611fe051", []string{"0000000000000000000000000000000000000000000000000000000000000000"}, 911},
+		{"mcopyOverlap", "6020600060085e600051600851", []string{"0102030405060708010203040506070809101112131415161718192021222324", word}, 42},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := hex.DecodeString("7f" + word + "600052" + tt.code)
+			if err != nil {
+				t.Fatal(err)
+			}
+			vm := NewVM(code, 1000)
+			vm.Fork = Cancun
+			if err := vm.Execute(); err != nil {
+				t.Fatal(err)
+			}
+			if used := 1000 - vm.Gas; used != tt.gas {
+				t.Errorf("gas used %d, want %d", used, tt.gas)
+			}
+			if len(vm.Stack.Data) != len(tt.stack) {
+				t.Fatalf("stack size %d, want %d", len(vm.Stack.Data), len(tt.stack))
+			}
+			for i, want := range tt.stack {
+				if got := hex.EncodeToString(vm.Stack.Data[i][:]); got != want {
+					t.Errorf("stack[%d] = %s, want %s", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+// newStateVM returns a frame running code for a contract holding 10 wei,
+// with a world state
+func newStateVM(code []byte, fork Fork) *VM {
+	vm := NewVM(code, 1_000_000)
+	vm.Fork = fork
+	vm.State = NewStateDB()
+	vm.Address = Address{19: 0x10}
+	vm.State.AddBalance(vm.Address, big.NewInt(10))
+	vm.State.SetNonce(vm.Address, 1)
+	vm.Storage = vm.State.GetOrNewAccount(vm.Address).Storage
+	return vm
+}
+
+func TestCreate(t *testing.T) {
+	// Init code returning the runtime code 602a60005260206000f3
+	deploy := "600a600c600039600a6000f3602a60005260206000f3"
+	tests := []struct {
+		name     string
+		opcode   byte
+		initCode string
+		value    byte
+		readOnly bool
+		created  bool
+		err      error
+	}{
+		{"create", CREATE, deploy, 3, false, true, nil},
+		{"create2", CREATE2, deploy, 0, false, true, nil},
+		{"insufficientBalance", CREATE, deploy, 11, false, false, nil},
+		{"revertingInitCode", CREATE, "60006000fd", 0, false, false, nil},
+		{"invalidCodePrefix", CREATE, "60ef60005360016000f3", 0, false, false, nil}, // EIP-3541
+		{"staticContext", CREATE, deploy, 0, true, false, ErrWriteProtection},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			initCode, err := hex.DecodeString(tt.initCode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Store the init code at the end of the first word and create
+			code := append([]byte{PUSH1 + byte(len(initCode)) - 1}, initCode...)
+			code = append(code, PUSH1, 0, MSTORE)
+			if tt.opcode == CREATE2 {
+				code = append(code, PUSH1, 1)
+			}
+			code = append(code, PUSH1, byte(len(initCode)), PUSH1, byte(32-len(initCode)), PUSH1, tt.value, tt.opcode)
+			vm := newStateVM(code, Cancun)
+			vm.ReadOnly = tt.readOnly
+			if err := vm.Execute(); err != tt.err {
+				t.Fatalf("error %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+
+			addr := CreateAddress(vm.Address, 1)
+			if tt.opcode == CREATE2 {
+				addr = Create2Address(vm.Address, NewWord(1), initCode)
+			}
+			want := Word{}
+			if tt.created {
+				want = NewWordFromBytes(addr[:])
+			}
+			if got := vm.Stack.Peek(); got != want {
+				t.Errorf("pushed %x, want %x", got, want)
+			}
+			if !tt.created {
+				if vm.State.Exist(addr) {
+					t.Error("failed creation left an account")
+				}
+				return
+			}
+			if got := hex.EncodeToString(vm.State.GetCode(addr)); got != "602a60005260206000f3" {
+				t.Errorf("code %s, want 602a60005260206000f3", got)
+			}
+			if got := vm.State.GetNonce(addr); got != 1 {
+				t.Errorf("new account nonce %d, want 1", got)
+			}
+			if got := vm.State.GetNonce(vm.Address); got != 2 {
+				t.Errorf("creator nonce %d, want 2", got)
+			}
+			if got := vm.State.GetBalance(addr); got.Int64() != int64(tt.value) {
+				t.Errorf("new account balance %v, want %d", got, tt.value)
+			}
+		})
+	}
+}
+
+func TestSelfDestruct(t *testing.T) {
+	// SELFDESTRUCT to a cold, empty beneficiary; gas from geth
+	beneficiary := Address{19: 0x20}
+	tests := []struct {
+		name    string
+		fork    Fork
+		created bool // Created by the transaction
+		deleted bool
+		refund  uint64
+		gas     uint64
+	}{
+		{"istanbul", Istanbul, false, true, GasSelfDestructRefund, 30003},
+		{"berlin", Berlin, false, true, GasSelfDestructRefund, 32603},
+		{"london", London, false, true, 0, 32603},
+		{"cancun", Cancun, false, false, 0, 32603}, // EIP-6780
+		{"cancunCreatedInTransaction", Cancun, true, true, 0, 32603},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := newStateVM([]byte{PUSH1, 0x20, SELFDESTRUCT, INVALID}, tt.fork)
+			vm.State.SetCode(vm.Address, vm.Code)
+			if tt.created {
+				vm.State.markCreated(vm.Address)
+			}
+			if err := vm.Execute(); err != nil {
+				t.Fatal(err)
+			}
+			if used := vm.GasLimit - vm.Gas; used != tt.gas {
+				t.Errorf("gas used %d, want %d", used, tt.gas)
+			}
+			if vm.Refund != tt.refund {
+				t.Errorf("refund %d, want %d", vm.Refund, tt.refund)
+			}
+			vm.State.Finalise()
+			if got := vm.State.GetBalance(beneficiary); got.Int64() != 10 {
+				t.Errorf("beneficiary balance %v, want 10", got)
+			}
+			if vm.State.Exist(vm.Address) == tt.deleted {
+				t.Errorf("account exists %v, want %v", !tt.deleted, tt.deleted)
+			}
+		})
+	}
+
+	vm := newStateVM([]byte{PUSH1, 0x20, SELFDESTRUCT}, Cancun)
+	vm.ReadOnly = true
+	if err := vm.Execute(); err != ErrWriteProtection {
+		t.Errorf("error in static context %v, want %v", err, ErrWriteProtection)
+	}
+}
@@ -0,0 +1,716 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/morelucks/minievm/crypto"
+	"github.com/morelucks/minievm/crypto/bls12381"
+	"github.com/morelucks/minievm/crypto/bn254"
+	"github.com/morelucks/minievm/crypto/kzg"
+)
+
+// Precompiled contract addresses
+var (
+	EcrecoverAddress = Address{19: 0x01}
+	Sha256Address    = Address{19: 0x02}
+	Ripemd160Address = Address{19: 0x03}
+	IdentityAddress  = Address{19: 0x04}
+	ModexpAddress    = Address{19: 0x05}
+	Bn254AddAddress  = Address{19: 0x06}
+	Bn254MulAddress  = Address{19: 0x07}
+	Bn254PairAddress = Address{19: 0x08}
+	Blake2fAddress   = Address{19: 0x09}
+
+	// KZG point evaluation (EIP-4844), available from Cancun
+	PointEvaluationAddress = Address{19: 0x0a}
+
+	// BLS12-381 (EIP-2537), available from Prague
+	Bls12381G1AddAddress   = Address{19: 0x0b}
+	Bls12381G1MsmAddress   = Address{19: 0x0c}
+	Bls12381G2AddAddress   = Address{19: 0x0d}
+	Bls12381G2MsmAddress   = Address{19: 0x0e}
+	Bls12381PairingAddress = Address{19: 0x0f}
+	Bls12381MapG1Address   = Address{19: 0x10}
+	Bls12381MapG2Address   = Address{19: 0x11}
+
+	// secp256r1 verification (EIP-7951), available from Osaka
+	P256VerifyAddress = Address{18: 0x01}
+)
+
+var (
+	ErrBlake2fInputLength  = errors.New("blake2f: input must be exactly 213 bytes")
+	ErrBlake2fFinalFlag    = errors.New("blake2f: final block flag must be 0 or 1")
+	ErrModexpInputTooLong  = errors.New("modexp: base, exponent or modulus longer than 1024 bytes")
+	ErrBn254PairingInput   = errors.New("bn254 pairing: input length must be a multiple of 192")
+	ErrBls12381InputLength = errors.New("bls12381: invalid input length")
+	ErrPointEvalInput      = errors.New("point evaluation: input must be exactly 192 bytes")
+	ErrPointEvalHash       = errors.New("point evaluation: versioned hash does not match commitment")
+	ErrPointEvalProof      = errors.New("point evaluation: invalid proof")
+)
+
+// Per-mille gas discounts for BLS12-381 multi-scalar multiplication,
+// indexed by the number of pairs minus one (EIP-2537)
+var bls12381G1MsmDiscount = [128]uint64{1000, 949, 848, 797, 764, 750, 738, 728, 719, 712, 705, 698, 692, 687, 682, 677, 673, 669, 665, 661, 658, 654, 651, 648, 645, 642, 640, 637, 635, 632, 630, 627, 625, 623, 621, 619, 617, 615, 613, 611, 609, 608, 606, 604, 603, 601, 599, 598, 596, 595, 593, 592, 591, 589, 588, 586, 585, 584, 582, 581, 580, 579, 577, 576, 575, 574, 573, 572, 570, 569, 568, 567, 566, 565, 564, 563, 562, 561, 560, 559, 558, 557, 556, 555, 554, 553, 552, 551, 550, 549, 548, 547, 547, 546, 545, 544, 543, 542, 541, 540, 540, 539, 538, 537, 536, 536, 535, 534, 533, 532, 532, 531, 530, 529, 528, 528, 527, 526, 525, 525, 524, 523, 522, 522, 521, 520, 520, 519}
+
+var bls12381G2MsmDiscount = [128]uint64{1000, 1000, 923, 884, 855, 832, 812, 796, 782, 770, 759, 749, 740, 732, 724, 717, 711, 704, 699, 693, 688, 683, 679, 674, 670, 666, 663, 659, 655, 652, 649, 646, 643, 640, 637, 634, 632, 629, 627, 624, 622, 620, 618, 615, 613, 611, 609, 607, 606, 604, 602, 600, 598, 597, 595, 593, 592, 590, 589, 587, 586, 584, 583, 582, 580, 579, 578, 576, 575, 574, 573, 571, 570, 569, 568, 567, 566, 565, 563, 562, 561, 560, 559, 558, 557, 556, 555, 554, 553, 552, 552, 551, 550, 549, 548, 547, 546, 545, 545, 544, 543, 542, 541, 541, 540, 539, 538, 537, 537, 536, 535, 535, 534, 533, 532, 532, 531, 530, 530, 529, 528, 528, 527, 526, 526, 525, 524, 524}
+
+// Precompile is a contract implemented natively by the client at a fixed address
+type Precompile interface {
+	// RequiredGas returns the gas charged for running the contract on input
+	RequiredGas(input []byte) uint64
+	// Run executes the contract; an error fails the call and consumes its gas
+	Run(input []byte) ([]byte, error)
+}
+
+// precompile adapts a gas function and a run function to Precompile
+type precompile struct {
+	gas func(input []byte) uint64
+	run func(input []byte) ([]byte, error)
+}
+
+func (p *precompile) RequiredGas(input []byte) uint64  { return p.gas(input) }
+func (p *precompile) Run(input []byte) ([]byte, error) { return p.run(input) }
+
+// fixedGas prices every call the same
+func fixedGas(gas uint64) func([]byte) uint64 {
+	return func([]byte) uint64 { return gas }
+}
+
+// wordGas prices a call as base plus perWord for each 32-byte word of input
+func wordGas(base, perWord uint64) func([]byte) uint64 {
+	return func(input []byte) uint64 { return base + wordCount(input)*perWord }
+}
+
+// standardPrecompileAddresses lists every address StandardPrecompile knows,
+// in ascending order
+var standardPrecompileAddresses = []Address{
+	EcrecoverAddress, Sha256Address, Ripemd160Address, IdentityAddress, ModexpAddress,
+	Bn254AddAddress, Bn254MulAddress, Bn254PairAddress, Blake2fAddress, PointEvaluationAddress,
+	Bls12381G1AddAddress, Bls12381G1MsmAddress, Bls12381G2AddAddress, Bls12381G2MsmAddress,
+	Bls12381PairingAddress, Bls12381MapG1Address, Bls12381MapG2Address, P256VerifyAddress,
+}
+
+// StandardPrecompile returns the precompile the given fork defines at addr,
+// or nil if there is none
+func StandardPrecompile(fork Fork, addr Address) Precompile {
+	switch addr {
+	case EcrecoverAddress:
+		return &precompile{fixedGas(GasEcrecover), runEcrecover}
+	case Sha256Address:
+		return &precompile{wordGas(GasSha256, GasSha256Word), runSha256}
+	case Ripemd160Address:
+		return &precompile{wordGas(GasRipemd160, GasRipemd160Word), runRipemd160}
+	case IdentityAddress:
+		return &precompile{wordGas(GasIdentity, GasIdentityWord), runIdentity}
+	case ModexpAddress:
+		return modexp{fork}
+	case Bn254AddAddress:
+		return &precompile{fixedGas(GasBn254Add), runBn254Add}
+	case Bn254MulAddress:
+		return &precompile{fixedGas(GasBn254Mul), runBn254Mul}
+	case Bn254PairAddress:
+		return &precompile{func(input []byte) uint64 {
+			return GasBn254PairingBase + uint64(len(input)/192)*GasBn254PairingPoint
+		}, runBn254Pairing}
+	case Blake2fAddress:
+		return &precompile{blake2fGas, runBlake2f}
+	case PointEvaluationAddress:
+		if fork.IsActive(Cancun) {
+			return &precompile{fixedGas(GasPointEvaluation), runPointEvaluation}
+		}
+	case Bls12381G1AddAddress:
+		if fork.IsActive(Prague) {
+			return &precompile{fixedGas(GasBls12381G1Add), runBls12381G1Add}
+		}
+	case Bls12381G1MsmAddress:
+		if fork.IsActive(Prague) {
+			return &precompile{func(input []byte) uint64 {
+				return bls12381MsmGas(len(input)/160, GasBls12381G1Mul, &bls12381G1MsmDiscount)
+			}, runBls12381G1Msm}
+		}
+	case Bls12381G2AddAddress:
+		if fork.IsActive(Prague) {
+			return &precompile{fixedGas(GasBls12381G2Add), runBls12381G2Add}
+		}
+	case Bls12381G2MsmAddress:
+		if fork.IsActive(Prague) {
+			return &precompile{func(input []byte) uint64 {
+				return bls12381MsmGas(len(input)/288, GasBls12381G2Mul, &bls12381G2MsmDiscount)
+			}, runBls12381G2Msm}
+		}
+	case Bls12381PairingAddress:
+		if fork.IsActive(Prague) {
+			return &precompile{func(input []byte) uint64 {
+				return GasBls12381PairingBase + uint64(len(input)/384)*GasBls12381PairingPerPair
+			}, runBls12381Pairing}
+		}
+	case Bls12381MapG1Address:
+		if fork.IsActive(Prague) {
+			return &precompile{fixedGas(GasBls12381MapG1), runBls12381MapG1}
+		}
+	case Bls12381MapG2Address:
+		if fork.IsActive(Prague) {
+			return &precompile{fixedGas(GasBls12381MapG2), runBls12381MapG2}
+		}
+	case P256VerifyAddress:
+		if fork.IsActive(Osaka) {
+			return P256Verify(fork)
+		}
+	}
+	return nil
+}
+
+// IsPrecompile returns true if addr holds a precompiled contract
+func (vm *VM) IsPrecompile(addr Address) bool {
+	return vm.Precompile(addr) != nil
+}
+
+// RunPrecompile executes the precompiled contract at addr with the given gas.
+// Returns the output and the gas left over after the precompile's cost.
+func (vm *VM) RunPrecompile(addr Address, input []byte, gas uint64) ([]byte, uint64, error) {
+	contract := vm.Precompile(addr)
+	if contract == nil {
+		return nil, gas, fmt.Errorf("no precompile at address 0x%x", addr)
+	}
+
+	required := contract.RequiredGas(input)
+	if gas < required {
+		return nil, 0, &OutOfGasError{
+			Required:  required,
+			Remaining: gas,
+		}
+	}
+	output, err := contract.Run(input)
+	return output, gas - required, err
+}
+
+// runEcrecover recovers the signer address of a hash (address 0x01).
+// Input: hash(32) || v(32) || r(32) || s(32). Malformed signatures return
+// empty output instead of failing the call.
+func runEcrecover(input []byte) ([]byte, error) {
+	data := rightPad(input, 128)
+
+	// v must be 27 or 28 encoded as a full 32-byte word
+	for _, b := range data[32:63] {
+		if b != 0 {
+			return nil, nil
+		}
+	}
+	v := data[63]
+	if v != 27 && v != 28 {
+		return nil, nil
+	}
+
+	r := new(big.Int).SetBytes(data[64:96])
+	s := new(big.Int).SetBytes(data[96:128])
+	pub, err := crypto.RecoverPubkey(data[:32], r, s, v-27)
+	if err != nil {
+		return nil, nil
+	}
+
+	addr := crypto.PubkeyToAddress(pub)
+	output := make([]byte, 32)
+	copy(output[12:], addr[:])
+	return output, nil
+}
+
+// runSha256 hashes the input with SHA-256 (address 0x02)
+func runSha256(input []byte) ([]byte, error) {
+	hash := sha256.Sum256(input)
+	return hash[:], nil
+}
+
+// runRipemd160 hashes the input with RIPEMD-160, left-padded to 32 bytes (address 0x03)
+func runRipemd160(input []byte) ([]byte, error) {
+	hash := crypto.Ripemd160(input)
+	output := make([]byte, 32)
+	copy(output[12:], hash[:])
+	return output, nil
+}
+
+// runIdentity returns a copy of its input (address 0x04)
+func runIdentity(input []byte) ([]byte, error) {
+	output := make([]byte, len(input))
+	copy(output, input)
+	return output, nil
+}
+
+// modexpLengths reads the base, exponent and modulus lengths from the
+// 96-byte header of a MODEXP input
+func modexpLengths(input []byte) (baseLen, expLen, modLen *big.Int) {
+	header := rightPad(input, 96)
+	baseLen = new(big.Int).SetBytes(header[0:32])
+	expLen = new(big.Int).SetBytes(header[32:64])
+	modLen = new(big.Int).SetBytes(header[64:96])
+	return baseLen, expLen, modLen
+}
+
+// modexp is the MODEXP precompile, whose pricing and limits depend on the fork
+type modexp struct {
+	fork Fork
+}
+
+// RequiredGas prices a MODEXP call with the formula of the configured fork:
+// EIP-198 before Berlin, EIP-2565 from Berlin and EIP-7883 from Osaka
+func (m modexp) RequiredGas(input []byte) uint64 {
+	baseLen, expLen, modLen := modexpLengths(input)
+	data := []byte{}
+	if len(input) > 96 {
+		data = input[96:]
+	}
+
+	// The first 32 bytes of the exponent drive the iteration count
+	expHead := new(big.Int)
+	if baseLen.IsUint64() && uint64(len(data)) > baseLen.Uint64() {
+		headLen := uint64(32)
+		if expLen.IsUint64() && expLen.Uint64() < 32 {
+			headLen = expLen.Uint64()
+		}
+		expHead.SetBytes(sliceData(data, baseLen.Uint64(), headLen))
+	}
+
+	// Iteration count: bits of the exponent head plus 8 (16 under Osaka)
+	// per exponent byte beyond the first 32
+	multiplier := big.NewInt(8)
+	if m.fork.IsActive(Osaka) {
+		multiplier = big.NewInt(16)
+	}
+	iterations := new(big.Int)
+	if expLen.Cmp(big.NewInt(32)) > 0 {
+		iterations.Sub(expLen, big.NewInt(32))
+		iterations.Mul(iterations, multiplier)
+	}
+	if bitLen := expHead.BitLen(); bitLen > 0 {
+		iterations.Add(iterations, big.NewInt(int64(bitLen-1)))
+	}
+	if iterations.Sign() == 0 {
+		iterations.SetInt64(1)
+	}
+
+	maxLen := baseLen
+	if modLen.Cmp(maxLen) > 0 {
+		maxLen = modLen
+	}
+	// ceil(maxLen / 8)^2, used by EIP-2565 and EIP-7883
+	words := new(big.Int).Add(maxLen, big.NewInt(7))
+	words.Rsh(words, 3)
+	wordsSquared := new(big.Int).Mul(words, words)
+
+	gas := new(big.Int)
+	switch {
+	case m.fork.IsActive(Osaka):
+		// EIP-7883: 16 for up to 32 bytes, else 2 * words^2, no divisor
+		complexity := big.NewInt(16)
+		if maxLen.Cmp(big.NewInt(32)) > 0 {
+			complexity.Lsh(wordsSquared, 1)
+		}
+		gas.Mul(complexity, iterations)
+		if gas.Cmp(big.NewInt(500)) < 0 {
+			gas.SetInt64(500)
+		}
+	case m.fork.IsActive(Berlin):
+		// EIP-2565: words^2 * iterations / 3, at least 200
+		gas.Mul(wordsSquared, iterations)
+		gas.Div(gas, big.NewInt(3))
+		if gas.Cmp(big.NewInt(200)) < 0 {
+			gas.SetInt64(200)
+		}
+	default:
+		// EIP-198: piecewise quadratic complexity * iterations / 20
+		gas.Mul(byzantiumMultComplexity(maxLen), iterations)
+		gas.Div(gas, big.NewInt(20))
+	}
+
+	if !gas.IsUint64() {
+		return ^uint64(0)
+	}
+	return gas.Uint64()
+}
+
+// byzantiumMultComplexity is the EIP-198 complexity of multiplying x-byte numbers
+func byzantiumMultComplexity(x *big.Int) *big.Int {
+	squared := new(big.Int).Mul(x, x)
+	switch {
+	case x.Cmp(big.NewInt(64)) <= 0:
+		return squared
+	case x.Cmp(big.NewInt(1024)) <= 0:
+		// x^2/4 + 96x - 3072
+		result := squared.Rsh(squared, 2)
+		result.Add(result, new(big.Int).Mul(x, big.NewInt(96)))
+		return result.Sub(result, big.NewInt(3072))
+	default:
+		// x^2/16 + 480x - 199680
+		result := squared.Rsh(squared, 4)
+		result.Add(result, new(big.Int).Mul(x, big.NewInt(480)))
+		return result.Sub(result, big.NewInt(199680))
+	}
+}
+
+// Run computes base^exp % mod over arbitrary length integers (address 0x05).
+// Input: baseLen(32) || expLen(32) || modLen(32) || base || exp || mod, with
+// missing bytes treated as zero. The result is left-padded to modLen bytes.
+func (m modexp) Run(input []byte) ([]byte, error) {
+	baseLenBig, expLenBig, modLenBig := modexpLengths(input)
+
+	// EIP-7823: Osaka caps each operand at 1024 bytes
+	if m.fork.IsActive(Osaka) {
+		limit := big.NewInt(1024)
+		if baseLenBig.Cmp(limit) > 0 || expLenBig.Cmp(limit) > 0 || modLenBig.Cmp(limit) > 0 {
+			return nil, ErrModexpInputTooLong
+		}
+	}
+	if baseLenBig.Sign() == 0 && modLenBig.Sign() == 0 {
+		return []byte{}, nil
+	}
+	if !baseLenBig.IsUint64() || !expLenBig.IsUint64() || !modLenBig.IsUint64() {
+		// Unreachable in practice, the gas cost cannot be paid
+		return nil, ErrModexpInputTooLong
+	}
+	baseLen, expLen, modLen := baseLenBig.Uint64(), expLenBig.Uint64(), modLenBig.Uint64()
+
+	data := []byte{}
+	if len(input) > 96 {
+		data = input[96:]
+	}
+	base := new(big.Int).SetBytes(sliceData(data, 0, baseLen))
+	exp := new(big.Int).SetBytes(sliceData(data, baseLen, expLen))
+	mod := new(big.Int).SetBytes(sliceData(data, baseLen+expLen, modLen))
+
+	output := make([]byte, modLen)
+	if mod.Sign() == 0 {
+		// Modulo zero is undefined and returns zero
+		return output, nil
+	}
+	new(big.Int).Exp(base, exp, mod).FillBytes(output)
+	return output, nil
+}
+
+// runBn254Add adds two alt_bn128 G1 points (address 0x06).
+// Input: x1 || y1 || x2 || y2, zero-padded to 128 bytes.
+func runBn254Add(input []byte) ([]byte, error) {
+	data := rightPad(input, 128)
+	p1, err := bn254.UnmarshalG1(data[:64])
+	if err != nil {
+		return nil, err
+	}
+	p2, err := bn254.UnmarshalG1(data[64:])
+	if err != nil {
+		return nil, err
+	}
+	return p1.Add(p2).Marshal(), nil
+}
+
+// runBn254Mul multiplies an alt_bn128 G1 point by a scalar (address 0x07).
+// Input: x || y || scalar, zero-padded to 96 bytes.
+func runBn254Mul(input []byte) ([]byte, error) {
+	data := rightPad(input, 96)
+	p, err := bn254.UnmarshalG1(data[:64])
+	if err != nil {
+		return nil, err
+	}
+	scalar := new(big.Int).SetBytes(data[64:])
+	return p.ScalarMult(scalar).Marshal(), nil
+}
+
+// runBn254Pairing checks that the product of pairings over (G1, G2) pairs is
+// one (address 0x08). Each pair is 192 bytes; returns 32-byte 1 or 0.
+func runBn254Pairing(input []byte) ([]byte, error) {
+	if len(input)%192 != 0 {
+		return nil, ErrBn254PairingInput
+	}
+	var g1s []*bn254.G1
+	var g2s []*bn254.G2
+	for i := 0; i < len(input); i += 192 {
+		g1, err := bn254.UnmarshalG1(input[i : i+64])
+		if err != nil {
+			return nil, err
+		}
+		g2, err := bn254.UnmarshalG2(input[i+64 : i+192])
+		if err != nil {
+			return nil, err
+		}
+		g1s = append(g1s, g1)
+		g2s = append(g2s, g2)
+	}
+
+	output := make([]byte, 32)
+	if bn254.PairingCheck(g1s, g2s) {
+		output[31] = 1
+	}
+	return output, nil
+}
+
+// blake2fGas charges one unit per round, read from the first four bytes
+func blake2fGas(input []byte) uint64 {
+	if len(input) != 213 {
+		return 0
+	}
+	return uint64(binary.BigEndian.Uint32(input[:4])) * GasBlake2fRound
+}
+
+// runBlake2f runs the BLAKE2b compression function F (address 0x09, EIP-152).
+// Input: rounds(4) || h(64) || m(128) || t(16) || f(1), integers little-endian
+// except the big-endian round count.
+func runBlake2f(input []byte) ([]byte, error) {
+	if len(input) != 213 {
+		return nil, ErrBlake2fInputLength
+	}
+	if input[212] > 1 {
+		return nil, ErrBlake2fFinalFlag
+	}
+
+	rounds := binary.BigEndian.Uint32(input[:4])
+	var h [8]uint64
+	var m [16]uint64
+	var t [2]uint64
+	for i := range h {
+		h[i] = binary.LittleEndian.Uint64(input[4+i*8:])
+	}
+	for i := range m {
+		m[i] = binary.LittleEndian.Uint64(input[68+i*8:])
+	}
+	t[0] = binary.LittleEndian.Uint64(input[196:])
+	t[1] = binary.LittleEndian.Uint64(input[204:])
+
+	crypto.Blake2bF(&h, m, t, input[212] == 1, rounds)
+
+	output := make([]byte, 64)
+	for i := range h {
+		binary.LittleEndian.PutUint64(output[i*8:], h[i])
+	}
+	return output, nil
+}
+
+// runPointEvaluation verifies a KZG proof that a blob polynomial evaluates
+// to y at z (address 0x0a, EIP-4844). Input: versionedHash(32) || z(32) ||
+// y(32) || commitment(48) || proof(48). Returns the number of field elements
+// per blob and the BLS modulus, each as a 32-byte word.
+func runPointEvaluation(input []byte) ([]byte, error) {
+	if len(input) != 192 {
+		return nil, ErrPointEvalInput
+	}
+	commitment := input[96:144]
+	if kzg.VersionedHash(commitment) != [32]byte(input[:32]) {
+		return nil, ErrPointEvalHash
+	}
+	ok, err := kzg.VerifyProof(commitment, input[32:64], input[64:96], input[144:192])
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrPointEvalProof
+	}
+
+	output := make([]byte, 64)
+	big.NewInt(kzg.FieldElementsPerBlob).FillBytes(output[:32])
+	bls12381.Order.FillBytes(output[32:])
+	return output, nil
+}
+
+// bls12381MsmGas prices a multi-scalar multiplication of k pairs:
+// k * mulGas * discount(k) / 1000, with the discount capped at 128 pairs
+func bls12381MsmGas(k int, mulGas uint64, discounts *[128]uint64) uint64 {
+	if k == 0 {
+		return 0
+	}
+	discount := discounts[len(discounts)-1]
+	if k <= len(discounts) {
+		discount = discounts[k-1]
+	}
+	return uint64(k) * mulGas * discount / 1000
+}
+
+// runBls12381G1Add adds two BLS12-381 G1 points (address 0x0b).
+// Input: two 128-byte points; no subgroup check is performed.
+func runBls12381G1Add(input []byte) ([]byte, error) {
+	if len(input) != 256 {
+		return nil, ErrBls12381InputLength
+	}
+	p1, err := bls12381.DecodeG1(input[:128])
+	if err != nil {
+		return nil, err
+	}
+	p2, err := bls12381.DecodeG1(input[128:])
+	if err != nil {
+		return nil, err
+	}
+	return p1.Add(p2).Encode(), nil
+}
+
+// runBls12381G1Msm computes the sum of scalar * point over 160-byte
+// (point, scalar) pairs (address 0x0c). Points must be in the subgroup.
+func runBls12381G1Msm(input []byte) ([]byte, error) {
+	if len(input) == 0 || len(input)%160 != 0 {
+		return nil, ErrBls12381InputLength
+	}
+	var result *bls12381.G1
+	for i := 0; i < len(input); i += 160 {
+		p, err := bls12381.DecodeG1(input[i : i+128])
+		if err != nil {
+			return nil, err
+		}
+		if !p.InSubgroup() {
+			return nil, bls12381.ErrNotInSubgroup
+		}
+		scalar := new(big.Int).SetBytes(input[i+128 : i+160])
+		if term := p.ScalarMult(scalar); result == nil {
+			result = term
+		} else {
+			result = result.Add(term)
+		}
+	}
+	return result.Encode(), nil
+}
+
+// runBls12381G2Add adds two BLS12-381 G2 points (address 0x0d).
+// Input: two 256-byte points; no subgroup check is performed.
+func runBls12381G2Add(input []byte) ([]byte, error) {
+	if len(input) != 512 {
+		return nil, ErrBls12381InputLength
+	}
+	p1, err := bls12381.DecodeG2(input[:256])
+	if err != nil {
+		return nil, err
+	}
+	p2, err := bls12381.DecodeG2(input[256:])
+	if err != nil {
+		return nil, err
+	}
+	return p1.Add(p2).Encode(), nil
+}
+
+// runBls12381G2Msm computes the sum of scalar * point over 288-byte
+// (point, scalar) pairs (address 0x0e). Points must be in the subgroup.
+func runBls12381G2Msm(input []byte) ([]byte, error) {
+	if len(input) == 0 || len(input)%288 != 0 {
+		return nil, ErrBls12381InputLength
+	}
+	var result *bls12381.G2
+	for i := 0; i < len(input); i += 288 {
+		p, err := bls12381.DecodeG2(input[i : i+256])
+		if err != nil {
+			return nil, err
+		}
+		if !p.InSubgroup() {
+			return nil, bls12381.ErrNotInSubgroup
+		}
+		scalar := new(big.Int).SetBytes(input[i+256 : i+288])
+		if term := p.ScalarMult(scalar); result == nil {
+			result = term
+		} else {
+			result = result.Add(term)
+		}
+	}
+	return result.Encode(), nil
+}
+
+// runBls12381Pairing checks that the product of pairings over 384-byte
+// (G1, G2) pairs is one (address 0x0f). Returns 32-byte 1 or 0.
+func runBls12381Pairing(input []byte) ([]byte, error) {
+	if len(input) == 0 || len(input)%384 != 0 {
+		return nil, ErrBls12381InputLength
+	}
+	var g1s []*bls12381.G1
+	var g2s []*bls12381.G2
+	for i := 0; i < len(input); i += 384 {
+		g1, err := bls12381.DecodeG1(input[i : i+128])
+		if err != nil {
+			return nil, err
+		}
+		g2, err := bls12381.DecodeG2(input[i+128 : i+384])
+		if err != nil {
+			return nil, err
+		}
+		if !g1.InSubgroup() || !g2.InSubgroup() {
+			return nil, bls12381.ErrNotInSubgroup
+		}
+		g1s = append(g1s, g1)
+		g2s = append(g2s, g2)
+	}
+
+	output := make([]byte, 32)
+	if bls12381.PairingCheck(g1s, g2s) {
+		output[31] = 1
+	}
+	return output, nil
+}
+
+// runBls12381MapG1 maps a 64-byte field element to G1 (address 0x10)
+func runBls12381MapG1(input []byte) ([]byte, error) {
+	if len(input) != 64 {
+		return nil, ErrBls12381InputLength
+	}
+	p, err := bls12381.MapToG1(input)
+	if err != nil {
+		return nil, err
+	}
+	return p.Encode(), nil
+}
+
+// runBls12381MapG2 maps a 128-byte Fp2 element to G2 (address 0x11)
+func runBls12381MapG2(input []byte) ([]byte, error) {
+	if len(input) != 128 {
+		return nil, ErrBls12381InputLength
+	}
+	p, err := bls12381.MapToG2(input)
+	if err != nil {
+		return nil, err
+	}
+	return p.Encode(), nil
+}
+
+// P256Verify returns the P256VERIFY precompile priced for fork: 6900 gas
+// from Osaka (EIP-7951), the RIP-7212 price of 3450 for chains that register
+// it at 0x100 before Osaka
+func P256Verify(fork Fork) Precompile {
+	if fork.IsActive(Osaka) {
+		return &precompile{fixedGas(GasP256Verify), runP256Verify}
+	}
+	return &precompile{fixedGas(GasP256VerifyRIP7212), runP256Verify}
+}
+
+// runP256Verify verifies a secp256r1 signature (address 0x100, EIP-7951).
+// Input: hash(32) || r(32) || s(32) || x(32) || y(32). Returns 32-byte 1 for a
+// valid signature; anything else, including a wrong input length, returns
+// empty output without failing the call.
+func runP256Verify(input []byte) ([]byte, error) {
+	if len(input) != 160 {
+		return nil, nil
+	}
+	hash := input[:32]
+	r := new(big.Int).SetBytes(input[32:64])
+	s := new(big.Int).SetBytes(input[64:96])
+	x := new(big.Int).SetBytes(input[96:128])
+	y := new(big.Int).SetBytes(input[128:160])
+	if !crypto.VerifyP256(hash, r, s, x, y) {
+		return nil, nil
+	}
+	output := make([]byte, 32)
+	output[31] = 1
+	return output, nil
+}
+
+// wordCount returns the number of 32-byte words needed to hold data
+func wordCount(data []byte) uint64 {
+	return (uint64(len(data)) + 31) / 32
+}
+
+// sliceData returns size bytes of data starting at start, zero-padded past the end
+func sliceData(data []byte, start, size uint64) []byte {
+	result := make([]byte, size)
+	if start < uint64(len(data)) {
+		copy(result, data[start:])
+	}
+	return result
+}
+
+// rightPad returns data zero-padded (or truncated) to exactly size bytes
+func rightPad(data []byte, size int) []byte {
+	padded := make([]byte, size)
+	copy(padded, data)
+	return padded
+}
@@ -0,0 +1,194 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Block processing: the system calls, the transactions, the withdrawals and
+// the checks of the results against the header (Yellow Paper section 12)
+
+var (
+	SystemAddress         = mustAddress("0xfffffffffffffffffffffffffffffffffffffffe")
+	BeaconRootsAddress    = mustAddress("0x000F3df6D732807Ef1319fB7B8bB8522d0Beac02") // EIP-4788
+	HistoryStorageAddress = mustAddress("0x0000F90827F1C53a10cb7A02335B175320002935") // EIP-2935
+)
+
+const (
+	SystemCallGas uint64 = 30_000_000 // Gas given to system calls
+	GweiPerEther         = 1_000_000_000
+)
+
+// BlockReward is paid to the coinbase of proof-of-work blocks from
+// Constantinople (EIP-1234); ommers are not supported
+var BlockReward = new(big.Int).Mul(big.NewInt(2), big.NewInt(1e18))
+
+// Errors for blocks whose header does not match their execution
+var (
+	ErrInvalidGasUsed      = errors.New("invalid gas used")
+	ErrInvalidBloom        = errors.New("invalid bloom")
+	ErrInvalidReceiptsRoot = errors.New("invalid receipt root hash")
+	ErrInvalidStateRoot    = errors.New("invalid merkle root")
+	ErrWithdrawalsBefore   = errors.New("withdrawals before shanghai")
+)
+
+// BlockResult is the outcome of executing a block
+type BlockResult struct {
+	Receipts     []*Receipt
+	GasUsed      uint64
+	Bloom        Bloom
+	ReceiptsRoot Word
+	StateRoot    Word
+	BlobGasUsed  uint64   // Blob gas of the blob transactions (EIP-4844)
+	Requests     [][]byte // Deposit, withdrawal and consolidation requests from Prague (EIP-7685)
+}
+
+// ProcessBlock executes a block on state and checks the gas used, bloom,
+// receipts root, state root and requests hash against the header. On error
+// the state is left partly updated and should be discarded.
+func ProcessBlock(state *StateDB, header *Header, txs []*Transaction, withdrawals []*Withdrawal, fork Fork, chainID *big.Int, getHash func(uint64) Word) (*BlockResult, error) {
+	result, err := ExecuteBlock(state, header, txs, withdrawals, fork, chainID, getHash)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateState(header, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ExecuteBlock applies the system calls, transactions, requests and
+// withdrawals of a block to state without checking the header's
+// commitments. A transaction that is invalid or exceeds the gas left in the
+// block makes the block invalid.
+func ExecuteBlock(state *StateDB, header *Header, txs []*Transaction, withdrawals []*Withdrawal, fork Fork, chainID *big.Int, getHash func(uint64) Word) (*BlockResult, error) {
+	block := header.Context(fork, chainID, getHash)
+	if header.ParentBeaconRoot != nil && fork.IsActive(Cancun) {
+		ProcessBeaconBlockRoot(state, block, *header.ParentBeaconRoot, fork)
+	}
+	if fork.IsActive(Prague) {
+		ProcessParentBlockHash(state, block, header.ParentHash, fork)
+	}
+
+	result := &BlockResult{Receipts: make([]*Receipt, 0, len(txs))}
+	var logIndex uint
+	for i, tx := range txs {
+		if available := header.GasLimit - result.GasUsed; tx.Gas > available {
+			return nil, fmt.Errorf("could not apply tx %d [0x%x]: %w: have %d, want %d", i, tx.Hash(), ErrGasLimitReached, available, tx.Gas)
+		}
+		// The blobs of the block are limited like its gas
+		if cfg := BlobParams(fork); cfg != nil {
+			if blobGas := uint64(len(tx.BlobHashes)) * GasPerBlob; result.BlobGasUsed+blobGas > cfg.MaxBlobGas() {
+				return nil, fmt.Errorf("could not apply tx %d [0x%x]: %w: block blob gas %d, max %d", i, tx.Hash(), ErrTooManyBlobs, result.BlobGasUsed+blobGas, cfg.MaxBlobGas())
+			}
+		}
+		receipt, err := ApplyTransaction(state, block, tx, fork, &result.GasUsed)
+		if err != nil {
+			return nil, fmt.Errorf("could not apply tx %d [0x%x]: %w", i, tx.Hash(), err)
+		}
+		result.BlobGasUsed += receipt.BlobGasUsed
+		receipt.TransactionIndex = uint(i)
+		for _, log := range receipt.Logs {
+			log.TxIndex, log.Index = uint(i), logIndex
+			logIndex++
+		}
+		result.Receipts = append(result.Receipts, receipt)
+	}
+
+	if fork.IsActive(Prague) {
+		if err := processRequests(state, block, result, fork); err != nil {
+			return nil, err
+		}
+	}
+	if len(withdrawals) > 0 && !fork.IsActive(Shanghai) {
+		return nil, fmt.Errorf("%w: %d withdrawals", ErrWithdrawalsBefore, len(withdrawals))
+	}
+	for _, w := range withdrawals {
+		amount := new(big.Int).Mul(new(big.Int).SetUint64(w.Amount), big.NewInt(GweiPerEther))
+		state.AddBalance(w.Address, amount)
+	}
+	if header.Difficulty != nil && header.Difficulty.Sign() != 0 {
+		state.AddBalance(header.Coinbase, BlockReward)
+	}
+	state.Finalise()
+
+	result.Bloom = CreateBloom(result.Receipts)
+	result.ReceiptsRoot = ReceiptsRoot(result.Receipts)
+	result.StateRoot = state.Root()
+	return result, nil
+}
+
+// ValidateState checks the results of executing a block against its header
+func ValidateState(header *Header, result *BlockResult) error {
+	if header.GasUsed != result.GasUsed {
+		return fmt.Errorf("%w (remote: %d local: %d)", ErrInvalidGasUsed, header.GasUsed, result.GasUsed)
+	}
+	if header.BlobGasUsed != nil && *header.BlobGasUsed != result.BlobGasUsed {
+		return fmt.Errorf("%w (remote: %d local: %d)", ErrInvalidBlobGasUsed, *header.BlobGasUsed, result.BlobGasUsed)
+	}
+	if header.Bloom != result.Bloom {
+		return fmt.Errorf("%w (remote: %x local: %x)", ErrInvalidBloom, header.Bloom, result.Bloom)
+	}
+	if header.ReceiptHash != result.ReceiptsRoot {
+		return fmt.Errorf("%w (remote: %x local: %x)", ErrInvalidReceiptsRoot, header.ReceiptHash, result.ReceiptsRoot)
+	}
+	if header.Root != result.StateRoot {
+		return fmt.Errorf("%w (remote: %x local: %x)", ErrInvalidStateRoot, header.Root, result.StateRoot)
+	}
+	if header.RequestsHash != nil {
+		if hash := RequestsHash(result.Requests); *header.RequestsHash != hash {
+			return fmt.Errorf("%w (remote: %x local: %x)", ErrInvalidRequestsHash, *header.RequestsHash, hash)
+		}
+	}
+	return nil
+}
+
+// processRequests collects the requests of a block (EIP-7685): the deposits
+// logged by its transactions, then the queued withdrawals and consolidations
+func processRequests(state *StateDB, block *BlockContext, result *BlockResult, fork Fork) error {
+	var logs []*Log
+	for _, receipt := range result.Receipts {
+		logs = append(logs, receipt.Logs...)
+	}
+	deposits, err := ParseDepositLogs(logs)
+	if err != nil {
+		return err
+	}
+	withdrawals, err := ProcessWithdrawalQueue(state, block, fork)
+	if err != nil {
+		return err
+	}
+	consolidations, err := ProcessConsolidationQueue(state, block, fork)
+	if err != nil {
+		return err
+	}
+	result.Requests = [][]byte{deposits, withdrawals, consolidations}
+	return nil
+}
+
+// ProcessBeaconBlockRoot stores the parent beacon block root in the
+// EIP-4788 contract, keyed by the block timestamp (Cancun). Failures are
+// ignored.
+func ProcessBeaconBlockRoot(state *StateDB, block *BlockContext, root Word, fork Fork) {
+	systemCall(state, block, BeaconRootsAddress, root[:], fork)
+}
+
+// ProcessParentBlockHash stores the parent block hash in the EIP-2935
+// history contract (Prague). Failures are ignored.
+func ProcessParentBlockHash(state *StateDB, block *BlockContext, parent Word, fork Fork) {
+	systemCall(state, block, HistoryStorageAddress, parent[:], fork)
+}
+
+// systemCall calls a system contract from SystemAddress outside of any
+// transaction: no gas is bought and no nonce is used. It returns the output
+// of the call; callers decide whether a failure invalidates the block.
+func systemCall(state *StateDB, block *BlockContext, addr Address, input []byte, fork Fork) ([]byte, error) {
+	msg := &Message{From: SystemAddress, To: &addr, Value: new(big.Int), GasLimit: SystemCallGas, GasPrice: new(big.Int), Data: input}
+	st := &stateTransition{state: state, block: block, msg: msg, fork: fork, gasPrice: new(big.Int), gasLeft: SystemCallGas}
+	state.BeginTransaction()
+	state.AddAddressToAccessList(addr)
+	output, _, err := st.call(addr)
+	state.Finalise()
+	return output, err
+}
@@ -0,0 +1,125 @@
+package types
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/morelucks/minievm/crypto"
+	"github.com/morelucks/minievm/trie"
+)
+
+// Account and storage proofs in the format of eth_getProof (EIP-1186)
+
+var ErrProofMismatch = errors.New("proof does not match the claimed value")
+
+// AccountResult is an account with the Merkle proof of its state trie entry
+// and proofs for some of its storage slots
+type AccountResult struct {
+	Address      Address         `json:"address"`
+	AccountProof []HexBytes      `json:"accountProof"`
+	Balance      *HexBig         `json:"balance"`
+	CodeHash     Word            `json:"codeHash"`
+	Nonce        HexUint64       `json:"nonce"`
+	StorageHash  Word            `json:"storageHash"`
+	StorageProof []StorageResult `json:"storageProof"`
+}
+
+// StorageResult is a storage slot with the proof of its storage trie entry
+type StorageResult struct {
+	Key   Word       `json:"key"`
+	Value *HexBig    `json:"value"`
+	Proof []HexBytes `json:"proof"`
+}
+
+// GetProof proves the account at addr and the given storage slots against
+// the current state root. Missing accounts and slots get proofs of absence.
+func (s *StateDB) GetProof(addr Address, keys []Word) *AccountResult {
+	hashedAddr := crypto.Keccak256(addr[:])
+	storage := s.storageTrie(addr)
+	result := &AccountResult{
+		Address:      addr,
+		AccountProof: toHexBytes(s.stateTrie().Prove(hashedAddr[:])),
+		Balance:      NewHexBig(s.GetBalance(addr)),
+		CodeHash:     s.CodeHash(addr),
+		Nonce:        HexUint64(s.GetNonce(addr)),
+		StorageHash:  Word(storage.Hash()),
+		StorageProof: make([]StorageResult, len(keys)),
+	}
+	for i, key := range keys {
+		hashedKey := crypto.Keccak256(key[:])
+		value := s.GetState(addr, key)
+		result.StorageProof[i] = StorageResult{
+			Key:   key,
+			Value: NewHexBig(value.ToBigInt()),
+			Proof: toHexBytes(storage.Prove(hashedKey[:])),
+		}
+	}
+	return result
+}
+
+// VerifyAccountProof checks the account and storage proofs of result
+// against the state root
+func VerifyAccountProof(root Word, result *AccountResult) error {
+	hashedAddr := crypto.Keccak256(result.Address[:])
+	value, err := trie.VerifyProof(root, hashedAddr[:], fromHexBytes(result.AccountProof))
+	if err != nil {
+		return fmt.Errorf("account 0x%x: %w", result.Address, err)
+	}
+	balance := new(big.Int)
+	if result.Balance != nil {
+		balance = result.Balance.ToInt()
+	}
+	// Absent accounts must be claimed as empty
+	want := encodeAccount(uint64(result.Nonce), balance, result.StorageHash, result.CodeHash)
+	if value == nil {
+		want = nil
+		if result.Nonce != 0 || balance.Sign() != 0 || result.CodeHash != EmptyCodeHash || result.StorageHash != EmptyRootHash {
+			return fmt.Errorf("%w: account 0x%x is not in the state", ErrProofMismatch, result.Address)
+		}
+	}
+	if !bytes.Equal(value, want) {
+		return fmt.Errorf("%w: account 0x%x", ErrProofMismatch, result.Address)
+	}
+
+	for _, slot := range result.StorageProof {
+		hashedKey := crypto.Keccak256(slot.Key[:])
+		value, err := trie.VerifyProof(result.StorageHash, hashedKey[:], fromHexBytes(slot.Proof))
+		if err != nil {
+			return fmt.Errorf("slot 0x%x: %w", slot.Key, err)
+		}
+		claimed := Word{}
+		if slot.Value != nil {
+			if slot.Value.ToInt().BitLen() > 256 {
+				return fmt.Errorf("%w: slot 0x%x value too large", ErrProofMismatch, slot.Key)
+			}
+			claimed = NewWordFromBytes(slot.Value.ToInt().Bytes())
+		}
+		// Zero slots are not stored, so they are proven by absence
+		var want []byte
+		if claimed != (Word{}) {
+			want = encodeSlot(claimed)
+		}
+		if !bytes.Equal(value, want) {
+			return fmt.Errorf("%w: slot 0x%x", ErrProofMismatch, slot.Key)
+		}
+	}
+	return nil
+}
+
+func toHexBytes(proof [][]byte) []HexBytes {
+	out := make([]HexBytes, len(proof))
+	for i, node := range proof {
+		out[i] = node
+	}
+	return out
+}
+
+func fromHexBytes(proof []HexBytes) [][]byte {
+	out := make([][]byte, len(proof))
+	for i, node := range proof {
+		out[i] = node
+	}
+	return out
+}
@@ -0,0 +1,137 @@
+package types
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func proofState() *StateDB {
+	state := NewStateDB()
+	for i := byte(1); i <= 20; i++ {
+		state.AddBalance(Address{19: i}, big.NewInt(int64(i)*1000))
+	}
+	contract := Address{19: 1}
+	state.SetNonce(contract, 5)
+	state.SetCode(contract, []byte{0x60, 0x00, 0x54})
+	for i := uint64(1); i <= 20; i++ {
+		state.SetState(contract, NewWord(i), NewWord(i*i))
+	}
+	return state
+}
+
+func TestGetProof(t *testing.T) {
+	tests := []struct {
+		name string
+		addr Address
+		keys []Word
+	}{
+		{name: "contract", addr: Address{19: 1}, keys: []Word{NewWord(1), NewWord(7), NewWord(20)}},
+		{name: "absentSlot", addr: Address{19: 1}, keys: []Word{NewWord(0), NewWord(21)}},
+		{name: "noStorage", addr: Address{19: 2}, keys: []Word{NewWord(1)}},
+		{name: "absentAccount", addr: Address{19: 0xff}, keys: []Word{NewWord(1)}},
+	}
+	state := proofState()
+	root := state.Root()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := state.GetProof(tt.addr, tt.keys)
+			if err := VerifyAccountProof(root, result); err != nil {
+				t.Fatal(err)
+			}
+			if result.Balance.ToInt().Cmp(state.GetBalance(tt.addr)) != 0 {
+				t.Errorf("balance %v, want %v", result.Balance.ToInt(), state.GetBalance(tt.addr))
+			}
+			if result.StorageHash != state.StorageRoot(tt.addr) {
+				t.Errorf("storage hash %x, want %x", result.StorageHash, state.StorageRoot(tt.addr))
+			}
+			for i, slot := range result.StorageProof {
+				if got := NewWordFromBytes(slot.Value.ToInt().Bytes()); got != state.GetState(tt.addr, tt.keys[i]) {
+					t.Errorf("slot %x = %x, want %x", tt.keys[i], got, state.GetState(tt.addr, tt.keys[i]))
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyAccountProofErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		addr   Address
+		modify func(result *AccountResult)
+		err    error // nil for any failure
+	}{
+		{
+			name:   "balance",
+			addr:   Address{19: 1},
+			modify: func(r *AccountResult) { r.Balance = NewHexBig(big.NewInt(1)) },
+			err:    ErrProofMismatch,
+		},
+		{
+			name:   "nonce",
+			addr:   Address{19: 1},
+			modify: func(r *AccountResult) { r.Nonce++ },
+			err:    ErrProofMismatch,
+		},
+		{
+			name:   "codeHash",
+			addr:   Address{19: 1},
+			modify: func(r *AccountResult) { r.CodeHash = EmptyCodeHash },
+			err:    ErrProofMismatch,
+		},
+		{
+			name:   "slotValue",
+			addr:   Address{19: 1},
+			modify: func(r *AccountResult) { r.StorageProof[0].Value = NewHexBig(big.NewInt(2)) },
+			err:    ErrProofMismatch,
+		},
+		{
+			name:   "absentSlotClaimed",
+			addr:   Address{19: 1},
+			modify: func(r *AccountResult) { r.StorageProof[1].Value = NewHexBig(big.NewInt(1)) },
+			err:    ErrProofMismatch,
+		},
+		{
+			name:   "slotValueTooLarge",
+			addr:   Address{19: 1},
+			modify: func(r *AccountResult) { r.StorageProof[0].Value = NewHexBig(new(big.Int).Lsh(big.NewInt(1), 256)) },
+			err:    ErrProofMismatch,
+		},
+		{
+			name:   "absentAccountClaimed",
+			addr:   Address{19: 0xff},
+			modify: func(r *AccountResult) { r.Balance = NewHexBig(big.NewInt(1)) },
+			err:    ErrProofMismatch,
+		},
+		{
+			name:   "otherAddress",
+			addr:   Address{19: 1},
+			modify: func(r *AccountResult) { r.Address = Address{19: 2} },
+		},
+		{
+			name:   "truncatedAccountProof",
+			addr:   Address{19: 1},
+			modify: func(r *AccountResult) { r.AccountProof = r.AccountProof[:len(r.AccountProof)-1] },
+		},
+		{
+			name:   "truncatedStorageProof",
+			addr:   Address{19: 1},
+			modify: func(r *AccountResult) { r.StorageProof[0].Proof = r.StorageProof[0].Proof[:1] },
+		},
+	}
+	state := proofState()
+	root := state.Root()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := state.GetProof(tt.addr, []Word{NewWord(1), NewWord(21)})
+			tt.modify(result)
+			err := VerifyAccountProof(root, result)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("error %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
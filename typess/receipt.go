@@ -0,0 +1,220 @@
+package types
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/morelucks/minievm/crypto"
+	"github.com/morelucks/minievm/trie"
+)
+
+// Receipt status codes (EIP-658)
+const (
+	ReceiptStatusFailed     uint64 = 0
+	ReceiptStatusSuccessful uint64 = 1
+)
+
+// ErrInvalidChainID is returned for transactions signed for another chain
+var ErrInvalidChainID = errors.New("invalid chain id for signer")
+
+// Log is an event emitted by LOG0..LOG4 (Yellow Paper section 4.3.1): the
+// emitting account, up to four topics and the data. The other fields are
+// filled in when the transaction is included in a block.
+type Log struct {
+	Address Address
+	Topics  []Word
+	Data    []byte
+
+	BlockNumber uint64 `rlp:"-"`
+	TxHash      Word   `rlp:"-"`
+	TxIndex     uint   `rlp:"-"`
+	Index       uint   `rlp:"-"` // Position of the log in the block
+}
+
+// BloomByteLength is the size of a logs bloom, 2048 bits
+const BloomByteLength = 256
+
+// Bloom is the 2048-bit filter over log addresses and topics used to find
+// receipts with logs of interest (Yellow Paper equation 26)
+type Bloom [BloomByteLength]byte
+
+// Add sets the three bits selected by keccak256(data): for each of the first
+// three byte pairs of the hash, its low 11 bits index a bit from the end
+func (b *Bloom) Add(data []byte) {
+	hash := crypto.Keccak256(data)
+	for i := 0; i < 6; i += 2 {
+		bit := (uint(hash[i])<<8 | uint(hash[i+1])) & 2047
+		b[BloomByteLength-1-bit/8] |= 1 << (bit % 8)
+	}
+}
+
+// Test reports whether data may have been added; false means it was not
+func (b Bloom) Test(data []byte) bool {
+	var probe Bloom
+	probe.Add(data)
+	for i := range probe {
+		if b[i]&probe[i] != probe[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Or merges other into b
+func (b *Bloom) Or(other Bloom) {
+	for i := range b {
+		b[i] |= other[i]
+	}
+}
+
+func (b Bloom) MarshalText() ([]byte, error) {
+	return []byte("0x" + hex.EncodeToString(b[:])), nil
+}
+
+func (b *Bloom) UnmarshalText(text []byte) error {
+	data, err := ParseHexBytes(string(text))
+	if err != nil || len(data) != BloomByteLength {
+		return fmt.Errorf("invalid bloom %q", text)
+	}
+	copy(b[:], data)
+	return nil
+}
+
+// LogsBloom returns the bloom of the addresses and topics of logs
+func LogsBloom(logs []*Log) Bloom {
+	var b Bloom
+	for _, log := range logs {
+		b.Add(log.Address[:])
+		for _, topic := range log.Topics {
+			b.Add(topic[:])
+		}
+	}
+	return b
+}
+
+// CreateBloom returns the block bloom, the union of the receipt blooms
+func CreateBloom(receipts []*Receipt) Bloom {
+	var b Bloom
+	for _, r := range receipts {
+		b.Or(r.Bloom)
+	}
+	return b
+}
+
+// Receipt is the outcome of a transaction included in a block (Yellow Paper
+// section 4.3.1). Only Type, Status, CumulativeGasUsed, Bloom and Logs are
+// part of the encoding; the other fields are derived from the transaction.
+type Receipt struct {
+	Type              byte
+	Status            uint64 // R_z
+	CumulativeGasUsed uint64 // R_u - Gas used in the block up to and including this transaction
+	Bloom             Bloom  // R_b
+	Logs              []*Log // R_l
+
+	TxHash            Word
+	TransactionIndex  uint
+	ContractAddress   *Address // Set for contract creations
+	GasUsed           uint64
+	EffectiveGasPrice *big.Int
+	BlobGasUsed       uint64   // Blob gas of blob transactions (EIP-4844)
+	BlobGasPrice      *big.Int // Blob base fee paid by blob transactions
+}
+
+// Encode returns the consensus encoding: rlp([status, cumulativeGasUsed,
+// bloom, logs]), prefixed with the transaction type for typed transactions
+func (r *Receipt) Encode() []byte {
+	payload := mustEncode([]any{r.Status, r.CumulativeGasUsed, r.Bloom, r.Logs})
+	if r.Type == LegacyTxType {
+		return payload
+	}
+	return append([]byte{r.Type}, payload...)
+}
+
+// ApplyTransaction applies a signed transaction to the state and returns
+// its receipt. usedGas holds the gas used by the earlier transactions of
+// the block and is increased by this one's.
+func ApplyTransaction(state *StateDB, block *BlockContext, tx *Transaction, fork Fork, usedGas *uint64) (*Receipt, error) {
+	if since := txTypeFork(tx.Type); !fork.IsActive(since) {
+		return nil, fmt.Errorf("%w: type %d before %s", ErrTxTypeNotSupported, tx.Type, since)
+	}
+	if tx.Protected() && tx.ChainID.Cmp(chainIDOf(block)) != 0 {
+		return nil, fmt.Errorf("%w: have %v want %v", ErrInvalidChainID, tx.ChainID, chainIDOf(block))
+	}
+	msg, err := tx.AsMessage()
+	if err != nil {
+		return nil, err
+	}
+	result, err := ApplyMessage(state, block, msg, fork)
+	if err != nil {
+		return nil, err
+	}
+	*usedGas += result.UsedGas
+
+	receipt := &Receipt{
+		Type:              tx.Type,
+		Status:            ReceiptStatusSuccessful,
+		CumulativeGasUsed: *usedGas,
+		Logs:              result.Logs,
+		TxHash:            tx.Hash(),
+		ContractAddress:   result.ContractAddress,
+		GasUsed:           result.UsedGas,
+		EffectiveGasPrice: result.EffectiveGasPrice,
+	}
+	if result.Failed() {
+		receipt.Status = ReceiptStatusFailed
+	}
+	if tx.Type == BlobTxType {
+		receipt.BlobGasUsed = uint64(len(tx.BlobHashes)) * GasPerBlob
+		receipt.BlobGasPrice = block.BlobBaseFee
+	}
+	if receipt.Logs == nil {
+		receipt.Logs = []*Log{}
+	}
+	for _, log := range receipt.Logs {
+		log.BlockNumber, log.TxHash = block.Number, receipt.TxHash
+	}
+	receipt.Bloom = LogsBloom(receipt.Logs)
+	return receipt, nil
+}
+
+// txTypeFork returns the fork that introduced a transaction type
+func txTypeFork(txType byte) Fork {
+	switch txType {
+	case AccessListTxType:
+		return Berlin
+	case DynamicFeeTxType:
+		return London
+	case BlobTxType:
+		return Cancun
+	case SetCodeTxType:
+		return Prague
+	}
+	return Istanbul
+}
+
+func chainIDOf(block *BlockContext) *big.Int {
+	if block.ChainID == nil {
+		return new(big.Int)
+	}
+	return block.ChainID
+}
+
+// TransactionsRoot and ReceiptsRoot return the roots stored in a block
+// header: the trie mapping rlp(index) to the encoding of each item
+func TransactionsRoot(txs []*Transaction) Word {
+	return deriveRoot(len(txs), func(i int) []byte { return txs[i].Encode() })
+}
+
+func ReceiptsRoot(receipts []*Receipt) Word {
+	return deriveRoot(len(receipts), func(i int) []byte { return receipts[i].Encode() })
+}
+
+func deriveRoot(n int, encode func(i int) []byte) Word {
+	t := trie.New()
+	for i := 0; i < n; i++ {
+		t.Update(mustEncode(uint64(i)), encode(i))
+	}
+	return Word(t.Hash())
+}
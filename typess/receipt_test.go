@@ -0,0 +1,187 @@
+package types
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// Receipts encoded by geth, with their blooms
+var receiptVectors = []struct {
+	receipt *Receipt
+	enc     string
+}{
+	{
+		receipt: &Receipt{Type: LegacyTxType, Status: ReceiptStatusSuccessful, CumulativeGasUsed: 21000, Logs: []*Log{
+			{Address: Address{0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11}, Topics: []Word{NewWord(1)}, Data: []byte{1, 2}},
+		}},
+		enc: "f9014501825208b9010000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000040000000000000000000000000000000000000000000000040000000000000000000000000000000000000000000000000000000000000000000000000000000000100000000000000000000000000000000000000000000000000000000000000000000000000000000100000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000040000000000000000002000000000000000000000000000000000000000000000000f83cf83a941111111111111111111111111111111111111111e1a00000000000000000000000000000000000000000000000000000000000000001820102",
+	},
+	{
+		receipt: &Receipt{Type: DynamicFeeTxType, Status: ReceiptStatusFailed, CumulativeGasUsed: 50000, Logs: []*Log{}},
+		enc:     "02f901088082c350b9010000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000c0",
+	},
+	{
+		receipt: &Receipt{Type: BlobTxType, Status: ReceiptStatusSuccessful, CumulativeGasUsed: 100000, Logs: []*Log{
+			{Address: Address{0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22}, Topics: []Word{NewWord(2), NewWord(3)}, Data: []byte{}},
+			{Address: Address{0x33, 0x33, 0x33, 0x33, 0x33, 0x33, 0x33, 0x33, 0x33, 0x33, 0x33, 0x33, 0x33, 0x33, 0x33, 0x33, 0x33, 0x33, 0x33, 0x33}, Data: []byte{}},
+		}},
+		enc: "03f9017e01830186a0b9010004000000020000000000000000000000000000000000000000000000400000000000000000000000000000000000000000000000020000000000000000000400000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000001000000000000000000000000000000000400000000000000000000000000000000000000000000000000000000000000000010100000000000000000000800000000000000000400000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000008000000000000000000000f874f85a942222222222222222222222222222222222222222f842a00000000000000000000000000000000000000000000000000000000000000002a0000000000000000000000000000000000000000000000000000000000000000380d7943333333333333333333333333333333333333333c080",
+	},
+}
+
+func receipts() []*Receipt {
+	out := make([]*Receipt, len(receiptVectors))
+	for i, tt := range receiptVectors {
+		r := *tt.receipt
+		r.Bloom = LogsBloom(r.Logs)
+		out[i] = &r
+	}
+	return out
+}
+
+func TestReceiptEncode(t *testing.T) {
+	for i, r := range receipts() {
+		if got := hex.EncodeToString(r.Encode()); got != receiptVectors[i].enc {
+			t.Errorf("receipt %d encoding\n got %s\nwant %s", i, got, receiptVectors[i].enc)
+		}
+	}
+}
+
+func TestBloom(t *testing.T) {
+	// The union of the receipt blooms, from geth
+	want := "04000000020000000000000000000000000000000000000000000000400000000000000000000000000000000000000000000000020000000000000000040400000000000000000000000000000000000000000000040000000000000000000000000000000000000000000000000000000000000000001000000000000000100000000000000000400000000000000000000000000000000000000000000000000000000000000100010100000000000000000000800000000000000000400000000000000000000000000000000000000000000000000000000000000040000000000000000002000000000000000000000000008000000000000000000000"
+	bloom := CreateBloom(receipts())
+	if got := hex.EncodeToString(bloom[:]); got != want {
+		t.Errorf("bloom\n got %s\nwant %s", got, want)
+	}
+	for _, tt := range receiptVectors {
+		for _, log := range tt.receipt.Logs {
+			if !bloom.Test(log.Address[:]) {
+				t.Errorf("address %x not in bloom", log.Address)
+			}
+			for _, topic := range log.Topics {
+				if !bloom.Test(topic[:]) {
+					t.Errorf("topic %x not in bloom", topic)
+				}
+			}
+		}
+	}
+	if missing := NewWord(4); bloom.Test(missing[:]) {
+		t.Errorf("topic %x in bloom", missing)
+	}
+
+	text, err := bloom.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Bloom
+	if err := decoded.UnmarshalText(text); err != nil || decoded != bloom {
+		t.Errorf("text round trip: %v", err)
+	}
+	if err := decoded.UnmarshalText([]byte("0x00")); err == nil {
+		t.Error("short bloom accepted")
+	}
+}
+
+func TestDeriveRoots(t *testing.T) {
+	// The legacy, access list, dynamic fee and blob vectors
+	var txs []*Transaction
+	for _, i := range []int{0, 2, 3, 5} {
+		txs = append(txs, mustDecodeTx(t, txVectors[i].raw))
+	}
+	tests := []struct {
+		name string
+		got  Word
+		want string
+	}{
+		{"emptyTransactions", TransactionsRoot(nil), "56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421"},
+		{"transactions", TransactionsRoot(txs), "366c808d5c4968db3a336b34ed88268332cf3f385965f0e3e58fc3e82f703dda"},
+		{"emptyReceipts", ReceiptsRoot(nil), "56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421"},
+		{"receipts", ReceiptsRoot(receipts()), "576de039a4a7ee1f312d42538e1f62eee6b59f3b452808f150e505f289cbb6c1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if hex.EncodeToString(tt.got[:]) != tt.want {
+				t.Errorf("root %x, want %s", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyTransaction(t *testing.T) {
+	sender, err := HexToAddress(txVectorSender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	to := Address{0x35, 0x35, 0x35, 0x35, 0x35, 0x35, 0x35, 0x35, 0x35, 0x35, 0x35, 0x35, 0x35, 0x35, 0x35, 0x35, 0x35, 0x35, 0x35, 0x35}
+	tests := []struct {
+		name    string
+		tx      int // Index into txVectors
+		fork    Fork
+		chainID int64
+		code    []byte // Code at the recipient
+		status  uint64
+		logs    int
+		err     error
+	}{
+		// PUSH1 7 PUSH1 0 PUSH1 0 LOG1
+		{name: "log", tx: 3, fork: London, chainID: 1, code: []byte{0x60, 0x07, 0x60, 0x00, 0x60, 0x00, 0xa1}, status: ReceiptStatusSuccessful, logs: 1},
+		{name: "failed", tx: 2, fork: Berlin, chainID: 1, code: []byte{0xfe}, status: ReceiptStatusFailed},
+		{name: "create", tx: 4, fork: London, chainID: 1, status: ReceiptStatusSuccessful},
+		{name: "wrongChain", tx: 3, fork: London, chainID: 2, err: ErrInvalidChainID},
+		{name: "typeBeforeFork", tx: 2, fork: Istanbul, chainID: 1, err: ErrTxTypeNotSupported},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := mustDecodeTx(t, txVectors[tt.tx].raw)
+			state := NewStateDB()
+			state.AddBalance(sender, new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil))
+			state.SetNonce(sender, tx.Nonce)
+			state.SetCode(to, tt.code)
+			block := &BlockContext{Number: 5, GasLimit: 30_000_000, ChainID: big.NewInt(tt.chainID)}
+			if tt.fork.IsActive(London) {
+				block.BaseFee = big.NewInt(1_000_000_000)
+			}
+
+			usedGas := uint64(1000)
+			receipt, err := ApplyTransaction(state, block, tx, tt.fork, &usedGas)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if receipt.Status != tt.status {
+				t.Errorf("status %d, want %d", receipt.Status, tt.status)
+			}
+			if receipt.CumulativeGasUsed != usedGas || usedGas != 1000+receipt.GasUsed {
+				t.Errorf("cumulative gas %d, used gas %d, counter %d", receipt.CumulativeGasUsed, receipt.GasUsed, usedGas)
+			}
+			if tt.status == ReceiptStatusFailed && receipt.GasUsed != tx.Gas {
+				t.Errorf("failed transaction used %d gas, want all %d", receipt.GasUsed, tx.Gas)
+			}
+			if receipt.TxHash != tx.Hash() || receipt.Type != tx.Type {
+				t.Errorf("receipt of tx %x type %d, want %x type %d", receipt.TxHash, receipt.Type, tx.Hash(), tx.Type)
+			}
+			if len(receipt.Logs) != tt.logs {
+				t.Fatalf("%d logs, want %d", len(receipt.Logs), tt.logs)
+			}
+			for _, log := range receipt.Logs {
+				if log.BlockNumber != 5 || log.TxHash != tx.Hash() {
+					t.Errorf("log in block %d tx %x", log.BlockNumber, log.TxHash)
+				}
+			}
+			if receipt.Bloom != LogsBloom(receipt.Logs) || (tt.logs > 0) != receipt.Bloom.Test(to[:]) {
+				t.Errorf("bloom %x does not match the logs", receipt.Bloom)
+			}
+			switch {
+			case tx.To == nil && (receipt.ContractAddress == nil || *receipt.ContractAddress != CreateAddress(sender, tx.Nonce)):
+				t.Errorf("contract address %v, want %x", receipt.ContractAddress, CreateAddress(sender, tx.Nonce))
+			case tx.To != nil && receipt.ContractAddress != nil:
+				t.Errorf("contract address %x for a call", *receipt.ContractAddress)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package types
+
+import (
+	"bytes"
+	"sort"
+)
+
+// Precompile registry
+//
+// Each VM starts with the standard precompiles of its fork. Custom chains and
+// experiments can layer their own contracts on top: registering at a standard
+// address overrides it, and removing an address hides the standard contract.
+// Overrides survive fork changes; the standard set follows vm.Fork.
+
+// RegisterPrecompile installs p at addr, replacing any contract there
+func (vm *VM) RegisterPrecompile(addr Address, p Precompile) {
+	if vm.precompiles == nil {
+		vm.precompiles = make(map[Address]Precompile)
+	}
+	vm.precompiles[addr] = p
+}
+
+// RemovePrecompile disables the contract at addr, standard or custom
+func (vm *VM) RemovePrecompile(addr Address) {
+	vm.RegisterPrecompile(addr, nil)
+}
+
+// ResetPrecompile drops any override at addr, restoring the fork's standard contract
+func (vm *VM) ResetPrecompile(addr Address) {
+	delete(vm.precompiles, addr)
+}
+
+// Precompile returns the contract at addr, or nil if there is none
+func (vm *VM) Precompile(addr Address) Precompile {
+	if p, ok := vm.precompiles[addr]; ok {
+		return p
+	}
+	return StandardPrecompile(vm.Fork, addr)
+}
+
+// ActivePrecompiles returns the addresses of all contracts currently
+// reachable as precompiles, in ascending order
+func (vm *VM) ActivePrecompiles() []Address {
+	var active []Address
+	for _, addr := range standardPrecompileAddresses {
+		if _, overridden := vm.precompiles[addr]; !overridden && StandardPrecompile(vm.Fork, addr) != nil {
+			active = append(active, addr)
+		}
+	}
+	for addr, p := range vm.precompiles {
+		if p != nil {
+			active = append(active, addr)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool {
+		return bytes.Compare(active[i][:], active[j][:]) < 0
+	})
+	return active
+}
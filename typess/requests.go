@@ -0,0 +1,113 @@
+package types
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+// Execution layer requests (EIP-7685, Prague): deposits read from the logs
+// of the deposit contract (EIP-6110) and the withdrawal (EIP-7002) and
+// consolidation (EIP-7251) requests queued in system contracts, committed
+// to by the header's requests hash
+
+// Request types, the first byte of each request list
+const (
+	DepositRequestType       byte = 0x00
+	WithdrawalRequestType    byte = 0x01
+	ConsolidationRequestType byte = 0x02
+)
+
+var (
+	// DepositContractAddress is the beacon chain deposit contract of mainnet,
+	// which test networks use as well
+	DepositContractAddress    = mustAddress("0x00000000219ab540356cBB839Cbe05303d7705Fa")
+	WithdrawalQueueAddress    = mustAddress("0x00000961Ef480Eb55e80D19ad83579A64c007002") // EIP-7002
+	ConsolidationQueueAddress = mustAddress("0x0000BBdDc7CE488642fb579F8B00f3a590007251") // EIP-7251
+
+	// DepositEventTopic is keccak256("DepositEvent(bytes,bytes,bytes,bytes,bytes)")
+	DepositEventTopic = mustWord("0x649bbc62d0e31342afea4e5cd82d4049e7e1ee912fc0889aa790803be39038c5")
+)
+
+var (
+	ErrInvalidRequestsHash = errors.New("invalid requests hash")
+	ErrInvalidDepositLog   = errors.New("invalid deposit log")
+	ErrSystemContract      = errors.New("system contract call failed")
+)
+
+// depositLogSize is the length of the ABI encoded data of a DepositEvent and
+// depositRequestSize that of the request built from it
+const (
+	depositLogSize     = 576
+	depositRequestSize = 192
+)
+
+// depositFieldSizes are the lengths of the values of a DepositEvent:
+// pubkey, withdrawal credentials, amount, signature and index
+var depositFieldSizes = []uint64{48, 32, 8, 96, 8}
+
+// ParseDepositLogs returns the deposit request list of a block: the type
+// byte followed by pubkey, withdrawal credentials, amount, signature and
+// index of every DepositEvent the deposit contract logged
+func ParseDepositLogs(logs []*Log) ([]byte, error) {
+	requests := []byte{DepositRequestType}
+	for _, log := range logs {
+		if log.Address != DepositContractAddress || len(log.Topics) == 0 || log.Topics[0] != DepositEventTopic {
+			continue
+		}
+		if len(log.Data) != depositLogSize {
+			return nil, fmt.Errorf("%w: length %d, want %d", ErrInvalidDepositLog, len(log.Data), depositLogSize)
+		}
+		// The five offsets are followed by the values in order, each a
+		// length word and the value padded to 32 bytes. Logs laid out any
+		// other way are invalid (EIP-6110).
+		offset := uint64(len(depositFieldSizes) * 32)
+		for i, size := range depositFieldSizes {
+			if got, ok := NewWordFromBytes(log.Data[i*32 : (i+1)*32]).ToUint64(); !ok || got != offset {
+				return nil, fmt.Errorf("%w: field %d at offset %x, want %d", ErrInvalidDepositLog, i, log.Data[i*32:(i+1)*32], offset)
+			}
+			if got, ok := NewWordFromBytes(log.Data[offset : offset+32]).ToUint64(); !ok || got != size {
+				return nil, fmt.Errorf("%w: field %d size %x, want %d", ErrInvalidDepositLog, i, log.Data[offset:offset+32], size)
+			}
+			requests = append(requests, log.Data[offset+32:offset+32+size]...)
+			offset += 32 + (size+31)/32*32
+		}
+	}
+	return requests, nil
+}
+
+// ProcessWithdrawalQueue and ProcessConsolidationQueue dequeue the requests
+// of the EIP-7002 and EIP-7251 contracts with a system call at the end of
+// the block. A contract without code or a failing call make the block invalid.
+func ProcessWithdrawalQueue(state *StateDB, block *BlockContext, fork Fork) ([]byte, error) {
+	return dequeueRequests(state, block, WithdrawalRequestType, WithdrawalQueueAddress, fork)
+}
+
+func ProcessConsolidationQueue(state *StateDB, block *BlockContext, fork Fork) ([]byte, error) {
+	return dequeueRequests(state, block, ConsolidationRequestType, ConsolidationQueueAddress, fork)
+}
+
+func dequeueRequests(state *StateDB, block *BlockContext, requestType byte, addr Address, fork Fork) ([]byte, error) {
+	if len(state.GetCode(addr)) == 0 {
+		return nil, fmt.Errorf("%w: no code at 0x%x", ErrSystemContract, addr)
+	}
+	output, err := systemCall(state, block, addr, nil, fork)
+	if err != nil {
+		return nil, fmt.Errorf("%w: 0x%x: %w", ErrSystemContract, addr, err)
+	}
+	return append([]byte{requestType}, output...), nil
+}
+
+// RequestsHash returns the requests hash of a header,
+// sha256(sha256(requests_0) ++ sha256(requests_1) ++ ...) over the
+// request lists that hold at least one request
+func RequestsHash(requests [][]byte) Word {
+	outer := sha256.New()
+	for _, list := range requests {
+		if len(list) > 1 {
+			inner := sha256.Sum256(list)
+			outer.Write(inner[:])
+		}
+	}
+	return Word(outer.Sum(nil))
+}
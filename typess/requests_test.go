@@ -0,0 +1,61 @@
+package types
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// depositLog returns a DepositEvent log whose fields are filled with their
+// index, in the layout of the deposit contract
+func depositLog() (*Log, []byte) {
+	var data, request []byte
+	offset := uint64(len(depositFieldSizes) * 32)
+	for _, size := range depositFieldSizes {
+		word := NewWord(offset)
+		data = append(data, word[:]...)
+		offset += 32 + (size+31)/32*32
+	}
+	for i, size := range depositFieldSizes {
+		value := bytes.Repeat([]byte{byte(i + 1)}, int(size))
+		padded := make([]byte, (size+31)/32*32)
+		copy(padded, value)
+		word := NewWord(size)
+		data = append(data, word[:]...)
+		data = append(data, padded...)
+		request = append(request, value...)
+	}
+	log := &Log{Address: DepositContractAddress, Topics: []Word{DepositEventTopic}, Data: data}
+	return log, request
+}
+
+func TestParseDepositLogs(t *testing.T) {
+	log, request := depositLog()
+	got, err := ParseDepositLogs([]*Log{log})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := append([]byte{DepositRequestType}, request...); !bytes.Equal(got, want) {
+		t.Errorf("requests %x, want %x", got, want)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(data []byte) []byte
+	}{
+		{"short", func(data []byte) []byte { return data[:len(data)-1] }},
+		{"pubkeyOffset", func(data []byte) []byte { data[31] = 0xc0; return data }},
+		{"hugeOffset", func(data []byte) []byte { data[0] = 1; return data }},
+		{"pubkeySize", func(data []byte) []byte { data[5*32+31] = 47; return data }},
+		{"indexSize", func(data []byte) []byte { data[512+31] = 32; return data }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log, _ := depositLog()
+			log.Data = tt.modify(log.Data)
+			if _, err := ParseDepositLogs([]*Log{log}); !errors.Is(err, ErrInvalidDepositLog) {
+				t.Errorf("err %v, want %v", err, ErrInvalidDepositLog)
+			}
+		})
+	}
+}
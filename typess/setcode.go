@@ -0,0 +1,91 @@
+package types
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+
+	"github.com/morelucks/minievm/crypto"
+)
+
+// EIP-7702 set-code transactions let an EOA delegate to contract code by
+// writing a delegation designator, 0xef0100 || address, as its code
+
+// DelegationPrefix starts every delegation designator. 0xef can never begin
+// deployed code (EIP-3541), so designators cannot clash with contracts.
+var DelegationPrefix = []byte{0xef, 0x01, 0x00}
+
+// setCodeAuthMagic is prepended to the authorization tuple before signing
+const setCodeAuthMagic byte = 0x05
+
+var (
+	// Reasons an authorization is skipped; the transaction itself stays valid
+	errAuthWrongChainID       = errors.New("authorization chain id mismatch")
+	errAuthNonceOverflow      = errors.New("authorization nonce too high")
+	errAuthDestinationHasCode = errors.New("authority has code that is not a delegation")
+	errAuthWrongNonce         = errors.New("authorization nonce does not match the authority")
+)
+
+// AddressToDelegation returns the designator delegating to addr
+func AddressToDelegation(addr Address) []byte {
+	return append(append([]byte(nil), DelegationPrefix...), addr[:]...)
+}
+
+// ParseDelegation returns the address code delegates to, if code is a designator
+func ParseDelegation(code []byte) (Address, bool) {
+	if len(code) != len(DelegationPrefix)+len(Address{}) || !bytes.HasPrefix(code, DelegationPrefix) {
+		return Address{}, false
+	}
+	return Address(code[len(DelegationPrefix):]), true
+}
+
+// SigHash is the hash the authority signs: keccak256(0x05 || rlp([chain_id, address, nonce]))
+func (auth *SetCodeAuthorization) SigHash() Word {
+	enc := mustEncode([]any{auth.ChainID, auth.Address, auth.Nonce})
+	return Word(crypto.Keccak256(append([]byte{setCodeAuthMagic}, enc...)))
+}
+
+// Authority recovers the account that signed the authorization
+func (auth *SetCodeAuthorization) Authority() (Address, error) {
+	if auth.R == nil || auth.S == nil || auth.R.BitLen() > 256 || auth.S.BitLen() > 256 {
+		return Address{}, ErrInvalidSig
+	}
+	return recoverSigner(auth.SigHash(), big.NewInt(int64(auth.V)), auth.R, auth.S)
+}
+
+// applyAuthorization checks one authorization against the state and, if it
+// is valid, writes the delegation and bumps the authority's nonce
+func (st *stateTransition) applyAuthorization(auth *SetCodeAuthorization) error {
+	if auth.ChainID != nil && auth.ChainID.Sign() != 0 && auth.ChainID.Cmp(st.chainID()) != 0 {
+		return errAuthWrongChainID
+	}
+	if auth.Nonce == ^uint64(0) {
+		return errAuthNonceOverflow
+	}
+	authority, err := auth.Authority()
+	if err != nil {
+		return err
+	}
+	// The authority is warm from here on, even if the checks below fail
+	st.state.AddAddressToAccessList(authority)
+	code := st.state.GetCode(authority)
+	if _, delegated := ParseDelegation(code); len(code) != 0 && !delegated {
+		return errAuthDestinationHasCode
+	}
+	if st.state.GetNonce(authority) != auth.Nonce {
+		return errAuthWrongNonce
+	}
+
+	// Existing accounts were overcharged by the intrinsic per-authorization cost
+	if st.state.Exist(authority) {
+		st.refund += GasTxAuthorization - GasTxAuthorizationBase
+	}
+	// Delegating to the zero address clears the delegation
+	if auth.Address == (Address{}) {
+		st.state.SetCode(authority, nil)
+	} else {
+		st.state.SetCode(authority, AddressToDelegation(auth.Address))
+	}
+	st.state.SetNonce(authority, auth.Nonce+1)
+	return nil
+}
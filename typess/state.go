@@ -0,0 +1,436 @@
+package types
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/morelucks/minievm/crypto"
+	"github.com/morelucks/minievm/trie"
+)
+
+// EmptyRootHash is the root of an empty trie, the storage root of accounts
+// without storage; EmptyCodeHash is the code hash of accounts without code
+var (
+	EmptyRootHash = Word(trie.EmptyRoot)
+	EmptyCodeHash = Word(crypto.Keccak256(nil))
+)
+
+// Account is the state of a single address (Yellow Paper section 4.1)
+type Account struct {
+	Nonce   uint64   // σ[a]_n - Number of transactions sent or contracts created
+	Balance *big.Int // σ[a]_b - Wei owned by the account
+	Code    []byte   // Code executed when the account is called
+	Storage *Storage // σ[a]_s - Contract storage
+}
+
+// StateDB is the world state σ, a mapping from addresses to accounts,
+// plus the substate the current transaction has built up
+type StateDB struct {
+	Accounts map[Address]*Account
+
+	touched       map[Address]bool          // Accounts changed since the last Finalise
+	originals     map[Address]map[Word]Word // Slot values before the transaction wrote them
+	accessedAddrs map[Address]bool          // A_a - Accessed addresses (EIP-2929)
+	accessedSlots map[Address]map[Word]bool // A_K - Accessed storage keys (EIP-2929)
+	transient     map[Address]map[Word]Word // Transient storage, dropped after the transaction (EIP-1153)
+	created       map[Address]bool          // Contracts created by the transaction
+	destructed    map[Address]bool          // A_s - Self-destructed accounts, deleted by Finalise
+
+	journal []func() // Undoes the changes of the current transaction, newest last
+}
+
+func NewStateDB() *StateDB {
+	s := &StateDB{Accounts: make(map[Address]*Account), touched: make(map[Address]bool)}
+	s.resetSubstate()
+	return s
+}
+
+func (s *StateDB) resetSubstate() {
+	s.journal = nil
+	s.originals = make(map[Address]map[Word]Word)
+	s.accessedAddrs = make(map[Address]bool)
+	s.accessedSlots = make(map[Address]map[Word]bool)
+	s.transient = make(map[Address]map[Word]Word)
+	s.created = make(map[Address]bool)
+	s.destructed = make(map[Address]bool)
+}
+
+func newAccount() *Account {
+	return &Account{Balance: new(big.Int), Storage: NewStorage()}
+}
+
+// Exist returns true if addr has an entry in the state
+func (s *StateDB) Exist(addr Address) bool {
+	_, ok := s.Accounts[addr]
+	return ok
+}
+
+// Empty returns true if addr is missing or has no code, nonce or balance (EIP-161)
+func (s *StateDB) Empty(addr Address) bool {
+	acc, ok := s.Accounts[addr]
+	return !ok || (acc.Nonce == 0 && acc.Balance.Sign() == 0 && len(acc.Code) == 0)
+}
+
+// hasContract reports whether addr already holds a contract, so that no
+// other can be created there (EIP-684, EIP-7610)
+func (s *StateDB) hasContract(addr Address) bool {
+	acc, ok := s.Accounts[addr]
+	return ok && (acc.Nonce != 0 || len(acc.Code) != 0 || len(acc.Storage.Data) != 0)
+}
+
+// GetOrNewAccount returns the account at addr, creating an empty one if
+// needed. The account counts as touched.
+func (s *StateDB) GetOrNewAccount(addr Address) *Account {
+	s.touch(addr)
+	acc, ok := s.Accounts[addr]
+	if !ok {
+		acc = newAccount()
+		s.Accounts[addr] = acc
+		s.record(func() { delete(s.Accounts, addr) })
+	}
+	return acc
+}
+
+// Balance, nonce and code accessors; missing accounts read as empty
+func (s *StateDB) GetBalance(addr Address) *big.Int {
+	if acc, ok := s.Accounts[addr]; ok {
+		return new(big.Int).Set(acc.Balance)
+	}
+	return new(big.Int)
+}
+
+func (s *StateDB) AddBalance(addr Address, amount *big.Int) {
+	s.setBalance(addr, new(big.Int).Add(s.GetBalance(addr), amount))
+}
+
+func (s *StateDB) SubBalance(addr Address, amount *big.Int) {
+	s.setBalance(addr, new(big.Int).Sub(s.GetBalance(addr), amount))
+}
+
+func (s *StateDB) setBalance(addr Address, balance *big.Int) {
+	acc := s.GetOrNewAccount(addr)
+	prev := acc.Balance
+	acc.Balance = balance
+	s.record(func() { acc.Balance = prev })
+}
+
+func (s *StateDB) GetNonce(addr Address) uint64 {
+	if acc, ok := s.Accounts[addr]; ok {
+		return acc.Nonce
+	}
+	return 0
+}
+
+func (s *StateDB) SetNonce(addr Address, nonce uint64) {
+	acc := s.GetOrNewAccount(addr)
+	prev := acc.Nonce
+	acc.Nonce = nonce
+	s.record(func() { acc.Nonce = prev })
+}
+
+func (s *StateDB) GetCode(addr Address) []byte {
+	if acc, ok := s.Accounts[addr]; ok {
+		return acc.Code
+	}
+	return nil
+}
+
+func (s *StateDB) SetCode(addr Address, code []byte) {
+	acc := s.GetOrNewAccount(addr)
+	prev := acc.Code
+	acc.Code = append([]byte(nil), code...)
+	s.record(func() { acc.Code = prev })
+}
+
+// GetState reads a storage slot of addr
+func (s *StateDB) GetState(addr Address, key Word) Word {
+	if acc, ok := s.Accounts[addr]; ok {
+		return acc.Storage.Load(key)
+	}
+	return Word{}
+}
+
+// SetState writes a storage slot of addr, remembering the value the
+// transaction started with
+func (s *StateDB) SetState(addr Address, key, value Word) {
+	slots, ok := s.originals[addr]
+	if !ok {
+		slots = make(map[Word]Word)
+		s.originals[addr] = slots
+	}
+	if _, seen := slots[key]; !seen {
+		slots[key] = s.GetState(addr, key)
+		s.record(func() { delete(slots, key) })
+	}
+	storage := s.GetOrNewAccount(addr).Storage
+	prev, existed := storage.Data[Byte32(key)]
+	storage.Store(key, value)
+	s.record(func() {
+		if existed {
+			storage.Data[Byte32(key)] = prev
+		} else {
+			delete(storage.Data, Byte32(key))
+		}
+	})
+}
+
+// GetCommittedState reads a storage slot as it was when the transaction began
+func (s *StateDB) GetCommittedState(addr Address, key Word) Word {
+	if value, ok := s.originals[addr][key]; ok {
+		return value
+	}
+	return s.GetState(addr, key)
+}
+
+// GetTransientState and SetTransientState access the transient storage of
+// addr, which lasts until the end of the transaction (EIP-1153)
+func (s *StateDB) GetTransientState(addr Address, key Word) Word {
+	return s.transient[addr][key]
+}
+
+func (s *StateDB) SetTransientState(addr Address, key, value Word) {
+	slots, ok := s.transient[addr]
+	if !ok {
+		slots = make(map[Word]Word)
+		s.transient[addr] = slots
+	}
+	prev, existed := slots[key]
+	slots[key] = value
+	s.record(func() {
+		if existed {
+			slots[key] = prev
+		} else {
+			delete(slots, key)
+		}
+	})
+}
+
+// markCreated records that the transaction created a contract at addr
+func (s *StateDB) markCreated(addr Address) {
+	if !s.created[addr] {
+		s.created[addr] = true
+		s.record(func() { delete(s.created, addr) })
+	}
+}
+
+// isNewContract reports whether the transaction created a contract at addr
+func (s *StateDB) isNewContract(addr Address) bool {
+	return s.created[addr]
+}
+
+// SelfDestruct empties the balance of addr and schedules the account for
+// deletion at the end of the transaction
+func (s *StateDB) SelfDestruct(addr Address) {
+	s.setBalance(addr, new(big.Int))
+	if !s.destructed[addr] {
+		s.destructed[addr] = true
+		s.record(func() { delete(s.destructed, addr) })
+	}
+}
+
+// HasSelfDestructed reports whether addr self-destructed in the transaction
+func (s *StateDB) HasSelfDestructed(addr Address) bool {
+	return s.destructed[addr]
+}
+
+// Touch marks an existing account as touched, as a zero-value transfer
+// would, so Finalise deletes it if it is empty
+func (s *StateDB) Touch(addr Address) {
+	if s.Exist(addr) {
+		s.touch(addr)
+	}
+}
+
+func (s *StateDB) touch(addr Address) {
+	if !s.touched[addr] {
+		s.touched[addr] = true
+		s.record(func() { delete(s.touched, addr) })
+	}
+}
+
+// Finalise ends a transaction by deleting the self-destructed accounts
+// and the touched accounts that are empty (EIP-161)
+func (s *StateDB) Finalise() {
+	for addr := range s.destructed {
+		delete(s.Accounts, addr)
+	}
+	s.destructed = make(map[Address]bool)
+	for addr := range s.touched {
+		if s.Exist(addr) && s.Empty(addr) {
+			delete(s.Accounts, addr)
+		}
+	}
+	s.touched = make(map[Address]bool)
+	s.journal = nil
+}
+
+// Snapshot returns a revision of the state that RevertToSnapshot can return
+// to. Only the changes made since are undone, so taking one is cheap.
+func (s *StateDB) Snapshot() int {
+	return len(s.journal)
+}
+
+// RevertToSnapshot undoes the changes made after the given revision,
+// newest first
+func (s *StateDB) RevertToSnapshot(revision int) {
+	for i := len(s.journal) - 1; i >= revision; i-- {
+		s.journal[i]()
+	}
+	s.journal = s.journal[:revision]
+}
+
+// record adds the undo action of a change to the journal
+func (s *StateDB) record(undo func()) {
+	s.journal = append(s.journal, undo)
+}
+
+// BeginTransaction clears the substate left by the previous transaction
+func (s *StateDB) BeginTransaction() {
+	s.resetSubstate()
+}
+
+// AddressInAccessList reports whether addr has been accessed (EIP-2929)
+func (s *StateDB) AddressInAccessList(addr Address) bool {
+	return s.accessedAddrs[addr]
+}
+
+// SlotInAccessList reports whether a storage slot has been accessed (EIP-2929)
+func (s *StateDB) SlotInAccessList(addr Address, key Word) bool {
+	return s.accessedSlots[addr][key]
+}
+
+// AddAddressToAccessList marks addr as accessed
+func (s *StateDB) AddAddressToAccessList(addr Address) {
+	if !s.accessedAddrs[addr] {
+		s.accessedAddrs[addr] = true
+		s.record(func() { delete(s.accessedAddrs, addr) })
+	}
+}
+
+// AddSlotToAccessList marks a storage slot, and its account, as accessed
+func (s *StateDB) AddSlotToAccessList(addr Address, key Word) {
+	s.AddAddressToAccessList(addr)
+	slots, ok := s.accessedSlots[addr]
+	if !ok {
+		slots = make(map[Word]bool)
+		s.accessedSlots[addr] = slots
+	}
+	if !slots[key] {
+		slots[key] = true
+		s.record(func() { delete(slots, key) })
+	}
+}
+
+// Copy returns a deep copy of the state with an empty journal
+func (s *StateDB) Copy() *StateDB {
+	cpy := NewStateDB()
+	for addr, acc := range s.Accounts {
+		storage := NewStorage()
+		for k, v := range acc.Storage.Data {
+			storage.Data[k] = v
+		}
+		cpy.Accounts[addr] = &Account{
+			Nonce:   acc.Nonce,
+			Balance: new(big.Int).Set(acc.Balance),
+			Code:    acc.Code,
+			Storage: storage,
+		}
+	}
+	for addr := range s.touched {
+		cpy.touched[addr] = true
+	}
+	for addr, slots := range s.originals {
+		cpy.originals[addr] = make(map[Word]Word, len(slots))
+		for k, v := range slots {
+			cpy.originals[addr][k] = v
+		}
+	}
+	for addr := range s.created {
+		cpy.created[addr] = true
+	}
+	for addr := range s.destructed {
+		cpy.destructed[addr] = true
+	}
+	for addr := range s.accessedAddrs {
+		cpy.accessedAddrs[addr] = true
+	}
+	for addr, slots := range s.accessedSlots {
+		cpy.accessedSlots[addr] = make(map[Word]bool, len(slots))
+		for k := range slots {
+			cpy.accessedSlots[addr][k] = true
+		}
+	}
+	return cpy
+}
+
+// StorageRoot returns σ[a]_s, the root of the trie mapping keccak256(key)
+// to rlp(value) for every non-zero slot of addr
+func (s *StateDB) StorageRoot(addr Address) Word {
+	return Word(s.storageTrie(addr).Hash())
+}
+
+// CodeHash returns σ[a]_c, the hash of the code of addr
+func (s *StateDB) CodeHash(addr Address) Word {
+	return Word(crypto.Keccak256(s.GetCode(addr)))
+}
+
+// Root returns the state root: the root of the trie mapping keccak256(address)
+// to rlp([nonce, balance, storageRoot, codeHash]) for every account
+func (s *StateDB) Root() Word {
+	return Word(s.stateTrie().Hash())
+}
+
+func (s *StateDB) storageTrie(addr Address) *trie.Trie {
+	t := trie.New()
+	acc, ok := s.Accounts[addr]
+	if !ok {
+		return t
+	}
+	for key, value := range acc.Storage.Data {
+		if value == (Byte32{}) {
+			continue
+		}
+		hashedKey := crypto.Keccak256(key[:])
+		t.Update(hashedKey[:], encodeSlot(Word(value)))
+	}
+	return t
+}
+
+func (s *StateDB) stateTrie() *trie.Trie {
+	t := trie.New()
+	for addr, acc := range s.Accounts {
+		hashedAddr := crypto.Keccak256(addr[:])
+		t.Update(hashedAddr[:], encodeAccount(acc.Nonce, acc.Balance, s.StorageRoot(addr), s.CodeHash(addr)))
+	}
+	return t
+}
+
+// encodeAccount and encodeSlot give the values stored in the state and
+// storage tries
+func encodeAccount(nonce uint64, balance *big.Int, storageRoot, codeHash Word) []byte {
+	return mustEncode([]any{nonce, balance, storageRoot, codeHash})
+}
+
+func encodeSlot(value Word) []byte {
+	return mustEncode(bytes.TrimLeft(value[:], "\x00"))
+}
+
+// CreateAddress derives the address of a contract created by sender:
+// keccak256(rlp([sender, nonce]))[12:]
+func CreateAddress(sender Address, nonce uint64) Address {
+	hash := crypto.Keccak256(mustEncode([]any{sender, nonce}))
+	var addr Address
+	copy(addr[:], hash[12:])
+	return addr
+}
+
+// Create2Address derives the address of a contract created by sender with
+// CREATE2: keccak256(0xff ++ sender ++ salt ++ keccak256(initCode))[12:]
+// (EIP-1014)
+func Create2Address(sender Address, salt Word, initCode []byte) Address {
+	codeHash := crypto.Keccak256(initCode)
+	hash := crypto.Keccak256([]byte{0xff}, sender[:], salt[:], codeHash[:])
+	var addr Address
+	copy(addr[:], hash[12:])
+	return addr
+}
@@ -0,0 +1,69 @@
+package types
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+func TestRevertToSnapshot(t *testing.T) {
+	state := NewStateDB()
+	addr, other := Address{19: 1}, Address{19: 2}
+	state.AddBalance(addr, big.NewInt(100))
+	state.SetState(addr, NewWord(1), NewWord(7))
+	root := state.Root()
+
+	snapshot := state.Snapshot()
+	state.SubBalance(addr, big.NewInt(40))
+	state.AddBalance(other, big.NewInt(40))
+	state.SetNonce(addr, 3)
+	state.SetCode(addr, []byte{0x00})
+	state.SetState(addr, NewWord(1), NewWord(8))
+	state.SetState(addr, NewWord(2), NewWord(9))
+	state.AddSlotToAccessList(other, NewWord(1))
+	state.SetTransientState(addr, NewWord(1), NewWord(5))
+	state.RevertToSnapshot(snapshot)
+
+	if got := state.Root(); got != root {
+		t.Errorf("root after revert %x, want %x", got, root)
+	}
+	if state.Exist(other) {
+		t.Error("account created after the snapshot still exists")
+	}
+	if state.AddressInAccessList(other) || state.SlotInAccessList(other, NewWord(1)) {
+		t.Error("access list additions after the snapshot were kept")
+	}
+	if got := state.GetTransientState(addr, NewWord(1)); got != (Word{}) {
+		t.Errorf("transient slot %x, want 0", got)
+	}
+	if got := state.GetState(addr, NewWord(1)); got != NewWord(7) {
+		t.Errorf("slot %x, want 7", got)
+	}
+}
+
+// EIP-1014 examples
+func TestCreate2Address(t *testing.T) {
+	tests := []struct {
+		sender, salt, initCode, want string
+	}{
+		{"0000000000000000000000000000000000000000", "0000000000000000000000000000000000000000000000000000000000000000", "00", "4d1a2e2bb4f88f0250f26ffff098b0b30b26bf38"},
+		{"deadbeef00000000000000000000000000000000", "000000000000000000000000feed000000000000000000000000000000000000", "00", "d04116cdd17bebe565eb2422f2497e06cc1c9833"},
+		{"00000000000000000000000000000000deadbeef", "00000000000000000000000000000000000000000000000000000000cafebabe", "deadbeef", "60f3f640a8508fc6a86d45df051962668e1e8ac7"},
+		{"0000000000000000000000000000000000000000", "0000000000000000000000000000000000000000000000000000000000000000", "", "e33c0c7f7df4809055c3eba6c09cfe4baf1bd9e0"},
+	}
+	decode := func(s string) []byte {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	for _, tt := range tests {
+		var sender Address
+		copy(sender[:], decode(tt.sender))
+		got := Create2Address(sender, NewWordFromBytes(decode(tt.salt)), decode(tt.initCode))
+		if hex.EncodeToString(got[:]) != tt.want {
+			t.Errorf("Create2Address(%s, %s, %s) = %x, want %s", tt.sender, tt.salt, tt.initCode, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// Tracer observes execution one instruction at a time
+type Tracer interface {
+	// CaptureState is called once per instruction, with the state before
+	// it and the gas it cost; step.Err is set if it halted the frame
+	CaptureState(step *Step)
+	// CaptureEnd is called when a message call or creation returns, at
+	// every depth, including calls that fail before running any code
+	CaptureEnd(output []byte, gasUsed uint64, err error)
+}
+
+// Step is the state of a frame before one of its instructions
+type Step struct {
+	PC      uint64
+	Op      byte
+	Gas     uint64 // Gas left before the instruction
+	GasCost uint64 // Gas charged, including gas passed on to a call
+	MemSize uint64 // Bytes
+	Stack   []Word // Bottom first
+	Depth   int    // 1 for the outermost frame
+	Refund  uint64 // Refund counter after the instruction is charged
+	Err     error
+}
+
+// JSONTracer writes traces in the EIP-3155 format: one JSON object per
+// line for every instruction and a summary of every frame as it returns
+type JSONTracer struct {
+	enc *json.Encoder
+}
+
+func NewJSONTracer(w io.Writer) *JSONTracer {
+	return &JSONTracer{enc: json.NewEncoder(w)}
+}
+
+type jsonStep struct {
+	PC      uint64    `json:"pc"`
+	Op      byte      `json:"op"`
+	Gas     HexUint64 `json:"gas"`
+	GasCost HexUint64 `json:"gasCost"`
+	MemSize uint64    `json:"memSize"`
+	Stack   []*HexBig `json:"stack"`
+	Depth   int       `json:"depth"`
+	Refund  uint64    `json:"refund"`
+	OpName  string    `json:"opName"`
+	Error   string    `json:"error,omitempty"`
+}
+
+type jsonSummary struct {
+	Output  string    `json:"output"`
+	GasUsed HexUint64 `json:"gasUsed"`
+	Error   string    `json:"error,omitempty"`
+}
+
+func (t *JSONTracer) CaptureState(step *Step) {
+	out := jsonStep{
+		PC:      step.PC,
+		Op:      step.Op,
+		Gas:     HexUint64(step.Gas),
+		GasCost: HexUint64(step.GasCost),
+		MemSize: step.MemSize,
+		Stack:   make([]*HexBig, len(step.Stack)),
+		Depth:   step.Depth,
+		Refund:  step.Refund,
+		OpName:  OpcodeName(step.Op),
+	}
+	for i, w := range step.Stack {
+		out.Stack[i] = (*HexBig)(new(big.Int).SetBytes(w[:]))
+	}
+	if step.Err != nil {
+		out.Error = step.Err.Error()
+	}
+	t.enc.Encode(out)
+}
+
+func (t *JSONTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	out := jsonSummary{Output: fmt.Sprintf("%x", output), GasUsed: HexUint64(gasUsed)}
+	if err != nil {
+		out.Error = err.Error()
+	}
+	t.enc.Encode(out)
+}
+
+// captureStep records the state before the instruction op at the PC. It
+// is traced once the gas it cost is known.
+func (vm *VM) captureStep(op byte) {
+	vm.step = &Step{
+		PC:      vm.PC,
+		Op:      op,
+		Gas:     vm.Gas,
+		MemSize: vm.Memory.Len(),
+		Stack:   append([]Word(nil), vm.Stack.Data...),
+		Depth:   vm.Depth + 1,
+		Refund:  vm.Refund,
+	}
+}
+
+// traceStep passes the captured step to the tracer, with the gas used
+// since it was captured and the refunds it added, as geth traces the
+// refund counter after charging an instruction. Like geth, a step that
+// failed its stack or gas checks is traced once with the error and the
+// gas it needed, while one that failed while running is traced as it
+// started and again with the error and the state it left.
+func (vm *VM) traceStep(err error) {
+	if vm.step == nil {
+		return
+	}
+	step := vm.step
+	vm.step = nil
+	step.GasCost = step.Gas - vm.Gas
+	step.Refund = vm.Refund
+	var oog *OutOfGasError
+	switch {
+	case err == nil:
+	case errors.As(err, &oog):
+		step.GasCost += oog.Required
+	case errors.Is(err, ErrStackUnderflow) || errors.Is(err, ErrStackOverflow):
+		step.GasCost = vm.constantGas(step.Op)
+	case errors.Is(err, ErrExecutionReverted) || errors.Is(err, ErrInvalidJump) ||
+		errors.Is(err, ErrReturnDataOutOfBounds) || errors.Is(err, ErrInvalidOpcode):
+		if errors.Is(err, ErrInvalidOpcode) {
+			step.GasCost = 0 // Undefined opcodes cost nothing in geth's tables
+		}
+		vm.Tracer.CaptureState(step)
+		fault := *step
+		fault.MemSize = vm.Memory.Len()
+		fault.Stack = append([]Word(nil), vm.Stack.Data...)
+		step = &fault
+	}
+	step.Err = err
+	vm.Tracer.CaptureState(step)
+}
+
+// constantGas returns the constant part of the cost of op, which geth
+// reports for an instruction failing its stack check. For account and
+// storage accesses this VM charges it together with the rest.
+func (vm *VM) constantGas(op byte) uint64 {
+	switch op {
+	case CALL, CALLCODE, DELEGATECALL, STATICCALL, BALANCE, EXTCODESIZE, EXTCODECOPY, EXTCODEHASH:
+		if vm.Fork.IsActive(Berlin) {
+			return GasWarmStorageRead
+		}
+		return GasCall // Equal to GasBalance and GasExtCode before Berlin
+	case SLOAD:
+		if vm.Fork.IsActive(Berlin) {
+			return 0
+		}
+		return GasSLoad
+	}
+	return GetOpcodeGasCost(op)
+}
+
+// traceExit traces the summary of a frame that returned output after
+// using gasUsed
+func (vm *VM) traceExit(output []byte, gasUsed uint64, err error) {
+	if err != nil && !errors.Is(err, ErrExecutionReverted) {
+		output = nil
+	}
+	vm.Tracer.CaptureEnd(output, gasUsed, err)
+}
+
+var opcodeNames = map[byte]string{
+	STOP: "STOP", ADD: "ADD", MUL: "MUL", SUB: "SUB", DIV: "DIV", SDIV: "SDIV",
+	MOD: "MOD", SMOD: "SMOD", ADDMOD: "ADDMOD", MULMOD: "MULMOD", EXP: "EXP",
+	SIGNEXTEND: "SIGNEXTEND",
+
+	LT: "LT", GT: "GT", SLT: "SLT", SGT: "SGT", EQ: "EQ", ISZERO: "ISZERO",
+	AND: "AND", OR: "OR", XOR: "XOR", NOT: "NOT", BYTE: "BYTE",
+	SHL: "SHL", SHR: "SHR", SAR: "SAR", CLZ: "CLZ", KECCAK256: "KECCAK256",
+
+	ADDRESS: "ADDRESS", BALANCE: "BALANCE", ORIGIN: "ORIGIN", CALLER: "CALLER",
+	CALLVALUE: "CALLVALUE", CALLDATALOAD: "CALLDATALOAD", CALLDATASIZE: "CALLDATASIZE",
+	CALLDATACOPY: "CALLDATACOPY", CODESIZE: "CODESIZE", CODECOPY: "CODECOPY",
+	GASPRICE: "GASPRICE", EXTCODESIZE: "EXTCODESIZE", EXTCODECOPY: "EXTCODECOPY",
+	RETURNDATASIZE: "RETURNDATASIZE", RETURNDATACOPY: "RETURNDATACOPY",
+	EXTCODEHASH: "EXTCODEHASH",
+
+	BLOCKHASH: "BLOCKHASH", COINBASE: "COINBASE", TIMESTAMP: "TIMESTAMP",
+	NUMBER: "NUMBER", PREVRANDAO: "PREVRANDAO", GASLIMIT: "GASLIMIT",
+	CHAINID: "CHAINID", SELFBALANCE: "SELFBALANCE", BASEFEE: "BASEFEE",
+	BLOBHASH: "BLOBHASH", BLOBBASEFEE: "BLOBBASEFEE",
+
+	POP: "POP", MLOAD: "MLOAD", MSTORE: "MSTORE", MSTORE8: "MSTORE8",
+	SLOAD: "SLOAD", SSTORE: "SSTORE", JUMP: "JUMP", JUMPI: "JUMPI",
+	PC: "PC", MSIZE: "MSIZE", GAS: "GAS", JUMPDEST: "JUMPDEST", TLOAD: "TLOAD",
+	TSTORE: "TSTORE", MCOPY: "MCOPY", PUSH0: "PUSH0",
+
+	DUPN: "DUPN", SWAPN: "SWAPN", EXCHANGE: "EXCHANGE",
+
+	CREATE: "CREATE", CALL: "CALL", CALLCODE: "CALLCODE", RETURN: "RETURN",
+	DELEGATECALL: "DELEGATECALL", CREATE2: "CREATE2", STATICCALL: "STATICCALL",
+	REVERT: "REVERT", INVALID: "INVALID", SELFDESTRUCT: "SELFDESTRUCT",
+}
+
+func init() {
+	for i := 0; i < 32; i++ {
+		opcodeNames[byte(PUSH1+i)] = fmt.Sprintf("PUSH%d", i+1)
+	}
+	for i := 0; i < 16; i++ {
+		opcodeNames[byte(DUP1+i)] = fmt.Sprintf("DUP%d", i+1)
+		opcodeNames[byte(SWAP1+i)] = fmt.Sprintf("SWAP%d", i+1)
+	}
+	for i := 0; i <= 4; i++ {
+		opcodeNames[byte(LOG0+i)] = fmt.Sprintf("LOG%d", i)
+	}
+}
+
+// OpcodeName returns the mnemonic of op, in the form geth names opcodes
+// it does not know
+func OpcodeName(op byte) string {
+	if name, ok := opcodeNames[op]; ok {
+		return name
+	}
+	return fmt.Sprintf("opcode %#x not defined", op)
+}
@@ -0,0 +1,296 @@
+package types
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/morelucks/minievm/crypto"
+	"github.com/morelucks/minievm/rlp"
+)
+
+// Transaction types (EIP-2718). Typed transactions are encoded as
+// type || rlp(payload); legacy transactions are a bare RLP list.
+const (
+	LegacyTxType     byte = 0x00
+	AccessListTxType byte = 0x01 // EIP-2930
+	DynamicFeeTxType byte = 0x02 // EIP-1559
+	BlobTxType       byte = 0x03 // EIP-4844
+	SetCodeTxType    byte = 0x04 // EIP-7702
+)
+
+var (
+	ErrTxTypeNotSupported = errors.New("transaction type not supported")
+	ErrTxTypedEmpty       = errors.New("typed transaction too short")
+	ErrTxMissingTo        = errors.New("transaction type requires a recipient")
+	ErrInvalidSig         = errors.New("invalid transaction v, r, s values")
+)
+
+// SetCodeAuthorization is an EIP-7702 authorization tuple, signed by the
+// account that delegates its code to Address
+type SetCodeAuthorization struct {
+	ChainID *big.Int
+	Address Address
+	Nonce   uint64
+	V       uint8 // y parity of the signature
+	R, S    *big.Int
+}
+
+// Transaction is a signed transaction of any type (Yellow Paper section 4.3).
+// Fields not used by a type are left nil or empty.
+type Transaction struct {
+	Type       byte
+	ChainID    *big.Int // Derived from V for legacy transactions; nil if not replay protected
+	Nonce      uint64   // T_n
+	GasPrice   *big.Int // T_p - Legacy and access list transactions
+	GasTipCap  *big.Int // Max priority fee per gas, from EIP-1559
+	GasFeeCap  *big.Int // Max fee per gas, from EIP-1559
+	Gas        uint64   // T_g - Gas limit
+	To         *Address // T_t - nil creates a contract
+	Value      *big.Int // T_v
+	Data       []byte   // T_d or T_i - Call data or init code
+	AccessList AccessList
+	BlobFeeCap *big.Int               // Max fee per blob gas (EIP-4844)
+	BlobHashes []Word                 // Versioned hashes of the blobs (EIP-4844)
+	AuthList   []SetCodeAuthorization // Delegations to apply (EIP-7702)
+	V, R, S    *big.Int               // Signature; V is the y parity for typed transactions
+}
+
+// Protected reports whether the signature commits to a chain id (EIP-155).
+// Typed transactions always do; legacy ones do when ChainID is set.
+func (tx *Transaction) Protected() bool {
+	return tx.Type != LegacyTxType || tx.ChainID != nil
+}
+
+// Encode returns the canonical encoding used for hashing and in blocks
+func (tx *Transaction) Encode() []byte {
+	payload := mustEncode(tx.fields(tx.recipient()))
+	if tx.Type == LegacyTxType {
+		return payload
+	}
+	return append([]byte{tx.Type}, payload...)
+}
+
+// Hash is the transaction hash, keccak256 of the encoding
+func (tx *Transaction) Hash() Word {
+	return Word(crypto.Keccak256(tx.Encode()))
+}
+
+// SigHash is the hash signed by the sender: the payload without the
+// signature, with the chain id appended for EIP-155 legacy transactions
+func (tx *Transaction) SigHash() Word {
+	fields := tx.fields(tx.recipient())
+	fields = fields[:len(fields)-3]
+	var enc []byte
+	switch {
+	case tx.Type != LegacyTxType:
+		enc = append([]byte{tx.Type}, mustEncode(fields)...)
+	case tx.Protected():
+		enc = mustEncode(append(fields, tx.ChainID, uint(0), uint(0)))
+	default:
+		enc = mustEncode(fields)
+	}
+	return Word(crypto.Keccak256(enc))
+}
+
+// Sender recovers the address that signed the transaction
+func (tx *Transaction) Sender() (Address, error) {
+	if tx.V == nil || tx.R == nil || tx.S == nil {
+		return Address{}, ErrInvalidSig
+	}
+	var recoveryID *big.Int
+	switch {
+	case tx.Type != LegacyTxType:
+		recoveryID = tx.V
+	case tx.Protected():
+		// v = chainId * 2 + 35 + recovery id
+		recoveryID = new(big.Int).Sub(tx.V, new(big.Int).Lsh(tx.ChainID, 1))
+		recoveryID.Sub(recoveryID, big.NewInt(35))
+	default:
+		recoveryID = new(big.Int).Sub(tx.V, big.NewInt(27))
+	}
+	return recoverSigner(tx.SigHash(), recoveryID, tx.R, tx.S)
+}
+
+// recoverSigner returns the address behind a signature over hash. Only
+// low-s signatures are valid (EIP-2).
+func recoverSigner(hash Word, recoveryID, r, s *big.Int) (Address, error) {
+	if !recoveryID.IsUint64() || recoveryID.Uint64() > 1 || !crypto.ValidateSignatureValues(r, s, true) {
+		return Address{}, ErrInvalidSig
+	}
+	pub, err := crypto.RecoverPubkey(hash[:], r, s, byte(recoveryID.Uint64()))
+	if err != nil {
+		return Address{}, fmt.Errorf("%w: %w", ErrInvalidSig, err)
+	}
+	return crypto.PubkeyToAddress(pub), nil
+}
+
+// AsMessage converts a signed transaction into the message ApplyMessage runs
+func (tx *Transaction) AsMessage() (*Message, error) {
+	from, err := tx.Sender()
+	if err != nil {
+		return nil, err
+	}
+	msg := &Message{
+		From:       from,
+		To:         tx.To,
+		Nonce:      tx.Nonce,
+		Value:      tx.Value,
+		GasLimit:   tx.Gas,
+		GasPrice:   tx.GasPrice,
+		Data:       tx.Data,
+		AccessList: tx.AccessList,
+	}
+	if tx.Type != LegacyTxType && msg.AccessList == nil {
+		// A non-nil list marks the message as typed, so preCheck can reject
+		// it before Berlin
+		msg.AccessList = AccessList{}
+	}
+	if tx.GasFeeCap != nil {
+		msg.GasPrice = tx.GasFeeCap
+		msg.GasFeeCap = tx.GasFeeCap
+		msg.GasTipCap = tx.GasTipCap
+	}
+	if tx.Type == BlobTxType {
+		msg.BlobHashes = tx.BlobHashes
+		if msg.BlobHashes == nil {
+			msg.BlobHashes = []Word{}
+		}
+		msg.BlobFeeCap = tx.BlobFeeCap
+	}
+	if tx.Type == SetCodeTxType {
+		// A non-nil list marks the message as a set-code transaction, so an
+		// empty one is rejected rather than treated as a plain call
+		msg.AuthList = tx.AuthList
+		if msg.AuthList == nil {
+			msg.AuthList = []SetCodeAuthorization{}
+		}
+	}
+	return msg, nil
+}
+
+// fields lists pointers to the encoded fields of the transaction's type in
+// order, ending with the signature. The recipient is passed as a byte slice
+// since contract creations encode it as the empty string.
+func (tx *Transaction) fields(to *[]byte) []any {
+	var f []any
+	if tx.Type != LegacyTxType {
+		f = append(f, &tx.ChainID)
+	}
+	f = append(f, &tx.Nonce)
+	switch tx.Type {
+	case LegacyTxType, AccessListTxType:
+		f = append(f, &tx.GasPrice)
+	default:
+		f = append(f, &tx.GasTipCap, &tx.GasFeeCap)
+	}
+	f = append(f, &tx.Gas, to, &tx.Value, &tx.Data)
+	if tx.Type != LegacyTxType {
+		f = append(f, &tx.AccessList)
+	}
+	switch tx.Type {
+	case BlobTxType:
+		f = append(f, &tx.BlobFeeCap, &tx.BlobHashes)
+	case SetCodeTxType:
+		f = append(f, &tx.AuthList)
+	}
+	return append(f, &tx.V, &tx.R, &tx.S)
+}
+
+func (tx *Transaction) recipient() *[]byte {
+	var to []byte
+	if tx.To != nil {
+		to = tx.To[:]
+	}
+	return &to
+}
+
+// mustEncode encodes values whose types are always serializable
+func mustEncode(val any) []byte {
+	enc, err := rlp.EncodeToBytes(val)
+	if err != nil {
+		panic(err)
+	}
+	return enc
+}
+
+// DecodeTransaction decodes a raw signed transaction as found in blocks and
+// returned by eth_getRawTransactionByHash. Blob transactions are also
+// accepted in their network form, with the sidecar dropped.
+func DecodeTransaction(raw []byte) (*Transaction, error) {
+	if len(raw) == 0 {
+		return nil, ErrTxTypedEmpty
+	}
+	tx := new(Transaction)
+	body := raw
+	if raw[0] < 0xc0 {
+		// Typed envelope; 0x80-0xbf would be an RLP string, not a type
+		if raw[0] > 0x7f {
+			return nil, ErrTxTypeNotSupported
+		}
+		tx.Type, body = raw[0], raw[1:]
+		if tx.Type > SetCodeTxType || tx.Type == LegacyTxType {
+			return nil, fmt.Errorf("%w: %d", ErrTxTypeNotSupported, tx.Type)
+		}
+	}
+	if tx.Type == BlobTxType {
+		// Network form: [tx_payload_body, blobs, commitments, proofs]
+		if content, _, err := rlp.SplitList(body); err == nil {
+			if k, _, rest, err := rlp.Split(content); err == nil && k == rlp.List {
+				body = content[:len(content)-len(rest)]
+			}
+		}
+	}
+	if err := tx.decodeFields(body); err != nil {
+		return nil, fmt.Errorf("decode transaction type %d: %w", tx.Type, err)
+	}
+	// v is 27 or 28 before EIP-155, then chainId * 2 + 35 + recovery id.
+	// Other values are caught when recovering the sender.
+	if tx.Type == LegacyTxType && tx.V.Cmp(big.NewInt(35)) >= 0 {
+		tx.ChainID = new(big.Int).Sub(tx.V, big.NewInt(35))
+		tx.ChainID.Rsh(tx.ChainID, 1)
+	}
+	return tx, nil
+}
+
+// decodeFields reads the RLP list in body into the fields of tx.Type
+func (tx *Transaction) decodeFields(body []byte) error {
+	var to []byte
+	r := bytes.NewReader(body)
+	s := rlp.NewStream(r, 0)
+	if _, err := s.List(); err != nil {
+		return err
+	}
+	for _, field := range tx.fields(&to) {
+		if err := s.Decode(field); err != nil {
+			return err
+		}
+	}
+	if err := s.ListEnd(); err != nil {
+		return fmt.Errorf("too many fields: %w", err)
+	}
+	if r.Len() > 0 {
+		return rlp.ErrMoreThanOneValue
+	}
+
+	switch len(to) {
+	case 0:
+		if tx.Type == BlobTxType || tx.Type == SetCodeTxType {
+			return ErrTxMissingTo
+		}
+	case len(Address{}):
+		tx.To = (*Address)(to)
+	default:
+		return fmt.Errorf("invalid recipient length %d", len(to))
+	}
+	// Typed transactions carry 256-bit integers
+	if tx.Type != LegacyTxType {
+		for _, x := range []*big.Int{tx.ChainID, tx.GasPrice, tx.GasTipCap, tx.GasFeeCap, tx.Value, tx.BlobFeeCap, tx.V, tx.R, tx.S} {
+			if x != nil && x.BitLen() > 256 {
+				return rlp.ErrValueTooLarge
+			}
+		}
+	}
+	return nil
+}
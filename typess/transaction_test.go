@@ -0,0 +1,146 @@
+package types
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/morelucks/minievm/rlp"
+)
+
+// Transactions signed by geth with the key 0x4646...46, whose address is
+// 0x9d8a62f656a8d1615c1294fd71e9cfb3e4855a4f. The EIP-155 one is the
+// example from the EIP.
+var txVectors = []struct {
+	name    string
+	raw     string
+	txType  byte
+	hash    string
+	chainID int64 // -1 for unprotected transactions
+}{
+	{"legacy", "f86c098504a817c800825208943535353535353535353535353535353535353535880de0b6b3a76400008025a028ef61340bd939bc2195fe537567866003e1a15d3c71ff63e1590620aa636276a067cbe9d8997f761aecb703304b3800ccf555c9f3dc64214b297fb1966a3b6d83", LegacyTxType, "33469b22e9f636356c4160a87eb19df52b7412e8eac32a4a55ffe88ea8350788", 1},
+	{"legacyPre155", "f864068504a817c80082520894353535353535353535353535353535353535353501801ca0f15ed8727bc9e5c8fb42af9ec2e62cfcba7f54917c2f8d3e9518aa3736340feaa0579525c0795baf92626023abb28dec49b80d16f816023e148e7754a6e466177f", LegacyTxType, "8439a2e70382b9b5d7f94c2891bbb95ecce6a7d3d7aea1f41242ef7bb5dd817b", -1},
+	{"accessList", "01f8a101018504a817c80082c3509435353535353535353535353535353535353535350a820102f838f7943535353535353535353535353535353535353535e1a0000000000000000000000000000000000000000000000000000000000000000180a0776244e7a0cca0ba6a0827f13ead832c51a4ea78a53caaa9f02be6469221654da0346c4b00a4aa31a7510ce5836db0c0b4cb1925bddeb19719bf3b0b7f258f85f7", AccessListTxType, "ee5bca070b2c8e2cd339cd1ee98dc6a3b1401326779ad8f4f00c3c17ac9e33ce", 1},
+	{"dynamicFee", "02f8a40102843b9aca008506fc23ac0082c3509435353535353535353535353535353535353535350a80f838f7943535353535353535353535353535353535353535e1a0000000000000000000000000000000000000000000000000000000000000000180a04245e8b98b0d32a75a4f15e2edf200b20813bc8ae77f6a97d2c9dd312797d505a03840551c014a953091dffa0ffcc5e494cc2dc24878d5ce2b207c40d8df4c6d73", DynamicFeeTxType, "c55a18890850d5542003db52f1289824747201d715a2086c31115e75d6b5cec6", 1},
+	{"create", "02f85a0103843b9aca008506fc23ac00830186a08080826000c001a0f5684ab1a95bc2170fe7b8ebec5bf786a1670b0496770217706f44a2ab1dcafda066d80e06c5e6042f88a8db388d9a962ff4167e7394e5ceaff51e43afcf838cb7", DynamicFeeTxType, "276c31967ba9a6b1e4b0ae804c89420068d8e8e5408cc64acc12d95ac5bdab1c", 1},
+	{"blob", "03f8920104843b9aca008506fc23ac0082c3509435353535353535353535353535353535353535358080c0843b9aca00e1a0010000000000000000000000000000000000000000000000000000000000000201a04473ef5fee01ecdc50fe3ce783d105d19bc8d4e7656f70b51a55e26340622d2ba0631fd9c19f3014bba5c91a2bfe5ccf2b21b3efcd5f376d37655c0e6defad9ef3", BlobTxType, "cf7fdba8340281edc7762ef5865cb5e13e017d55f72e37c7c5bd5b75c87b35ce", 1},
+	{"setCode", "04f8c90105843b9aca008506fc23ac00830138809435353535353535353535353535353535353535358080c0f85cf85a019435353535353535353535353535353535353535350780a0ada169c25b37d5ec7657b637677f2cc29cba28c40bfd0ac50bececf91263edd5a056da4e2c712f3431bcb8728e66bb387b0e177ea92484d366c055c7ec6b56969a01a07274e5d1247ef7e01f79d6a8a5e925481a0285b60808b69c9b8d516401818d879f849589bebf4075b70c0cd1dedeb546d49d11a7ccd92a2158035beea234d790", SetCodeTxType, "3ce002612bada15d27567a1031adbc10a8ebfd5e3af4d5b16dd11434d620bb28", 1},
+}
+
+var txVectorSender = "9d8a62f656a8d1615c1294fd71e9cfb3e4855a4f"
+
+func mustDecodeTx(t *testing.T, raw string) *Transaction {
+	t.Helper()
+	data, err := hex.DecodeString(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := DecodeTransaction(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func TestDecodeTransaction(t *testing.T) {
+	for _, tt := range txVectors {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := mustDecodeTx(t, tt.raw)
+			if tx.Type != tt.txType {
+				t.Errorf("type %d, want %d", tx.Type, tt.txType)
+			}
+			if got := hex.EncodeToString(tx.Encode()); got != tt.raw {
+				t.Errorf("encoding %s, want %s", got, tt.raw)
+			}
+			if got := tx.Hash(); hex.EncodeToString(got[:]) != tt.hash {
+				t.Errorf("hash %x, want %s", got, tt.hash)
+			}
+			switch {
+			case tt.chainID < 0 && tx.Protected():
+				t.Error("unprotected transaction reported protected")
+			case tt.chainID >= 0 && (tx.ChainID == nil || tx.ChainID.Int64() != tt.chainID):
+				t.Errorf("chain id %v, want %d", tx.ChainID, tt.chainID)
+			}
+			from, err := tx.Sender()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hex.EncodeToString(from[:]); got != txVectorSender {
+				t.Errorf("sender %s, want %s", got, txVectorSender)
+			}
+			msg, err := tx.AsMessage()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if typed := msg.AccessList != nil; typed != (tt.txType != LegacyTxType) {
+				t.Errorf("message marked typed: %v", typed)
+			}
+			for i, auth := range tx.AuthList {
+				authority, err := auth.Authority()
+				if err != nil {
+					t.Fatalf("authorization %d: %v", i, err)
+				}
+				if got := hex.EncodeToString(authority[:]); got != txVectorSender {
+					t.Errorf("authority %s, want %s", got, txVectorSender)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeTransactionErrors(t *testing.T) {
+	to := Address{19: 1}
+	blobCreate := (&Transaction{Type: BlobTxType, ChainID: big.NewInt(1), GasTipCap: new(big.Int), GasFeeCap: new(big.Int), Value: new(big.Int), BlobFeeCap: new(big.Int), V: new(big.Int), R: new(big.Int), S: new(big.Int)}).Encode()
+	setCodeCreate := (&Transaction{Type: SetCodeTxType, ChainID: big.NewInt(1), GasTipCap: new(big.Int), GasFeeCap: new(big.Int), Value: new(big.Int), V: new(big.Int), R: new(big.Int), S: new(big.Int)}).Encode()
+	legacy := (&Transaction{GasPrice: new(big.Int), To: &to, Value: new(big.Int), V: big.NewInt(27), R: big.NewInt(1), S: big.NewInt(1)}).Encode()
+	tests := []struct {
+		name string
+		raw  []byte
+		err  error
+	}{
+		{"empty", nil, ErrTxTypedEmpty},
+		{"unknownType", []byte{0x05, 0xc0}, ErrTxTypeNotSupported},
+		{"legacyTypeByte", []byte{0x00, 0xc0}, ErrTxTypeNotSupported},
+		{"rlpString", []byte{0x80}, ErrTxTypeNotSupported},
+		{"blobCreate", blobCreate, ErrTxMissingTo},
+		{"setCodeCreate", setCodeCreate, ErrTxMissingTo},
+		{"trailingBytes", append(legacy, 0x00), rlp.ErrMoreThanOneValue},
+		{"truncated", legacy[:len(legacy)-1], rlp.ErrValueTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeTransaction(tt.raw); !errors.Is(err, tt.err) {
+				t.Errorf("err %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestSenderErrors(t *testing.T) {
+	// secp256k1 group order
+	n, _ := new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+	tests := []struct {
+		name   string
+		raw    string
+		modify func(tx *Transaction)
+	}{
+		{"highS", txVectors[3].raw, func(tx *Transaction) {
+			tx.S.Sub(n, tx.S)
+			tx.V.Xor(tx.V, big.NewInt(1))
+		}},
+		{"typedParity", txVectors[3].raw, func(tx *Transaction) { tx.V.SetInt64(2) }},
+		{"legacyV", txVectors[1].raw, func(tx *Transaction) { tx.V.SetInt64(29) }},
+		{"rZero", txVectors[0].raw, func(tx *Transaction) { tx.R.SetInt64(0) }},
+		{"missingSignature", txVectors[2].raw, func(tx *Transaction) { tx.S = nil }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := mustDecodeTx(t, tt.raw)
+			tt.modify(tx)
+			if _, err := tx.Sender(); !errors.Is(err, ErrInvalidSig) {
+				t.Errorf("err %v, want %v", err, ErrInvalidSig)
+			}
+		})
+	}
+}
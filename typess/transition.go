@@ -0,0 +1,488 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/morelucks/minievm/crypto/kzg"
+)
+
+// Transaction-level constants
+const (
+	GasCodeDeposit        uint64 = 200     // G_codedeposit - Per byte of deployed code
+	MaxCodeSize                  = 24576   // EIP-170
+	MaxInitCodeSize              = 49152   // EIP-3860, twice MaxCodeSize
+	MaxBlobsPerTx                = 6       // EIP-7594 (Osaka)
+	MaxTxGas              uint64 = 1 << 24 // EIP-7825 (Osaka)
+	RefundQuotient        uint64 = 2       // Refund cap divisor before London
+	RefundQuotientEIP3529 uint64 = 5       // Refund cap divisor from London
+)
+
+// Errors that make a transaction invalid; the state is left untouched
+var (
+	ErrNonceTooLow       = errors.New("nonce too low")
+	ErrNonceTooHigh      = errors.New("nonce too high")
+	ErrNonceMax          = errors.New("nonce has max value")
+	ErrSenderNoEOA       = errors.New("sender not an eoa")
+	ErrInsufficientFunds = errors.New("insufficient funds for gas * price + value")
+	ErrIntrinsicGas      = errors.New("intrinsic gas too low")
+	ErrFloorDataGas      = errors.New("insufficient gas for floor data gas cost")
+	ErrMaxInitCodeSize   = errors.New("max initcode size exceeded")
+	ErrFeeCapTooLow      = errors.New("max fee per gas less than block base fee")
+	ErrTipAboveFeeCap    = errors.New("max priority fee per gas higher than max fee per gas")
+	ErrGasLimitReached   = errors.New("gas limit reached")
+	ErrGasLimitTooHigh   = errors.New("transaction gas limit too high")
+	ErrEmptyAuthList     = errors.New("set code transaction with empty authorization list")
+	ErrSetCodeTxCreate   = errors.New("set code transaction cannot create a contract")
+	ErrBlobFeeCapTooLow  = errors.New("max fee per blob gas less than block blob gas fee")
+	ErrMissingBlobHashes = errors.New("blob transaction missing blob hashes")
+	ErrTooManyBlobs      = errors.New("blob transaction has too many blobs")
+	ErrBlobTxCreate      = errors.New("blob transaction of type create")
+	ErrInvalidBlobHash   = errors.New("invalid blob hash version")
+)
+
+// Errors that fail execution; the transaction is still included
+var (
+	ErrContractAddressCollision = errors.New("contract address collision")
+	ErrMaxCodeSizeExceeded      = errors.New("max code size exceeded")
+	ErrInvalidCode              = errors.New("invalid code: must not begin with 0xef")
+	ErrCodeStoreOutOfGas        = errors.New("contract creation code storage out of gas")
+)
+
+// BlockContext holds the block-level environment of a transaction
+type BlockContext struct {
+	Coinbase Address  // H_c - Beneficiary of the fees
+	Number   uint64   // H_i
+	Time     uint64   // H_s
+	GasLimit uint64   // H_l
+	BaseFee  *big.Int // H_f - nil before London
+	ChainID  *big.Int // Chain the block belongs to; nil means 0
+
+	BlobBaseFee *big.Int // Price of blob gas (EIP-4844); nil before Cancun
+
+	Difficulty *big.Int                 // H_d - Returned by PREVRANDAO before the merge
+	Random     *Word                    // H_a - PREVRANDAO from the merge (EIP-4399)
+	GetHash    func(number uint64) Word // Hash of an earlier block, for BLOCKHASH
+
+	Tracer Tracer // Traces the execution of each transaction; nil disables tracing
+}
+
+// Message is a transaction reduced to what execution needs
+type Message struct {
+	From       Address
+	To         *Address // nil creates a contract
+	Nonce      uint64
+	Value      *big.Int
+	GasLimit   uint64
+	GasPrice   *big.Int // Legacy gas price; also used as fee cap and tip if those are nil
+	GasFeeCap  *big.Int // EIP-1559 max fee per gas
+	GasTipCap  *big.Int // EIP-1559 max priority fee per gas
+	Data       []byte
+	AccessList AccessList             // EIP-2930 addresses and slots declared up front
+	AuthList   []SetCodeAuthorization // EIP-7702 delegations; non-nil only for set-code transactions
+	BlobHashes []Word                 // EIP-4844 versioned hashes; non-nil only for blob transactions
+	BlobFeeCap *big.Int               // EIP-4844 max fee per blob gas
+}
+
+// ExecutionResult is the receipt-like outcome of applying a message
+type ExecutionResult struct {
+	UsedGas           uint64   // Gas charged to the sender after refunds
+	RefundedGas       uint64   // Refund counter paid back, after the cap
+	ReturnData        []byte   // Output of the call, or the revert reason
+	Err               error    // Execution error; nil on success
+	ContractAddress   *Address // Address of the created contract, if any
+	EffectiveGasPrice *big.Int // Price per gas paid by the sender
+	Logs              []*Log   // Logs emitted; empty if execution failed
+}
+
+// Failed returns true if execution failed or reverted
+func (r *ExecutionResult) Failed() bool {
+	return r.Err != nil
+}
+
+// Revert returns the revert reason if execution was reverted
+func (r *ExecutionResult) Revert() []byte {
+	if errors.Is(r.Err, ErrExecutionReverted) {
+		return r.ReturnData
+	}
+	return nil
+}
+
+// stateTransition applies one message to the state (Yellow Paper section 6)
+type stateTransition struct {
+	state *StateDB
+	block *BlockContext
+	msg   *Message
+	fork  Fork
+
+	gasPrice *big.Int // Effective price per gas
+	gasLeft  uint64
+	refund   uint64 // Refunds earned before execution, by authorizations
+	logs     []*Log // Logs of the successful execution
+}
+
+// ApplyMessage executes msg against state in the given block and fork.
+// It checks the message, buys gas, runs the code, refunds unused gas and
+// pays the coinbase. A returned error means the message is invalid and the
+// state is unchanged; execution failures are reported in the result instead.
+func ApplyMessage(state *StateDB, block *BlockContext, msg *Message, fork Fork) (*ExecutionResult, error) {
+	st := &stateTransition{state: state, block: block, msg: msg, fork: fork}
+	return st.execute()
+}
+
+func (st *stateTransition) feeCap() *big.Int {
+	if st.msg.GasFeeCap != nil {
+		return st.msg.GasFeeCap
+	}
+	return st.msg.GasPrice
+}
+
+func (st *stateTransition) tipCap() *big.Int {
+	if st.msg.GasTipCap != nil {
+		return st.msg.GasTipCap
+	}
+	return st.msg.GasPrice
+}
+
+func (st *stateTransition) chainID() *big.Int {
+	return chainIDOf(st.block)
+}
+
+func (st *stateTransition) value() *big.Int {
+	if st.msg.Value == nil {
+		return new(big.Int)
+	}
+	return st.msg.Value
+}
+
+// preCheck validates the nonce, sender and fee fields
+func (st *stateTransition) preCheck() error {
+	msg := st.msg
+	nonce := st.state.GetNonce(msg.From)
+	switch {
+	case nonce < msg.Nonce:
+		return fmt.Errorf("%w: address 0x%x, tx: %d state: %d", ErrNonceTooHigh, msg.From, msg.Nonce, nonce)
+	case nonce > msg.Nonce:
+		return fmt.Errorf("%w: address 0x%x, tx: %d state: %d", ErrNonceTooLow, msg.From, msg.Nonce, nonce)
+	case nonce == ^uint64(0):
+		return fmt.Errorf("%w: address 0x%x", ErrNonceMax, msg.From)
+	}
+	// EIP-3607: only accounts without code can send transactions, though
+	// delegated EOAs keep sending (EIP-7702)
+	code := st.state.GetCode(msg.From)
+	if _, delegated := ParseDelegation(code); len(code) != 0 && !(delegated && st.fork.IsActive(Prague)) {
+		return fmt.Errorf("%w: address 0x%x", ErrSenderNoEOA, msg.From)
+	}
+	// Typed transactions are only valid from the fork that introduced them
+	switch {
+	case msg.AccessList != nil && !st.fork.IsActive(Berlin):
+		return fmt.Errorf("%w: access list transaction before Berlin", ErrTxTypeNotSupported)
+	case msg.GasFeeCap != nil && !st.fork.IsActive(London):
+		return fmt.Errorf("%w: dynamic fee transaction before London", ErrTxTypeNotSupported)
+	}
+	if msg.AuthList != nil {
+		switch {
+		case !st.fork.IsActive(Prague):
+			return fmt.Errorf("%w: set code transaction before Prague", ErrTxTypeNotSupported)
+		case msg.To == nil:
+			return fmt.Errorf("%w: sender 0x%x", ErrSetCodeTxCreate, msg.From)
+		case len(msg.AuthList) == 0:
+			return fmt.Errorf("%w: sender 0x%x", ErrEmptyAuthList, msg.From)
+		}
+	}
+	if msg.BlobHashes != nil {
+		if err := st.checkBlobs(); err != nil {
+			return err
+		}
+	}
+	if st.fork.IsActive(Osaka) && msg.GasLimit > MaxTxGas {
+		return fmt.Errorf("%w: %d, max %d", ErrGasLimitTooHigh, msg.GasLimit, MaxTxGas)
+	}
+	if st.block.GasLimit != 0 && msg.GasLimit > st.block.GasLimit {
+		return fmt.Errorf("%w: tx %d, block %d", ErrGasLimitReached, msg.GasLimit, st.block.GasLimit)
+	}
+
+	// EIP-1559: the sender pays the base fee plus at most the tip
+	feeCap, tipCap := st.feeCap(), st.tipCap()
+	st.gasPrice = new(big.Int).Set(feeCap)
+	if st.fork.IsActive(London) && st.block.BaseFee != nil {
+		if tipCap.Cmp(feeCap) > 0 {
+			return fmt.Errorf("%w: tip %v, fee cap %v", ErrTipAboveFeeCap, tipCap, feeCap)
+		}
+		if feeCap.Cmp(st.block.BaseFee) < 0 {
+			return fmt.Errorf("%w: fee cap %v, base fee %v", ErrFeeCapTooLow, feeCap, st.block.BaseFee)
+		}
+		st.gasPrice.Add(st.block.BaseFee, tipCap)
+		if st.gasPrice.Cmp(feeCap) > 0 {
+			st.gasPrice.Set(feeCap)
+		}
+	}
+	return nil
+}
+
+// checkBlobs validates the blobs of a blob transaction and its blob fee cap
+func (st *stateTransition) checkBlobs() error {
+	msg := st.msg
+	switch {
+	case !st.fork.IsActive(Cancun):
+		return fmt.Errorf("%w: blob transaction before Cancun", ErrTxTypeNotSupported)
+	case msg.To == nil:
+		return ErrBlobTxCreate
+	case len(msg.BlobHashes) == 0:
+		return ErrMissingBlobHashes
+	case st.fork.IsActive(Osaka) && len(msg.BlobHashes) > MaxBlobsPerTx: // EIP-7594
+		return fmt.Errorf("%w: %d, max %d", ErrTooManyBlobs, len(msg.BlobHashes), MaxBlobsPerTx)
+	}
+	for i, hash := range msg.BlobHashes {
+		if hash[0] != kzg.VersionedHashVersion {
+			return fmt.Errorf("%w: blob %d", ErrInvalidBlobHash, i)
+		}
+	}
+	if blobFee := st.blobBaseFee(); msg.BlobFeeCap == nil || msg.BlobFeeCap.Cmp(blobFee) < 0 {
+		return fmt.Errorf("%w: address 0x%x blobGasFeeCap: %v, blobBaseFee: %v", ErrBlobFeeCapTooLow, msg.From, msg.BlobFeeCap, blobFee)
+	}
+	return nil
+}
+
+// blobGasUsed returns the blob gas of the message, GasPerBlob per blob
+func (st *stateTransition) blobGasUsed() uint64 {
+	return uint64(len(st.msg.BlobHashes)) * GasPerBlob
+}
+
+func (st *stateTransition) blobBaseFee() *big.Int {
+	if st.block.BlobBaseFee == nil {
+		return new(big.Int)
+	}
+	return st.block.BlobBaseFee
+}
+
+// buyGas debits gasLimit * price, plus the blob fee, from the sender after
+// checking it can also afford the worst case of gasLimit * feeCap + value,
+// plus blobGas * blobFeeCap. The blob fee is burnt, not refunded.
+func (st *stateTransition) buyGas() error {
+	msg := st.msg
+	limit := new(big.Int).SetUint64(msg.GasLimit)
+	cost := new(big.Int).Mul(limit, st.gasPrice)
+	worstCase := new(big.Int).Mul(limit, st.feeCap())
+	worstCase.Add(worstCase, st.value())
+	if blobGas := new(big.Int).SetUint64(st.blobGasUsed()); blobGas.Sign() > 0 {
+		worstCase.Add(worstCase, new(big.Int).Mul(blobGas, msg.BlobFeeCap))
+		cost.Add(cost, blobGas.Mul(blobGas, st.blobBaseFee()))
+	}
+	if balance := st.state.GetBalance(msg.From); balance.Cmp(worstCase) < 0 {
+		return fmt.Errorf("%w: address 0x%x have %v want %v", ErrInsufficientFunds, msg.From, balance, worstCase)
+	}
+	st.state.SubBalance(msg.From, cost)
+	st.gasLeft = msg.GasLimit
+	return nil
+}
+
+func (st *stateTransition) execute() (*ExecutionResult, error) {
+	msg := st.msg
+	if err := st.preCheck(); err != nil {
+		return nil, err
+	}
+	isCreate := msg.To == nil
+	if isCreate && st.fork.IsActive(Shanghai) && len(msg.Data) > MaxInitCodeSize {
+		return nil, fmt.Errorf("%w: code size %d limit %d", ErrMaxInitCodeSize, len(msg.Data), MaxInitCodeSize)
+	}
+	intrinsic := IntrinsicGas(msg.Data, msg.AccessList, len(msg.AuthList), isCreate, st.fork)
+	if msg.GasLimit < intrinsic {
+		return nil, fmt.Errorf("%w: have %d, want %d", ErrIntrinsicGas, msg.GasLimit, intrinsic)
+	}
+	// EIP-7623: the gas limit must also cover the calldata floor price
+	var floor uint64
+	if st.fork.IsActive(Prague) {
+		floor = FloorDataGas(msg.Data)
+		if msg.GasLimit < floor {
+			return nil, fmt.Errorf("%w: have %d, want %d", ErrFloorDataGas, msg.GasLimit, floor)
+		}
+	}
+	if err := st.buyGas(); err != nil {
+		return nil, err
+	}
+	st.gasLeft -= intrinsic
+	st.state.BeginTransaction()
+	if st.fork.IsActive(Berlin) {
+		st.prepareAccessList()
+	}
+
+	result := &ExecutionResult{EffectiveGasPrice: st.gasPrice}
+	var refund uint64
+	execGas := st.gasLeft
+	if isCreate {
+		addr := CreateAddress(msg.From, msg.Nonce)
+		result.ContractAddress = &addr
+		st.state.SetNonce(msg.From, msg.Nonce+1)
+		result.ReturnData, refund, result.Err = st.create(addr)
+	} else {
+		st.state.SetNonce(msg.From, msg.Nonce+1)
+		// Invalid authorizations are skipped without failing the transaction
+		for i := range msg.AuthList {
+			st.applyAuthorization(&msg.AuthList[i])
+		}
+		// The delegation target is known only now, after the authorizations
+		if target, ok := ParseDelegation(st.state.GetCode(*msg.To)); ok && st.fork.IsActive(Prague) {
+			st.state.AddAddressToAccessList(target)
+		}
+		result.ReturnData, refund, result.Err = st.call(*msg.To)
+	}
+	if tracer := st.block.Tracer; tracer != nil {
+		// The outermost frame, which returns the deployed code of a creation
+		output := result.ReturnData
+		if isCreate && result.Err == nil {
+			output = st.state.GetCode(*result.ContractAddress)
+		}
+		tracer.CaptureEnd(output, execGas-st.gasLeft, result.Err)
+	}
+
+	// Refunds are capped at a fraction of the gas used (EIP-3529 from London)
+	refund = CappedRefund(refund+st.refund, msg.GasLimit-st.gasLeft, st.fork)
+	st.gasLeft += refund
+	result.RefundedGas = refund
+	if used := msg.GasLimit - st.gasLeft; used < floor {
+		st.gasLeft = msg.GasLimit - floor
+	}
+	result.UsedGas = msg.GasLimit - st.gasLeft
+	result.Logs = st.logs
+
+	// Return unused gas to the sender and pay the coinbase its tip
+	remaining := new(big.Int).Mul(new(big.Int).SetUint64(st.gasLeft), st.gasPrice)
+	st.state.AddBalance(msg.From, remaining)
+	tip := new(big.Int).Set(st.gasPrice)
+	if st.fork.IsActive(London) && st.block.BaseFee != nil {
+		tip.Sub(tip, st.block.BaseFee)
+	}
+	st.state.AddBalance(st.block.Coinbase, tip.Mul(tip, new(big.Int).SetUint64(result.UsedGas)))
+	st.state.Finalise()
+	return result, nil
+}
+
+// prepareAccessList warms the accounts and slots every transaction touches
+// up front: the sender, the recipient, the precompiles and the transaction's
+// access list (EIP-2929, EIP-2930), plus the coinbase from Shanghai (EIP-3651)
+func (st *stateTransition) prepareAccessList() {
+	msg := st.msg
+	st.state.AddAddressToAccessList(msg.From)
+	if msg.To != nil {
+		st.state.AddAddressToAccessList(*msg.To)
+	} else {
+		st.state.AddAddressToAccessList(CreateAddress(msg.From, msg.Nonce))
+	}
+	vm := NewVM(nil, 0)
+	vm.Fork = st.fork
+	for _, addr := range vm.ActivePrecompiles() {
+		st.state.AddAddressToAccessList(addr)
+	}
+	for _, tuple := range msg.AccessList {
+		st.state.AddAddressToAccessList(tuple.Address)
+		for _, key := range tuple.StorageKeys {
+			st.state.AddSlotToAccessList(tuple.Address, key)
+		}
+	}
+	if st.fork.IsActive(Shanghai) {
+		st.state.AddAddressToAccessList(st.block.Coinbase)
+	}
+}
+
+// newFrame builds a VM running code on behalf of the message
+func (st *stateTransition) newFrame(addr Address, code, input []byte) *VM {
+	vm := NewVM(code, st.gasLeft)
+	vm.Fork = st.fork
+	vm.State = st.state
+	vm.Block = st.block
+	vm.Address = addr
+	vm.Origin = st.msg.From
+	vm.Caller = st.msg.From
+	vm.Value = st.value()
+	vm.Input = input
+	vm.GasPrice = st.gasPrice
+	vm.BlobHashes = st.msg.BlobHashes
+	vm.Storage = st.state.GetOrNewAccount(addr).Storage
+	vm.Tracer = st.block.Tracer
+	return vm
+}
+
+// run executes a frame, reverting the state to snapshot if it fails.
+// A revert keeps the remaining gas; any other failure consumes it all.
+func (st *stateTransition) run(vm *VM, snapshot int) ([]byte, uint64, error) {
+	err := vm.Execute()
+	st.gasLeft = vm.Gas
+	if err != nil {
+		st.state.RevertToSnapshot(snapshot)
+		if errors.Is(err, ErrExecutionReverted) {
+			return vm.Output, 0, err
+		}
+		st.gasLeft = 0
+		return nil, 0, err
+	}
+	st.logs = vm.Logs
+	return vm.Output, vm.Refund, nil
+}
+
+// call transfers the value to addr and runs its code with the message data
+func (st *stateTransition) call(addr Address) ([]byte, uint64, error) {
+	snapshot := st.state.Snapshot()
+	st.state.SubBalance(st.msg.From, st.value())
+	st.state.AddBalance(addr, st.value())
+
+	vm := st.newFrame(addr, resolveCode(st.state, st.fork, addr), st.msg.Data)
+	if vm.IsPrecompile(addr) {
+		output, gasLeft, err := vm.RunPrecompile(addr, st.msg.Data, st.gasLeft)
+		st.gasLeft = gasLeft
+		if err != nil {
+			st.state.RevertToSnapshot(snapshot)
+			st.gasLeft = 0
+			return nil, 0, err
+		}
+		return output, 0, nil
+	}
+	return st.run(vm, snapshot)
+}
+
+// create deploys a contract at addr by running the message data as init code
+// and storing its output as the contract code
+func (st *stateTransition) create(addr Address) ([]byte, uint64, error) {
+	if st.state.hasContract(addr) {
+		st.gasLeft = 0
+		return nil, 0, ErrContractAddressCollision
+	}
+
+	snapshot := st.state.Snapshot()
+	st.state.SetNonce(addr, 1) // EIP-161
+	st.state.markCreated(addr)
+	st.state.SubBalance(st.msg.From, st.value())
+	st.state.AddBalance(addr, st.value())
+
+	vm := st.newFrame(addr, st.msg.Data, nil)
+	output, refund, err := st.run(vm, snapshot)
+	if err != nil {
+		return output, 0, err
+	}
+
+	// Validate and pay for the deployed code
+	if err := checkDeployedCode(st.fork, output, st.gasLeft); err != nil {
+		st.state.RevertToSnapshot(snapshot)
+		st.gasLeft = 0
+		st.logs = nil
+		return nil, 0, err
+	}
+	st.gasLeft -= uint64(len(output)) * GasCodeDeposit
+	st.state.SetCode(addr, output)
+	return nil, refund, nil
+}
+
+// checkDeployedCode validates the code returned by init code and checks
+// that gas covers storing it
+func checkDeployedCode(fork Fork, code []byte, gas uint64) error {
+	switch {
+	case len(code) > MaxCodeSize:
+		return ErrMaxCodeSizeExceeded
+	case len(code) > 0 && code[0] == 0xef && fork.IsActive(London):
+		return ErrInvalidCode // EIP-3541
+	case gas < uint64(len(code))*GasCodeDeposit:
+		return ErrCodeStoreOutOfGas
+	}
+	return nil
+}
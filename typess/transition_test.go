@@ -0,0 +1,40 @@
+package types
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestPreCheck(t *testing.T) {
+	from, to := Address{19: 1}, Address{19: 2}
+	tests := []struct {
+		name   string
+		fork   Fork
+		modify func(msg *Message)
+		err    error
+	}{
+		{"legacy", Istanbul, func(msg *Message) {}, nil},
+		{"gasAtCap", Osaka, func(msg *Message) { msg.GasLimit = MaxTxGas }, nil},
+		{"gasAboveCap", Osaka, func(msg *Message) { msg.GasLimit = MaxTxGas + 1 }, ErrGasLimitTooHigh},
+		{"gasAboveCapPrague", Prague, func(msg *Message) { msg.GasLimit = MaxTxGas + 1 }, nil},
+		{"accessListBerlin", Berlin, func(msg *Message) { msg.AccessList = AccessList{} }, nil},
+		{"accessListIstanbul", Istanbul, func(msg *Message) { msg.AccessList = AccessList{} }, ErrTxTypeNotSupported},
+		{"dynamicFeeLondon", London, func(msg *Message) { msg.GasFeeCap, msg.GasTipCap = big.NewInt(1), big.NewInt(1) }, nil},
+		{"dynamicFeeBerlin", Berlin, func(msg *Message) { msg.GasFeeCap, msg.GasTipCap = big.NewInt(1), big.NewInt(1) }, ErrTxTypeNotSupported},
+		{"blobShanghai", Shanghai, func(msg *Message) { msg.BlobHashes = []Word{{0: 1}} }, ErrTxTypeNotSupported},
+		{"setCodeCancun", Cancun, func(msg *Message) { msg.AuthList = []SetCodeAuthorization{{}} }, ErrTxTypeNotSupported},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := NewStateDB()
+			state.AddBalance(from, new(big.Int).Lsh(big.NewInt(1), 64))
+			msg := &Message{From: from, To: &to, GasLimit: 21000, GasPrice: big.NewInt(1)}
+			tt.modify(msg)
+			_, err := ApplyMessage(state, &BlockContext{}, msg, tt.fork)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("err %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
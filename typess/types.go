@@ -1,10 +1,15 @@
 package types
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
 
 const (
 	MaximumDepth uint = 1024
-	WordSize     int  = 32 // 256 bits = 32 bytes
+	CallDepth    int  = 1024 // Maximum nesting of message calls
+	WordSize     int  = 32   // 256 bits = 32 bytes
 )
 
 type Byte32 [32]byte
@@ -12,6 +17,9 @@ type Byte32 [32]byte
 // EVM uses 256-bit words (32 bytes)
 type Word [32]byte
 
+// Address is a 160-bit account address
+type Address [20]byte
+
 // Stack holds 256-bit values
 type Stack struct {
 	Data []Word
@@ -35,6 +43,34 @@ type VM struct {
 	Stack    *Stack   // μ_s - Stack contents
 	Memory   *Memory  // μ_m - Memory contents
 	Storage  *Storage // μ_s - Storage contents
+	Fork     Fork     // Protocol rules used for fork-gated opcodes
+
+	EnableEIP663 bool // Enables DUPN, SWAPN and EXCHANGE (EIP-663), which no fork includes
+
+	ReturnData []byte // Output of the most recent call
+	Output     []byte // H_RETURN - Data passed to RETURN or REVERT
+	Refund     uint64 // A_r - Refund counter, applied at the end of the transaction
+	Depth      int    // I_e - Depth of the current message call
+	ReadOnly   bool   // ¬I_w - Set inside STATICCALL, where state changes are forbidden
+	Logs       []*Log // A_l - Logs emitted by this frame and the calls it made
+
+	// Execution environment I (Yellow Paper section 9.3)
+	State      *StateDB      // World state; nil when running bare bytecode
+	Block      *BlockContext // I_H - Block the transaction is included in
+	Address    Address       // I_a - Account whose code is running
+	Origin     Address       // I_o - Sender of the transaction
+	Caller     Address       // I_s - Account that made this call
+	Value      *big.Int      // I_v - Wei sent with the call
+	Input      []byte        // I_d - Call data
+	GasPrice   *big.Int      // I_p - Effective gas price of the transaction
+	BlobHashes []Word        // Versioned hashes of the transaction's blobs (EIP-4844)
+
+	Tracer Tracer // Observes every instruction; nil disables tracing
+
+	precompiles map[Address]Precompile // Per-VM overrides of the fork's precompiles; nil removes one
+	jumpdests   []bool                 // Valid jump destinations in Code, computed on the first jump
+	transient   *Storage               // Transient storage of a VM without a world state
+	step        *Step                  // Instruction being executed, traced once its gas is known
 }
 
 // EVM Opcodes
@@ -70,9 +106,61 @@ const (
 	SHL  = 0x1b
 	SHR  = 0x1c
 	SAR  = 0x1d
+	CLZ  = 0x1e // EIP-7939 (Osaka)
+
+	// Hashing
+	KECCAK256 = 0x20
 
-	// Stack manipulation
-	POP    = 0x50
+	// Environmental information
+	ADDRESS        = 0x30
+	BALANCE        = 0x31
+	ORIGIN         = 0x32
+	CALLER         = 0x33
+	CALLVALUE      = 0x34
+	CALLDATALOAD   = 0x35
+	CALLDATASIZE   = 0x36
+	CALLDATACOPY   = 0x37
+	CODESIZE       = 0x38
+	CODECOPY       = 0x39
+	GASPRICE       = 0x3a
+	EXTCODESIZE    = 0x3b
+	EXTCODECOPY    = 0x3c
+	RETURNDATASIZE = 0x3d
+	RETURNDATACOPY = 0x3e
+	EXTCODEHASH    = 0x3f
+
+	// Block information
+	BLOCKHASH   = 0x40
+	COINBASE    = 0x41
+	TIMESTAMP   = 0x42
+	NUMBER      = 0x43
+	PREVRANDAO  = 0x44 // DIFFICULTY before the merge (EIP-4399)
+	GASLIMIT    = 0x45
+	CHAINID     = 0x46
+	SELFBALANCE = 0x47
+	BASEFEE     = 0x48 // EIP-3198 (London)
+	BLOBHASH    = 0x49 // EIP-4844 (Cancun)
+	BLOBBASEFEE = 0x4a // EIP-7516 (Cancun)
+
+	// Stack, memory and flow operations
+	POP      = 0x50
+	MLOAD    = 0x51
+	MSTORE   = 0x52
+	MSTORE8  = 0x53
+	SLOAD    = 0x54
+	SSTORE   = 0x55
+	JUMP     = 0x56
+	JUMPI    = 0x57
+	PC       = 0x58
+	MSIZE    = 0x59
+	GAS      = 0x5a
+	JUMPDEST = 0x5b
+	TLOAD    = 0x5c // EIP-1153 (Cancun)
+	TSTORE   = 0x5d // EIP-1153 (Cancun)
+	MCOPY    = 0x5e // EIP-5656 (Cancun)
+
+	// Push operations
+	PUSH0  = 0x5f // EIP-3855 (Shanghai)
 	PUSH1  = 0x60
 	PUSH2  = 0x61
 	PUSH3  = 0x62
@@ -141,6 +229,30 @@ const (
 	SWAP14 = 0x9d
 	SWAP15 = 0x9e
 	SWAP16 = 0x9f
+
+	// Logging operations
+	LOG0 = 0xa0
+	LOG1 = 0xa1
+	LOG2 = 0xa2
+	LOG3 = 0xa3
+	LOG4 = 0xa4
+
+	// Stack operations with immediate argument (EIP-663, behind VM.EnableEIP663)
+	DUPN     = 0xe6
+	SWAPN    = 0xe7
+	EXCHANGE = 0xe8
+
+	// System operations
+	CREATE       = 0xf0
+	CALL         = 0xf1
+	CALLCODE     = 0xf2
+	RETURN       = 0xf3
+	DELEGATECALL = 0xf4
+	CREATE2      = 0xf5 // EIP-1014
+	STATICCALL   = 0xfa
+	REVERT       = 0xfd
+	INVALID      = 0xfe // Designated invalid instruction (EIP-141)
+	SELFDESTRUCT = 0xff
 )
 
 // Gas cost constants (Istanbul fork - pre-Berlin)
@@ -153,11 +265,11 @@ const (
 	GasHigh         uint64 = 10
 	GasExtStep      uint64 = 20
 	GasExtCode      uint64 = 700
-	GasBalance      uint64 = 400
-	GasSLoad        uint64 = 200
+	GasBalance      uint64 = 700 // EIP-1884
+	GasSLoad        uint64 = 800 // EIP-1884
 	GasSStore       uint64 = 20000
 	GasSStoreReset  uint64 = 5000
-	GasSStoreNoop   uint64 = 200
+	GasSStoreNoop   uint64 = 800 // EIP-2200, same as SLOAD
 	GasCall         uint64 = 700
 	GasCreate       uint64 = 32000
 	GasMemory       uint64 = 3 // Per word (32 bytes)
@@ -172,6 +284,85 @@ const (
 	GasCopyWord     uint64 = 3
 	GasJumpDest     uint64 = 1
 	GasSelfDestruct uint64 = 5000
+	GasCallValue    uint64 = 9000
+	GasCallStipend  uint64 = 2300
+	GasNewAccount   uint64 = 25000 // CALL with value to an empty account
+
+	// Storage refunds and access costs
+	GasSStoreSentry             uint64 = 2300  // SSTORE fails with this much gas or less (EIP-2200)
+	GasSStoreClearRefund        uint64 = 15000 // Refund for clearing a slot (EIP-2200)
+	GasSStoreClearRefundEIP3529 uint64 = 4800  // Reduced clearing refund from London
+	GasSelfDestructRefund       uint64 = 24000 // Refund for SELFDESTRUCT, removed in London (EIP-3529)
+	GasColdSLoad                uint64 = 2100  // First access to a slot (EIP-2929)
+	GasColdAccountAccess        uint64 = 2600  // First access to an account (EIP-2929)
+	GasWarmStorageRead          uint64 = 100   // Later accesses (EIP-2929)
+
+	// Precompiled contracts
+	GasEcrecover     uint64 = 3000
+	GasSha256        uint64 = 60
+	GasSha256Word    uint64 = 12
+	GasRipemd160     uint64 = 600
+	GasRipemd160Word uint64 = 120
+	GasIdentity      uint64 = 15
+	GasIdentityWord  uint64 = 3
+	GasBlake2fRound  uint64 = 1
+
+	// BN254 curve operations (EIP-1108)
+	GasBn254Add          uint64 = 150
+	GasBn254Mul          uint64 = 6000
+	GasBn254PairingBase  uint64 = 45000
+	GasBn254PairingPoint uint64 = 34000
+
+	// KZG point evaluation (EIP-4844)
+	GasPointEvaluation uint64 = 50000
+
+	// BLS12-381 curve operations (EIP-2537)
+	GasBls12381G1Add          uint64 = 375
+	GasBls12381G1Mul          uint64 = 12000
+	GasBls12381G2Add          uint64 = 600
+	GasBls12381G2Mul          uint64 = 22500
+	GasBls12381PairingBase    uint64 = 37700
+	GasBls12381PairingPerPair uint64 = 32600
+	GasBls12381MapG1          uint64 = 5500
+	GasBls12381MapG2          uint64 = 23800
+
+	// secp256r1 signature verification: EIP-7951 from Osaka, RIP-7212 on
+	// chains that added it earlier
+	GasP256Verify        uint64 = 6900
+	GasP256VerifyRIP7212 uint64 = 3450
+)
+
+// Largest memory size in bytes; anything above it can never be paid for
+const MaxMemorySize uint64 = 0x1fffffffe0
+
+// ErrExecutionReverted is returned when code halts with REVERT; unlike other
+// errors it hands the remaining gas back to the caller
+var ErrExecutionReverted = errors.New("execution reverted")
+
+// Stack errors are exceptional halts, consuming all gas like running out of it
+var (
+	ErrStackUnderflow = errors.New("stack underflow")
+	ErrStackOverflow  = errors.New("stack overflow")
+)
+
+// ErrInvalidJump is returned for a jump to anything but a JUMPDEST opcode
+var ErrInvalidJump = errors.New("invalid jump destination")
+
+// ErrReturnDataOutOfBounds is returned by RETURNDATACOPY reading past the
+// end of the return data
+var ErrReturnDataOutOfBounds = errors.New("return data out of bounds")
+
+// ErrInvalidOpcode is returned for opcodes that are undefined in the fork
+var ErrInvalidOpcode = errors.New("invalid opcode")
+
+// ErrWriteProtection is returned when code inside a STATICCALL tries to
+// change the state
+var ErrWriteProtection = errors.New("write protection")
+
+// Calls that fail before running: the caller keeps the gas it offered
+var (
+	ErrDepth               = errors.New("max call depth exceeded")
+	ErrInsufficientBalance = errors.New("insufficient balance for transfer")
 )
 
 // OutOfGasError represents when execution runs out of gas